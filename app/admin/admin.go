@@ -0,0 +1,41 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Server serves the admin API. Every request must carry the admin token as a
+// bearer token.
+type Server struct {
+	Token string
+	mux   *http.ServeMux
+}
+
+func NewServer(token string) *Server {
+	return &Server{
+		Token: token,
+		mux:   http.NewServeMux(),
+	}
+}
+
+func (this *Server) Handle(pattern string, handler http.Handler) {
+	this.mux.Handle(pattern, handler)
+}
+
+func (this *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if this.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(this.Token)) != 1 {
+		WriteJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+	this.mux.ServeHTTP(w, r)
+}
+
+func WriteJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
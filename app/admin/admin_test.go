@@ -0,0 +1,124 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/bot"
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/controller"
+	"github.com/choobot/choo-dict-bot/app/scheduler"
+	"github.com/choobot/choo-dict-bot/app/service"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+func TestServerBreaker(t *testing.T) {
+	breaker := service.NewCircuitBreaker(&service.OxfordService{}, scheduler.NewFakeClock(time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC)))
+	server := NewServer("secret")
+	server.Handle("/admin/breaker", BreakerHandler(breaker))
+	cases := []struct {
+		method string
+		token  string
+		status int
+		want   string
+	}{
+		{"GET", "", http.StatusUnauthorized, `"error":"unauthorized"`},
+		{"GET", "wrong", http.StatusUnauthorized, `"error":"unauthorized"`},
+		{"GET", "secret", http.StatusOK, `"state":"closed"`},
+		{"POST", "secret", http.StatusOK, `"state":"closed"`},
+		{"DELETE", "secret", http.StatusMethodNotAllowed, `"error"`},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, "/admin/breaker", nil)
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		res := httptest.NewRecorder()
+		server.ServeHTTP(res, req)
+		if res.Code != c.status || !strings.Contains(res.Body.String(), c.want) {
+			t.Errorf("%s /admin/breaker with token %q == %d %q, want %d %q", c.method, c.token, res.Code, res.Body.String(), c.status, c.want)
+		}
+	}
+
+	unconfigured := NewServer("")
+	req := httptest.NewRequest("GET", "/admin/breaker", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	res := httptest.NewRecorder()
+	unconfigured.ServeHTTP(res, req)
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("GET /admin/breaker without an admin token configured == %d, want %d", res.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestServerQuota(t *testing.T) {
+	quotas := service.NewQuotaManager(store.NewMemoryQuotaStore(), scheduler.NewFakeClock(time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC)))
+	quotas.SetLimits("oxford", service.QuotaLimits{PerMonth: 3000})
+	quotas.Record("oxford")
+	server := NewServer("secret")
+	server.Handle("/admin/quota", QuotaHandler(quotas))
+	cases := []struct {
+		method string
+		status int
+		want   string
+	}{
+		{"GET", http.StatusOK, `"provider":"oxford","minute":1,"day":1,"month":1,"limits":{"per_minute":0,"per_day":0,"per_month":3000},"exhausted":false`},
+		{"POST", http.StatusMethodNotAllowed, `"error"`},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, "/admin/quota", nil)
+		req.Header.Set("Authorization", "Bearer secret")
+		res := httptest.NewRecorder()
+		server.ServeHTTP(res, req)
+		if res.Code != c.status || !strings.Contains(res.Body.String(), c.want) {
+			t.Errorf("%s /admin/quota == %d %q, want %d %q", c.method, res.Code, res.Body.String(), c.status, c.want)
+		}
+	}
+}
+
+func TestServerTiers(t *testing.T) {
+	plans := controller.NewPlans(store.NewMemoryTierStore(), scheduler.NewFakeClock(time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC)))
+	server := NewServer("secret")
+	server.Handle("/admin/tiers", TiersHandler(plans))
+	cases := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{"GET", "/admin/tiers", "", http.StatusOK, `"assignments":{}`},
+		{"PUT", "/admin/tiers", `{"id":"user1","tier":"premium"}`, http.StatusOK, `"assignments":{"user1":"premium"}`},
+		{"PUT", "/admin/tiers", `{"id":"group1","tier":"gold"}`, http.StatusBadRequest, `"tiers":["free","premium","staff"]`},
+		{"PUT", "/admin/tiers", `{"tier":"staff"}`, http.StatusBadRequest, `"error"`},
+		{"GET", "/admin/tiers?id=user1", "", http.StatusOK, `"name":"premium","per_hour":300,"per_day":1000},"hour_left":300`},
+		{"DELETE", "/admin/tiers?id=user1", "", http.StatusOK, `"assignments":{}`},
+		{"DELETE", "/admin/tiers?id=user1", "", http.StatusNotFound, `"error"`},
+		{"POST", "/admin/tiers", "", http.StatusMethodNotAllowed, `"error"`},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+		req.Header.Set("Authorization", "Bearer secret")
+		res := httptest.NewRecorder()
+		server.ServeHTTP(res, req)
+		if res.Code != c.status || !strings.Contains(res.Body.String(), c.want) {
+			t.Errorf("%s %s %s == %d %q, want %d %q", c.method, c.path, c.body, res.Code, res.Body.String(), c.status, c.want)
+		}
+	}
+}
+
+func TestServerWebhook(t *testing.T) {
+	dispatcher := bot.NewDispatcher(func(event *chat.Event) error { return nil }, 2, 16)
+	defer dispatcher.Stop()
+	server := NewServer("secret")
+	server.Handle("/admin/webhook", DispatcherHandler(dispatcher))
+	req := httptest.NewRequest("GET", "/admin/webhook", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	res := httptest.NewRecorder()
+	server.ServeHTTP(res, req)
+	if want := `"workers":2,"capacity":32,"queued":0`; res.Code != http.StatusOK || !strings.Contains(res.Body.String(), want) {
+		t.Errorf("GET /admin/webhook == %d %q, want %d %q", res.Code, res.Body.String(), http.StatusOK, want)
+	}
+}
@@ -0,0 +1,23 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/choobot/choo-dict-bot/app/service"
+)
+
+// BreakerHandler shows the state of a circuit breaker on GET, and closes it
+// on POST.
+func BreakerHandler(breaker *service.CircuitBreaker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			breaker.Reset()
+		default:
+			WriteJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		WriteJSON(w, http.StatusOK, breaker.Status())
+	})
+}
@@ -0,0 +1,18 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/choobot/choo-dict-bot/app/service"
+)
+
+// QuotaHandler shows the usage of each provider's quota on GET.
+func QuotaHandler(quotas *service.QuotaManager) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			WriteJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		WriteJSON(w, http.StatusOK, quotas.Usage())
+	})
+}
@@ -0,0 +1,62 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/choobot/choo-dict-bot/app/controller"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+type tierAssignment struct {
+	ID   string `json:"id"`
+	Tier string `json:"tier"`
+}
+
+// TiersHandler manages which tier users, groups and rooms are on. GET lists
+// the tiers and assignments, or shows the allowance of the user in the id
+// query parameter, in the chat of the chat_id parameter if any. PUT assigns
+// the tier in the body, {"id": ..., "tier": ...}, and DELETE removes the
+// assignment of the id query parameter.
+func TiersHandler(plans *controller.Plans) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("id")
+		switch r.Method {
+		case http.MethodGet:
+			if id != "" {
+				WriteJSON(w, http.StatusOK, plans.Allowance(id, r.URL.Query().Get("chat_id")))
+				return
+			}
+		case http.MethodPut:
+			assignment := tierAssignment{}
+			if err := json.NewDecoder(r.Body).Decode(&assignment); err != nil || assignment.ID == "" {
+				WriteJSON(w, http.StatusBadRequest, map[string]string{"error": "expected {\"id\": ..., \"tier\": ...}"})
+				return
+			}
+			if err := plans.Assign(assignment.ID, assignment.Tier); err == controller.ErrUnknownTier {
+				WriteJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error(), "tiers": plans.Names()})
+				return
+			} else if err != nil {
+				WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+		case http.MethodDelete:
+			if err := plans.Unassign(id); err == store.ErrTierNotFound {
+				WriteJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+				return
+			} else if err != nil {
+				WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+		default:
+			WriteJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		assignments, err := plans.Assignments()
+		if err != nil {
+			WriteJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		WriteJSON(w, http.StatusOK, map[string]interface{}{"tiers": plans.Tiers, "assignments": assignments})
+	})
+}
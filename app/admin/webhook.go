@@ -0,0 +1,18 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/choobot/choo-dict-bot/app/bot"
+)
+
+// DispatcherHandler shows the webhook event queue on GET.
+func DispatcherHandler(dispatcher *bot.Dispatcher) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			WriteJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		WriteJSON(w, http.StatusOK, dispatcher.Stats())
+	})
+}
@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+)
+
+const commandPrefix = "/"
+
+type CommandHandler func(event *chat.Event, args []string) error
+
+type Command struct {
+	Name    string
+	Aliases []string
+	Usage   string
+	// Descriptions are keyed by language code, "en" is the fallback.
+	Descriptions map[string]string
+	MinArgs      int
+	GroupOnly    bool
+	Handler      CommandHandler
+}
+
+func (this *Command) Description(language string) string {
+	if description, ok := this.Descriptions[language]; ok {
+		return description
+	}
+	return this.Descriptions["en"]
+}
+
+type Commands struct {
+	commands []*Command
+	byName   map[string]*Command
+}
+
+func NewCommands() *Commands {
+	return &Commands{
+		byName: map[string]*Command{},
+	}
+}
+
+func (this *Commands) Register(command *Command) {
+	this.commands = append(this.commands, command)
+	this.byName[command.Name] = command
+	for _, alias := range command.Aliases {
+		this.byName[alias] = command
+	}
+}
+
+func (this *Commands) Find(name string) (*Command, bool) {
+	command, ok := this.byName[strings.ToLower(name)]
+	return command, ok
+}
+
+// Suggest returns the command name closest to an unknown one, or "" when
+// nothing is close enough.
+func (this *Commands) Suggest(name string) string {
+	name = strings.ToLower(name)
+	best, bestDistance := "", 3
+	names := []string{}
+	for n := range this.byName {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		distance := editDistance(name, n)
+		if strings.HasPrefix(n, name) && len(name) >= 2 {
+			distance = 1
+		}
+		if distance < bestDistance {
+			best, bestDistance = this.byName[n].Name, distance
+		}
+	}
+	return best
+}
+
+func (this *Commands) Help(language string, inGroup bool) string {
+	header := map[string]string{
+		"en": "Send me any English word to look it up, or use these commands:",
+		"th": "ส่งคำภาษาอังกฤษมาเพื่อค้นหาความหมาย หรือใช้คำสั่งต่อไปนี้:",
+	}
+	lines := []string{header["en"]}
+	if text, ok := header[language]; ok {
+		lines[0] = text
+	}
+	for _, command := range this.commands {
+		if command.GroupOnly && !inGroup {
+			continue
+		}
+		lines = append(lines, command.Usage+" - "+command.Description(language))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ParseCommand splits "/name arg1 "arg 2"" into its name and arguments.
+// Double quotes group words into one argument.
+func ParseCommand(text string) (string, []string, bool) {
+	text = strings.TrimSpace(text)
+	body := strings.TrimPrefix(text, commandPrefix)
+	if !strings.HasPrefix(text, commandPrefix) || body == "" || strings.TrimSpace(body[:1]) == "" {
+		return "", nil, false
+	}
+	args := []string{}
+	current := ""
+	quoted, started := false, false
+	for _, r := range body {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			started = true
+		case (r == ' ' || r == '\t' || r == '\n') && !quoted:
+			if started {
+				args = append(args, current)
+			}
+			current, started = "", false
+		default:
+			current += string(r)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, current)
+	}
+	if len(args) == 0 || args[0] == "" {
+		return "", nil, false
+	}
+	return strings.ToLower(args[0]), args[1:], true
+}
+
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current := make([]int, len(rb)+1)
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j] + 1
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+			if previous[j-1]+cost < current[j] {
+				current[j] = previous[j-1] + cost
+			}
+		}
+		previous = current
+	}
+	return previous[len(rb)]
+}
@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+)
+
+func TestParseCommand(t *testing.T) {
+	cases := []struct {
+		in   string
+		name string
+		args []string
+		ok   bool
+	}{
+		{"line", "", nil, false},
+		{"/", "", nil, false},
+		{"/ def", "", nil, false},
+		{"/help", "help", []string{}, true},
+		{" /DEF  line ", "def", []string{"line"}, true},
+		{`/def "ice cream" now`, "def", []string{"ice cream", "now"}, true},
+		{`/syn ""`, "syn", []string{""}, true},
+	}
+	for _, c := range cases {
+		name, args, ok := ParseCommand(c.in)
+		if name != c.name || ok != c.ok || !reflect.DeepEqual(args, c.args) {
+			t.Errorf("ParseCommand(%q) == %q, %q, %v, want %q, %q, %v", c.in, name, args, ok, c.name, c.args, c.ok)
+		}
+	}
+}
+
+func TestCommandsSuggest(t *testing.T) {
+	commands := NewCommands()
+	commands.Register(&Command{Name: "def", Aliases: []string{"define"}})
+	commands.Register(&Command{Name: "help"})
+	commands.Register(&Command{Name: "history"})
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"dfe", "def"},
+		{"defnie", "def"},
+		{"hepl", "help"},
+		{"hist", "history"},
+		{"quizzical", ""},
+	}
+	for _, c := range cases {
+		got := commands.Suggest(c.in)
+		if got != c.want {
+			t.Errorf("Commands.Suggest(%q) == %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestCommandsHelp(t *testing.T) {
+	commands := NewCommands()
+	commands.Register(&Command{Name: "def", Usage: "/def <word>", Descriptions: map[string]string{"en": "Define", "th": "ความหมาย"}})
+	commands.Register(&Command{Name: "mode", Usage: "/mode", Descriptions: map[string]string{"en": "Mode"}, GroupOnly: true})
+	cases := []struct {
+		language string
+		inGroup  bool
+		want     string
+	}{
+		{"en", false, "Send me any English word to look it up, or use these commands:\n/def <word> - Define"},
+		{"ja", true, "Send me any English word to look it up, or use these commands:\n/def <word> - Define\n/mode - Mode"},
+		{"th", true, "ส่งคำภาษาอังกฤษมาเพื่อค้นหาความหมาย หรือใช้คำสั่งต่อไปนี้:\n/def <word> - ความหมาย\n/mode - Mode"},
+	}
+	for _, c := range cases {
+		got := commands.Help(c.language, c.inGroup)
+		if got != c.want {
+			t.Errorf("Commands.Help(%q, %v) == %q, want %q", c.language, c.inGroup, got, c.want)
+		}
+	}
+}
+
+func TestDictBotResponseCommand(t *testing.T) {
+	adapter := newFakeAdapter()
+	bot := NewDictBot(mockServiceController{}, adapter)
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"/help", "Definitions and synonyms of a word"},
+		{"/def line", `"text":"dummy"`},
+		{"/define", "Usage: /def"},
+		{"/syn line", "synonyms of line"},
+		{"/s line", "synonyms of line"},
+		{"/say line", "pronunciations of line"},
+		{"/dfe line", "Unknown command '/dfe'. Did you mean '/def'? Send /help to see all commands."},
+		{"/mode all", "Unknown command '/mode'."},
+	}
+	for _, c := range cases {
+		event := &chat.Event{
+			Type:        chat.EventTypeMessage,
+			MessageType: chat.MessageTypeText, Text: c.text,
+			Source:     chat.Source{Type: chat.SourceTypeUser, UserID: "user1"},
+			ReplyToken: "dummy",
+		}
+		if err := bot.Response([]*chat.Event{event}); err != nil {
+			t.Errorf("DictBot.Response(%q) == %v, want %v", c.text, err, nil)
+		}
+		requests := adapter.Requests()
+		if got := requests[len(requests)-1]; !strings.Contains(got, c.want) {
+			t.Errorf("DictBot.Response(%q) replied %q, want %q", c.text, got, c.want)
+		}
+	}
+}
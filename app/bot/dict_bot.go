@@ -1,38 +1,378 @@
 package bot
 
 import (
+	"errors"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
 	"github.com/choobot/choo-dict-bot/app/controller"
-	"github.com/line/line-bot-sdk-go/linebot"
+	"github.com/choobot/choo-dict-bot/app/i18n"
+	"github.com/choobot/choo-dict-bot/app/scheduler"
+	"github.com/choobot/choo-dict-bot/app/service"
+	"github.com/choobot/choo-dict-bot/app/store"
 )
 
+type quickReplyAction struct {
+	action string
+	label  i18n.Code
+}
+
+var quickReplyActions = []quickReplyAction{
+	{ActionSynonyms, i18n.SynonymsLabel},
+	{ActionAntonyms, i18n.AntonymsLabel},
+	{ActionExamples, i18n.ExamplesLabel},
+	{ActionPronunciations, i18n.PronounceLabel},
+}
+
 type DictBot struct {
 	ServiceController controller.ServiceController
-	Client            *linebot.Client
-}
-
-func (this *DictBot) Response(events []*linebot.Event) error {
-	for _, event := range events {
-		if event.Type == linebot.EventTypeMessage {
-			switch message := event.Message.(type) {
-			case *linebot.TextMessage:
-				word := message.Text
-				definistions, synonyms, err := this.ServiceController.FindDefinitionsAndSynonyms(event.Source.UserID, word)
-				if err != nil {
-					if _, err = this.Client.ReplyMessage(event.ReplyToken, linebot.NewTextMessage(err.Error())).Do(); err != nil {
-						return err
-					}
-				} else {
-					if _, err = this.Client.ReplyMessage(event.ReplyToken, linebot.NewTextMessage(definistions), linebot.NewTextMessage(synonyms)).Do(); err != nil {
-						return err
-					}
-				}
-			}
-		} else if event.Type == linebot.EventTypeJoin {
-			replyMessage := "Thanks for adding me. I'm Choo Dict Bot, I'm here to help you to find English word definitions and synonyms. Try to send me some words."
-			if _, err := this.Client.ReplyMessage(event.ReplyToken, linebot.NewTextMessage(replyMessage)).Do(); err != nil {
-				return err
-			}
+	Adapter           chat.Adapter
+	Router            *Router
+	Commands          *Commands
+	Registry          store.UserRegistry
+	Groups            store.GroupStore
+	Notebook          store.Notebook
+	Reviews           store.ReviewStore
+	Quizzes           store.QuizStore
+	Subscriptions     store.SubscriptionStore
+	History           store.History
+	Plans             *controller.Plans
+	Events            store.EventStore
+	Clock             scheduler.Clock
+	Random            func(n int) int
+	BotUserIDs        []string
+	GroupPrefix       string
+	Admins            []string
+	CuratedWords      []string
+	WordOfTheDayHour  int
+	lookups           *lookupCache
+	wordsOfTheDay     *wordOfTheDayCache
+}
+
+func NewDictBot(serviceController controller.ServiceController, adapter chat.Adapter) *DictBot {
+	bot := &DictBot{
+		ServiceController: serviceController,
+		Adapter:           adapter,
+		Router:            NewRouter(),
+		Commands:          NewCommands(),
+		GroupPrefix:       "?",
+		Clock:             scheduler.RealClock{},
+		Random:            rand.Intn,
+		CuratedWords:      DefaultCuratedWords,
+		WordOfTheDayHour:  8,
+		lookups:           newLookupCache(),
+		wordsOfTheDay:     newWordOfTheDayCache(),
+	}
+	userRateLimiter := NewUserRateLimiter(20, time.Minute, bot.handleRateLimited)
+	chatRateLimiter := NewChatRateLimiter(60, time.Minute, nil)
+	bot.Router.Use(LoggingMiddleware(log.New(os.Stdout, "", log.LstdFlags)), RecoveryMiddleware, bot.dedup, bot.addressed, chatRateLimiter.Middleware, userRateLimiter.Middleware)
+	bot.Router.HandleMessage(chat.MessageTypeText, bot.handleText)
+	bot.Router.Handle(chat.EventTypeJoin, bot.handleJoin)
+	bot.Router.Handle(chat.EventTypeFollow, bot.handleFollow)
+	bot.Router.Handle(chat.EventTypeUnfollow, bot.handleUnfollow)
+	bot.Router.HandleUnknownPostback(bot.handleUnknownPostback)
+	bot.registerCommands()
+	return bot
+}
+
+func (this *DictBot) registerCommands() {
+	this.Commands.Register(&Command{
+		Name:    "help",
+		Aliases: []string{"h", "start"},
+		Usage:   "/help",
+		Descriptions: map[string]string{
+			"en": "Show this help",
+			"th": "แสดงวิธีใช้งาน",
+		},
+		Handler: this.handleHelpCommand,
+	})
+	this.Commands.Register(&Command{
+		Name:    "def",
+		Aliases: []string{"define", "d"},
+		Usage:   "/def <word>",
+		Descriptions: map[string]string{
+			"en": "Definitions and synonyms of a word",
+			"th": "ความหมายและคำพ้องความหมายของคำ",
+		},
+		MinArgs: 1,
+		Handler: func(event *chat.Event, args []string) error {
+			return this.lookup(event, strings.Join(args, " "))
+		},
+	})
+	details := []struct {
+		action       string
+		name         string
+		aliases      []string
+		descriptions map[string]string
+		find         func(userID string, chatID string, word string) (string, error)
+		notFound     i18n.Code
+	}{
+		{ActionSynonyms, "syn", []string{"synonyms", "s"}, map[string]string{"en": "Synonyms of a word", "th": "คำพ้องความหมายของคำ"}, this.ServiceController.FindSynonyms, i18n.NoSynonyms},
+		{ActionAntonyms, "ant", []string{"antonyms", "a"}, map[string]string{"en": "Antonyms of a word", "th": "คำตรงข้ามของคำ"}, this.ServiceController.FindAntonyms, i18n.NoAntonyms},
+		{ActionExamples, "ex", []string{"examples", "example", "e"}, map[string]string{"en": "Example sentences of a word", "th": "ตัวอย่างประโยคของคำ"}, this.ServiceController.FindExamples, i18n.NoExamples},
+		{ActionPronunciations, "say", []string{"pron", "pronounce", "p"}, map[string]string{"en": "Pronunciation of a word", "th": "วิธีออกเสียงของคำ"}, this.ServiceController.FindPronunciations, i18n.NoPronunciations},
+	}
+	for _, detail := range details {
+		find, notFound := detail.find, detail.notFound
+		this.Router.HandlePostback(detail.action, func(event *chat.Event, postback Postback) error {
+			return this.replyDetail(event, find, notFound, postback.Word)
+		})
+		this.Commands.Register(&Command{
+			Name:         detail.name,
+			Aliases:      detail.aliases,
+			Usage:        "/" + detail.name + " <word>",
+			Descriptions: detail.descriptions,
+			MinArgs:      1,
+			Handler: func(event *chat.Event, args []string) error {
+				return this.replyDetail(event, find, notFound, strings.Join(args, " "))
+			},
+		})
+	}
+	this.Commands.Register(&Command{
+		Name:  "mode",
+		Usage: "/mode [mention|all|off]",
+		Descriptions: map[string]string{
+			"en": "Show or change when I answer in this chat",
+			"th": "ดูหรือเปลี่ยนโหมดการตอบในแชทนี้",
+		},
+		GroupOnly: true,
+		Handler: func(event *chat.Event, args []string) error {
+			return this.handleModeCommand(event, event.Source.ChatID, args)
+		},
+	})
+	this.Commands.Register(&Command{
+		Name:  "admins",
+		Usage: "/admins [add|remove] [@member]",
+		Descriptions: map[string]string{
+			"en": "Show or change who can change my settings in this chat",
+			"th": "ดูหรือเปลี่ยนผู้ที่ตั้งค่าบอทในแชทนี้ได้",
+		},
+		GroupOnly: true,
+		Handler: func(event *chat.Event, args []string) error {
+			return this.handleAdminsCommand(event, event.Source.ChatID, args)
+		},
+	})
+	this.registerNotebookCommands()
+	this.registerReviewCommands()
+	this.registerQuizCommands()
+	this.registerWordOfTheDayCommands()
+	this.registerHistoryCommands()
+	this.registerSettingsCommands()
+	this.registerQuotaCommands()
+}
+
+// Response handles every event of a batch, even when some of them fail, and
+// returns a *ResponseError with the failures.
+func (this *DictBot) Response(events []*chat.Event) error {
+	errs := []*EventError{}
+	for i, event := range events {
+		if err := this.HandleEvent(event); err != nil {
+			errs = append(errs, &EventError{Index: i, Event: event, Err: err})
 		}
 	}
+	if len(errs) > 0 {
+		return &ResponseError{Events: len(events), Errors: errs}
+	}
 	return nil
 }
+
+func (this *DictBot) QuickReplies(language string, word string) []chat.Button {
+	buttons := []chat.Button{}
+	actions := append([]quickReplyAction{}, quickReplyActions...)
+	if this.Notebook != nil {
+		actions = append(actions, quickReplyAction{ActionSave, i18n.SaveToNotebook})
+	}
+	for _, item := range actions {
+		data := Postback{Action: item.action, Word: word}.Encode()
+		if len(data) > maxPostbackDataLength {
+			return nil
+		}
+		buttons = append(buttons, chat.NewPostbackButton(i18n.Text(language, item.label), data))
+	}
+	return buttons
+}
+
+func (this *DictBot) reply(event *chat.Event, messages ...chat.Message) error {
+	return this.Adapter.Reply(event, messages...)
+}
+
+func (this *DictBot) handleText(event *chat.Event) error {
+	word := event.Text
+	name, args, isCommand := ParseCommand(word)
+	if chatID := event.Source.ChatID; chatID != "" {
+		name, args, isCommand = this.groupCommand(event)
+		mode := this.group(chatID).Mode
+		if isCommand && allowsCommand(mode, name) {
+			return this.handleCommand(event, name, args)
+		}
+		text, ok := this.groupLookupText(event, mode)
+		if !ok {
+			return nil
+		}
+		if text == "" {
+			return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.GroupLookupHint, this.GroupPrefix)))
+		}
+		word = text
+	} else if isCommand {
+		return this.handleCommand(event, name, args)
+	}
+	return this.lookup(event, word)
+}
+
+func (this *DictBot) handleCommand(event *chat.Event, name string, args []string) error {
+	command, ok := this.Commands.Find(name)
+	if !ok || (command.GroupOnly && event.Source.ChatID == "") {
+		replyMessage := this.text(event.Source, i18n.UnknownCommand, name)
+		if suggestion := this.Commands.Suggest(name); suggestion != "" {
+			replyMessage += this.text(event.Source, i18n.DidYouMean, suggestion)
+		}
+		return this.reply(event, chat.NewTextMessage(replyMessage+this.text(event.Source, i18n.SeeHelp)))
+	}
+	if len(args) < command.MinArgs {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.Usage, command.Usage)))
+	}
+	return command.Handler(event, args)
+}
+
+func (this *DictBot) handleHelpCommand(event *chat.Event, args []string) error {
+	return this.reply(event, chat.NewTextMessage(this.Commands.Help(this.language(event.Source), event.Source.ChatID != "")))
+}
+
+// language is the user's reply language setting or the language of their
+// chat app, falling back to English.
+func (this *DictBot) language(source chat.Source) string {
+	if this.Registry != nil && source.UserID != "" {
+		if language := this.preference(source.UserID, SettingLanguage, ""); language != "" {
+			return language
+		}
+		if user, err := this.Registry.Get(source.UserID); err == nil && user.Language != "" {
+			return user.Language
+		}
+	}
+	return i18n.English
+}
+
+// text returns a message from the catalog in the user's language.
+func (this *DictBot) text(source chat.Source, code i18n.Code, args ...interface{}) string {
+	return i18n.Text(this.language(source), code, args...)
+}
+
+// errorText returns the text of an error in the user's language.
+func (this *DictBot) errorText(source chat.Source, err error) string {
+	return i18n.Message(this.language(source), err)
+}
+
+// lookupErrorText is like errorText, but says what wasn't found when the
+// dictionary doesn't know the word.
+func (this *DictBot) lookupErrorText(source chat.Source, err error, notFound i18n.Code, word string) string {
+	if errors.Is(err, service.ErrNotFound) {
+		return this.text(source, notFound, word)
+	}
+	return this.errorText(source, err)
+}
+
+func (this *DictBot) lookup(event *chat.Event, word string) error {
+	settings := this.settings(event.Source.UserID)
+	entry, err := this.ServiceController.Lookup(requesterID(event.Source), event.Source.ChatID, word, settings.LookupOptions())
+	if err != nil {
+		return this.reply(event, chat.NewTextMessage(this.errorText(event.Source, err)))
+	}
+	word = strings.Split(word, " ")[0]
+	definitions, translations, synonyms := renderEntry(entry, settings)
+	if len(entry.Senses) > 0 {
+		// The notebook keeps the main sense only
+		this.lookups.add(word, lookupResult{definitions: entry.Senses[0].Definition, synonyms: entry.Synonyms})
+	}
+	this.recordLookup(event.Source, word, len(entry.Senses) > 0)
+	messages := []chat.Message{{Title: word, Text: definitions}}
+	if translations != "" {
+		messages = append(messages, chat.NewTextMessage(translations))
+	}
+	messages = append(messages, chat.NewTextMessage(synonyms).WithQuickReplies(this.QuickReplies(this.language(event.Source), word)...))
+	return this.reply(event, messages...)
+}
+
+func (this *DictBot) handleJoin(event *chat.Event) error {
+	return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.JoinGreeting, this.GroupPrefix)))
+}
+
+func (this *DictBot) handleFollow(event *chat.Event) error {
+	user := store.User{
+		ID:         event.Source.UserID,
+		FollowedAt: event.Timestamp,
+	}
+	if user.FollowedAt.IsZero() {
+		user.FollowedAt = this.Clock.Now()
+	}
+	profile, err := this.Adapter.Profile(user.ID)
+	if err != nil {
+		log.Println(err)
+	} else {
+		user.DisplayName = profile.DisplayName
+		user.PictureURL = profile.PictureURL
+		user.Language = profile.Language
+	}
+	returning := false
+	if this.Registry != nil {
+		_, err := this.Registry.Get(user.ID)
+		returning = err == nil
+		if err := this.Registry.Follow(user); err != nil {
+			log.Println(err)
+		}
+	}
+	language := this.language(event.Source)
+	if this.Registry == nil && user.Language != "" {
+		language = user.Language
+	}
+	name := user.DisplayName
+	if name == "" {
+		name = i18n.Text(language, i18n.Friend)
+	}
+	replyMessage := i18n.Text(language, i18n.FollowGreeting, name)
+	if returning {
+		replyMessage = i18n.Text(language, i18n.WelcomeBack, name)
+	}
+	return this.reply(event, chat.NewTextMessage(replyMessage))
+}
+
+func (this *DictBot) handleUnfollow(event *chat.Event) error {
+	if this.Registry == nil {
+		return nil
+	}
+	at := event.Timestamp
+	if at.IsZero() {
+		at = this.Clock.Now()
+	}
+	return this.Registry.Unfollow(event.Source.UserID, at)
+}
+
+func (this *DictBot) replyDetail(event *chat.Event, find func(userID string, chatID string, word string) (string, error), notFound i18n.Code, word string) error {
+	word = strings.Split(word, " ")[0]
+	res, err := find(requesterID(event.Source), event.Source.ChatID, word)
+	if errors.Is(err, service.ErrNotFound) {
+		res, err = this.text(event.Source, notFound, word), nil
+	}
+	if err != nil {
+		return this.reply(event, chat.NewTextMessage(this.errorText(event.Source, err)))
+	}
+	return this.reply(event, chat.NewTextMessage(res).WithQuickReplies(this.QuickReplies(this.language(event.Source), word)...))
+}
+
+func (this *DictBot) handleUnknownPostback(event *chat.Event) error {
+	return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.ButtonExpired)))
+}
+
+func (this *DictBot) handleRateLimited(event *chat.Event) error {
+	if event.ReplyToken == "" {
+		return nil
+	}
+	// A group that turned the bot off never hears from it
+	if event.Source.ChatID != "" && this.group(event.Source.ChatID).Mode == store.GroupModeOff {
+		return nil
+	}
+	return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.TooFast)))
+}
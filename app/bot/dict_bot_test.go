@@ -1,44 +1,158 @@
 package bot
 
 import (
+	"encoding/json"
 	"errors"
-	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
-	"github.com/line/line-bot-sdk-go/linebot"
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/scheduler"
+	"github.com/choobot/choo-dict-bot/app/service"
+	"github.com/choobot/choo-dict-bot/app/store"
 )
 
 type mockServiceController struct {
 }
 
-func (this mockServiceController) FindDefinitionsAndSynonyms(userID string, word string) (string, string, error) {
+func (this mockServiceController) FindDefinitionsAndSynonyms(userID string, chatID string, word string) (string, string, error) {
 	if word == "error_word" {
 		return "", "", errors.New("dummy")
 	}
 	return "dummy", "dummy", nil
 }
 
+func (this mockServiceController) Lookup(userID string, chatID string, word string, options service.LookupOptions) (service.Entry, error) {
+	if word == "error_word" {
+		return service.Entry{}, errors.New("dummy")
+	}
+	entry := service.Entry{Word: word, Synonyms: []string{"dummy"}}
+	if word == "lonely" {
+		entry.Synonyms = nil
+	}
+	for i := 0; i < options.Senses; i++ {
+		sense := service.Sense{Definition: "dummy"}
+		if options.Examples {
+			sense.Examples = []string{"example of " + word}
+		}
+		entry.Senses = append(entry.Senses, sense)
+	}
+	if options.Pronunciation {
+		entry.Pronunciations = []string{"/" + word + "/"}
+	}
+	if options.TranslateTo != "" {
+		entry.Translations = []string{options.TranslateTo + " " + word}
+	}
+	return entry, nil
+}
+
+func (this mockServiceController) FindSynonyms(userID string, chatID string, word string) (string, error) {
+	if word == "error_word" {
+		return "", errors.New("dummy")
+	}
+	return "synonyms of " + word, nil
+}
+
+func (this mockServiceController) FindAntonyms(userID string, chatID string, word string) (string, error) {
+	if word == "unknown_word" {
+		return "", &service.Error{Kind: service.ErrNotFound}
+	}
+	return "antonyms of " + word, nil
+}
+
+func (this mockServiceController) FindExamples(userID string, chatID string, word string) (string, error) {
+	return "examples of " + word, nil
+}
+
+func (this mockServiceController) FindPronunciations(userID string, chatID string, word string) (string, error) {
+	return "pronunciations of " + word, nil
+}
+
+// fakeAdapter records what the bot sends as "<kind> <JSON>", e.g.
+// `push {"to":"user1","messages":[{"text":"hello"}]}`.
+type fakeAdapter struct {
+	// err is returned by Reply
+	err error
+	// groupAdmins are the users IsGroupAdmin reports as chat admins
+	groupAdmins []string
+	requestsMux sync.Mutex
+	requests    []string
+}
+
+func newFakeAdapter() *fakeAdapter {
+	return &fakeAdapter{}
+}
+
+func (this *fakeAdapter) Requests() []string {
+	this.requestsMux.Lock()
+	defer this.requestsMux.Unlock()
+	return append([]string{}, this.requests...)
+}
+
+func (this *fakeAdapter) record(kind string, to interface{}, messages []chat.Message) {
+	body, _ := json.Marshal(struct {
+		To       interface{}    `json:"to,omitempty"`
+		Messages []chat.Message `json:"messages,omitempty"`
+	}{to, messages})
+	this.requestsMux.Lock()
+	defer this.requestsMux.Unlock()
+	this.requests = append(this.requests, kind+" "+string(body))
+}
+
+func (this *fakeAdapter) Reply(event *chat.Event, messages ...chat.Message) error {
+	this.record("reply", nil, messages)
+	return this.err
+}
+
+func (this *fakeAdapter) Push(to string, messages ...chat.Message) error {
+	this.record("push", to, messages)
+	return nil
+}
+
+func (this *fakeAdapter) Multicast(to []string, messages ...chat.Message) error {
+	this.record("multicast", to, messages)
+	return nil
+}
+
+func (this *fakeAdapter) Broadcast(messages ...chat.Message) error {
+	this.record("broadcast", nil, messages)
+	return nil
+}
+
+func (this *fakeAdapter) IsGroupAdmin(chatID string, userID string) (bool, error) {
+	for _, admin := range this.groupAdmins {
+		if admin == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (this *fakeAdapter) Profile(userID string) (chat.Profile, error) {
+	this.record("profile", userID, nil)
+	return chat.Profile{DisplayName: "Choo", Language: "en"}, nil
+}
+
 func TestDictBotResponse(t *testing.T) {
-	wantErr := errors.New("linebot: APIError 400 Invalid reply token")
-	client, _ := linebot.New(os.Getenv("LINE_BOT_SECRET"), os.Getenv("LINE_BOT_TOKEN"))
+	wantErr := errors.New("invalid reply token")
+	adapter := newFakeAdapter()
+	adapter.err = wantErr
 	serviceController := mockServiceController{}
-	bot := &DictBot{
-		ServiceController: serviceController,
-		Client:            client,
-	}
+	bot := NewDictBot(serviceController, adapter)
 
-	events := []*linebot.Event{}
+	events := []*chat.Event{}
 	err := bot.Response(events)
 	if err != nil {
 		t.Errorf("DictBot.Response(%v) == %v, want %v", events, err, nil)
 	}
 
-	event := linebot.Event{
-		Type: linebot.EventTypeMessage,
-		Message: &linebot.TextMessage{
-			Text: "dummy",
-		},
-		Source: &linebot.EventSource{
+	event := chat.Event{
+		Type:        chat.EventTypeMessage,
+		MessageType: chat.MessageTypeText,
+		Text:        "dummy",
+		Source: chat.Source{
 			UserID: "dummy",
 		},
 		ReplyToken: "dummy",
@@ -46,39 +160,198 @@ func TestDictBotResponse(t *testing.T) {
 	events = append(events, &event)
 	bot.Response(events)
 	err = bot.Response(events)
-	if err == nil || err.Error() != wantErr.Error() {
+	if !failedWith(err, len(events), wantErr) {
 		t.Errorf("DictBot.Response(%v) == %v, want %v", events, err, wantErr)
 	}
 
-	event = linebot.Event{
-		Type: linebot.EventTypeMessage,
-		Message: &linebot.TextMessage{
-			Text: "error_word",
-		},
-		Source: &linebot.EventSource{
+	event = chat.Event{
+		Type:        chat.EventTypeMessage,
+		MessageType: chat.MessageTypeText,
+		Text:        "error_word",
+		Source: chat.Source{
 			UserID: "dummy",
 		},
 		ReplyToken: "dummy",
 	}
 	events = append(events, &event)
 	err = bot.Response(events)
-	if err == nil || err.Error() != wantErr.Error() {
+	if !failedWith(err, len(events), wantErr) {
 		t.Errorf("DictBot.Response(%v) == %v, want %v", events, err, wantErr)
 	}
 
-	event = linebot.Event{
-		Type: linebot.EventTypeJoin,
-		Message: &linebot.TextMessage{
-			Text: "error_word",
-		},
-		Source: &linebot.EventSource{
+	event = chat.Event{
+		Type:        chat.EventTypeJoin,
+		MessageType: chat.MessageTypeText,
+		Text:        "error_word",
+		Source: chat.Source{
 			UserID: "dummy",
 		},
 		ReplyToken: "dummy",
 	}
 	events = append(events, &event)
 	err = bot.Response(events)
-	if err == nil || err.Error() != wantErr.Error() {
+	if !failedWith(err, len(events), wantErr) {
 		t.Errorf("DictBot.Response(%v) == %v, want %v", events, err, wantErr)
 	}
 }
+
+// failedWith reports whether every one of n events failed with want.
+func failedWith(err error, n int, want error) bool {
+	var responseErr *ResponseError
+	if !errors.As(err, &responseErr) || len(responseErr.Errors) != n {
+		return false
+	}
+	for _, eventErr := range responseErr.Errors {
+		if eventErr.Err.Error() != want.Error() {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDictBotResponseIsolation(t *testing.T) {
+	adapter := newFakeAdapter()
+	bot := NewDictBot(mockServiceController{}, adapter)
+	bot.Commands.Register(&Command{Name: "boom", Handler: func(event *chat.Event, args []string) error {
+		panic("boom")
+	}})
+	bot.Commands.Register(&Command{Name: "fail", Handler: func(event *chat.Event, args []string) error {
+		return errors.New("failed")
+	}})
+	events := []*chat.Event{}
+	for _, text := range []string{"/boom", "/fail", "/quota"} {
+		events = append(events, &chat.Event{
+			Type:        chat.EventTypeMessage,
+			ReplyToken:  "token",
+			Source:      chat.Source{Type: chat.SourceTypeUser, UserID: "user1"},
+			MessageType: chat.MessageTypeText, Text: text,
+		})
+	}
+	err := bot.Response(events)
+	var responseErr *ResponseError
+	if !errors.As(err, &responseErr) || responseErr.Events != 3 || len(responseErr.Errors) != 2 || responseErr.Errors[0].Index != 0 || responseErr.Errors[1].Index != 1 {
+		t.Fatalf("DictBot.Response() of a batch with failures == %v", err)
+	}
+	if !strings.Contains(responseErr.Errors[0].Error(), "panic") || responseErr.Errors[1].Err.Error() != "failed" {
+		t.Errorf("DictBot.Response() errors == %v", err)
+	}
+	if requests := adapter.Requests(); len(requests) != 1 || !strings.Contains(requests[0], "There's no limit on your lookups.") {
+		t.Errorf("DictBot.Response() sent %q, want the reply to the last event", requests)
+	}
+}
+
+func TestDictBotResponsePostback(t *testing.T) {
+	adapter := newFakeAdapter()
+	bot := NewDictBot(mockServiceController{}, adapter)
+	cases := []struct {
+		data string
+		want string
+	}{
+		{Postback{Action: ActionSynonyms, Word: "line"}.Encode(), "synonyms of line"},
+		{Postback{Action: ActionAntonyms, Word: "line"}.Encode(), "antonyms of line"},
+		{Postback{Action: ActionExamples, Word: "line"}.Encode(), "examples of line"},
+		{Postback{Action: ActionPronunciations, Word: "line"}.Encode(), "pronunciations of line"},
+		{Postback{Action: ActionSynonyms, Word: "error_word"}.Encode(), "dummy"},
+		{Postback{Action: ActionAntonyms, Word: "unknown_word"}.Encode(), "No antonyms for 'unknown_word'."},
+		{"0|syn|line", "This button is no longer available, please send the word again."},
+		{"1|unknown|line", "This button is no longer available, please send the word again."},
+	}
+	for i, c := range cases {
+		events := []*chat.Event{
+			{
+				Type:       chat.EventTypePostback,
+				Postback:   c.data,
+				Source:     chat.Source{UserID: "dummy"},
+				ReplyToken: "dummy",
+			},
+		}
+		if err := bot.Response(events); err != nil {
+			t.Errorf("DictBot.Response(%q) == %v, want %v", c.data, err, nil)
+		}
+		requests := adapter.Requests()
+		if len(requests) != i+1 || !strings.Contains(requests[i], c.want) {
+			t.Errorf("DictBot.Response(%q) replied %q, want %q", c.data, requests[len(requests)-1], c.want)
+		}
+	}
+}
+
+func TestDictBotResponseQuickReplies(t *testing.T) {
+	adapter := newFakeAdapter()
+	bot := NewDictBot(mockServiceController{}, adapter)
+	events := []*chat.Event{
+		{
+			Type:        chat.EventTypeMessage,
+			MessageType: chat.MessageTypeText, Text: "line up",
+			Source:     chat.Source{UserID: "dummy"},
+			ReplyToken: "dummy",
+		},
+	}
+	if err := bot.Response(events); err != nil {
+		t.Errorf("DictBot.Response(%v) == %v, want %v", events, err, nil)
+	}
+	requests := adapter.Requests()
+	for _, want := range []string{`"quick_replies"`, `"data":"1|syn|line"`, `"data":"1|pron|line"`} {
+		if len(requests) != 1 || !strings.Contains(requests[0], want) {
+			t.Errorf("DictBot.Response(%v) replied %q, want %q", events, requests, want)
+		}
+	}
+}
+
+func TestDictBotResponseFollow(t *testing.T) {
+	adapter := newFakeAdapter()
+	bot := NewDictBot(mockServiceController{}, adapter)
+	bot.Registry = store.NewMemoryUserRegistry()
+	now := time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC)
+	bot.Clock = scheduler.NewFakeClock(now)
+	follow := &chat.Event{
+		Type:       chat.EventTypeFollow,
+		Source:     chat.Source{Type: chat.SourceTypeUser, UserID: "user1"},
+		ReplyToken: "dummy",
+	}
+	if err := bot.Response([]*chat.Event{follow}); err != nil {
+		t.Errorf("DictBot.Response(follow) == %v, want %v", err, nil)
+	}
+	user, err := bot.Registry.Get("user1")
+	if err != nil || !user.Active || user.DisplayName != "Choo" || user.Language != "en" || !user.FollowedAt.Equal(now) {
+		t.Errorf("Registry.Get(%q) == %+v, %v", "user1", user, err)
+	}
+	requests := adapter.Requests()
+	want := "Hi Choo, thanks for adding me."
+	if len(requests) != 2 || !strings.Contains(requests[1], want) {
+		t.Errorf("DictBot.Response(follow) replied %q, want %q", requests, want)
+	}
+
+	unfollow := &chat.Event{
+		Type:   chat.EventTypeUnfollow,
+		Source: chat.Source{Type: chat.SourceTypeUser, UserID: "user1"},
+	}
+	if err := bot.Response([]*chat.Event{unfollow}); err != nil {
+		t.Errorf("DictBot.Response(unfollow) == %v, want %v", err, nil)
+	}
+	user, err = bot.Registry.Get("user1")
+	if err != nil || user.Active {
+		t.Errorf("Registry.Get(%q) == %+v, %v", "user1", user, err)
+	}
+
+	bot.Response([]*chat.Event{follow})
+	requests = adapter.Requests()
+	want = "Welcome back, Choo!"
+	if !strings.Contains(requests[len(requests)-1], want) {
+		t.Errorf("DictBot.Response(follow) replied %q, want %q", requests[len(requests)-1], want)
+	}
+
+	bot.Registry.SetPreference("user1", SettingLanguage, "th")
+	bot.Response([]*chat.Event{follow})
+	requests = adapter.Requests()
+	want = "ยินดีต้อนรับกลับมา Choo!"
+	if !strings.Contains(requests[len(requests)-1], want) {
+		t.Errorf("DictBot.Response(follow) in Thai replied %q, want %q", requests[len(requests)-1], want)
+	}
+	limited := &chat.Event{Type: chat.EventTypeMessage, MessageType: chat.MessageTypeText, Text: "line", Source: follow.Source, ReplyToken: "dummy"}
+	bot.handleRateLimited(limited)
+	requests = adapter.Requests()
+	want = "ส่งเร็วเกินไป"
+	if !strings.Contains(requests[len(requests)-1], want) {
+		t.Errorf("DictBot.handleRateLimited() in Thai replied %q, want %q", requests[len(requests)-1], want)
+	}
+}
@@ -0,0 +1,132 @@
+package bot
+
+import (
+	"errors"
+	"hash/fnv"
+	"log"
+	"sync"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+)
+
+var ErrQueueFull = errors.New("event queue is full")
+
+// DispatcherStats shows how far the workers are behind.
+type DispatcherStats struct {
+	Workers   int `json:"workers"`
+	Capacity  int `json:"capacity"`
+	Queued    int `json:"queued"`
+	HighWater int `json:"high_water"`
+	Enqueued  int `json:"enqueued"`
+	Processed int `json:"processed"`
+	Failed    int `json:"failed"`
+	Dropped   int `json:"dropped"`
+}
+
+// Dispatcher handles webhook events in the background, so that the webhook
+// can be acknowledged at once. Events of the same chat go to the same worker
+// and are handled in order. Each worker has a bounded queue, and a batch of
+// events that doesn't fit is refused as a whole.
+type Dispatcher struct {
+	handle   func(event *chat.Event) error
+	queues   []chan *chat.Event
+	stats    DispatcherStats
+	stopped  bool
+	wg       sync.WaitGroup
+	statsMux sync.Mutex
+}
+
+// NewDispatcher starts the workers. handle must not panic: DictBot.HandleEvent
+// recovers the panics of its handlers.
+func NewDispatcher(handle func(event *chat.Event) error, workers int, queueSize int) *Dispatcher {
+	dispatcher := &Dispatcher{
+		handle: handle,
+		stats: DispatcherStats{
+			Workers:  workers,
+			Capacity: workers * queueSize,
+		},
+	}
+	for i := 0; i < workers; i++ {
+		queue := make(chan *chat.Event, queueSize)
+		dispatcher.queues = append(dispatcher.queues, queue)
+		dispatcher.wg.Add(1)
+		go dispatcher.work(queue)
+	}
+	return dispatcher
+}
+
+// Enqueue queues events for their workers, or returns ErrQueueFull without
+// queueing any of them.
+func (this *Dispatcher) Enqueue(events []*chat.Event) error {
+	this.statsMux.Lock()
+	defer this.statsMux.Unlock()
+	if this.stopped {
+		this.stats.Dropped += len(events)
+		return ErrQueueFull
+	}
+	counts := map[chan *chat.Event]int{}
+	for _, event := range events {
+		queue := this.queue(event)
+		counts[queue]++
+		if len(queue)+counts[queue] > cap(queue) {
+			this.stats.Dropped += len(events)
+			return ErrQueueFull
+		}
+	}
+	// Workers only take from the queues, so the events fit
+	for _, event := range events {
+		this.queue(event) <- event
+	}
+	this.stats.Enqueued += len(events)
+	this.stats.Queued += len(events)
+	if this.stats.Queued > this.stats.HighWater {
+		this.stats.HighWater = this.stats.Queued
+	}
+	return nil
+}
+
+// Stop stops taking events and waits for the queued ones to be handled.
+func (this *Dispatcher) Stop() {
+	this.statsMux.Lock()
+	if !this.stopped {
+		this.stopped = true
+		for _, queue := range this.queues {
+			close(queue)
+		}
+	}
+	this.statsMux.Unlock()
+	this.wg.Wait()
+}
+
+func (this *Dispatcher) Stats() DispatcherStats {
+	this.statsMux.Lock()
+	defer this.statsMux.Unlock()
+	return this.stats
+}
+
+func (this *Dispatcher) work(queue chan *chat.Event) {
+	defer this.wg.Done()
+	for event := range queue {
+		err := this.handle(event)
+		if err != nil {
+			log.Println(err)
+		}
+		this.statsMux.Lock()
+		this.stats.Queued--
+		this.stats.Processed++
+		if err != nil {
+			this.stats.Failed++
+		}
+		this.statsMux.Unlock()
+	}
+}
+
+func (this *Dispatcher) queue(event *chat.Event) chan *chat.Event {
+	key := event.Source.ChatID
+	if key == "" {
+		key = event.Source.UserID
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return this.queues[int(hash.Sum32()%uint32(len(this.queues)))]
+}
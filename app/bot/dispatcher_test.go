@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+)
+
+func textEvent(chatID string, userID string, text string) *chat.Event {
+	source := chat.Source{Type: chat.SourceTypeUser, UserID: userID}
+	if chatID != "" {
+		source = chat.Source{Type: chat.SourceTypeGroup, ChatID: chatID, UserID: userID}
+	}
+	return &chat.Event{
+		Type:        chat.EventTypeMessage,
+		Source:      source,
+		MessageType: chat.MessageTypeText,
+		Text:        text,
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	handled := map[string][]string{}
+	handledMux := sync.Mutex{}
+	dispatcher := NewDispatcher(func(event *chat.Event) error {
+		started <- struct{}{}
+		<-release
+		text := event.Text
+		handledMux.Lock()
+		defer handledMux.Unlock()
+		key := requesterID(event.Source)
+		if event.Source.ChatID != "" {
+			key = event.Source.ChatID
+		}
+		handled[key] = append(handled[key], text)
+		if text == "fail" {
+			return errors.New("failed")
+		}
+		return nil
+	}, 1, 4)
+
+	if err := dispatcher.Enqueue([]*chat.Event{textEvent("group1", "user1", "1"), textEvent("group1", "user2", "2"), textEvent("", "user3", "a")}); err != nil {
+		t.Errorf("Dispatcher.Enqueue() == %v, want %v", err, nil)
+	}
+	// The worker is busy with the first event
+	<-started
+	if err := dispatcher.Enqueue([]*chat.Event{textEvent("group1", "user1", "3"), textEvent("group1", "user1", "4"), textEvent("group1", "user1", "5")}); err != ErrQueueFull {
+		t.Errorf("Dispatcher.Enqueue() over a worker's queue == %v, want %v", err, ErrQueueFull)
+	}
+	if err := dispatcher.Enqueue([]*chat.Event{textEvent("group1", "user1", "3"), textEvent("", "user3", "fail")}); err != nil {
+		t.Errorf("Dispatcher.Enqueue() == %v, want %v", err, nil)
+	}
+	stats := dispatcher.Stats()
+	if stats.Queued != 5 || stats.HighWater != 5 || stats.Enqueued != 5 || stats.Dropped != 3 || stats.Capacity != 4 {
+		t.Errorf("Dispatcher.Stats() before handling == %+v", stats)
+	}
+	close(release)
+	dispatcher.Stop()
+
+	if got := handled["group1"]; len(got) != 3 || got[0] != "1" || got[1] != "2" || got[2] != "3" {
+		t.Errorf("Dispatcher handled %q in group1, want them in order", got)
+	}
+	if got := handled["user3"]; len(got) != 2 || got[0] != "a" || got[1] != "fail" {
+		t.Errorf("Dispatcher handled %q for user3, want them in order", got)
+	}
+	stats = dispatcher.Stats()
+	if stats.Queued != 0 || stats.Processed != 5 || stats.Failed != 1 {
+		t.Errorf("Dispatcher.Stats() after handling == %+v", stats)
+	}
+	if err := dispatcher.Enqueue([]*chat.Event{textEvent("", "user3", "late")}); err != ErrQueueFull {
+		t.Errorf("Dispatcher.Enqueue() after Stop() == %v, want %v", err, ErrQueueFull)
+	}
+}
+
+func TestDispatcherFailure(t *testing.T) {
+	dispatcher := NewDispatcher(func(event *chat.Event) error {
+		if event.Text == "fail" {
+			return errors.New("failed")
+		}
+		return nil
+	}, 1, 2)
+	dispatcher.Enqueue([]*chat.Event{textEvent("", "user1", "fail"), textEvent("", "user1", "next")})
+	dispatcher.Stop()
+	if stats := dispatcher.Stats(); stats.Processed != 2 || stats.Failed != 1 {
+		t.Errorf("Dispatcher.Stats() after a failure == %+v, want both events handled", stats)
+	}
+}
+
+func TestDispatcherQueue(t *testing.T) {
+	dispatcher := NewDispatcher(func(event *chat.Event) error { return nil }, 8, 1)
+	defer dispatcher.Stop()
+	if dispatcher.queue(textEvent("group1", "user1", "")) != dispatcher.queue(textEvent("group1", "user2", "")) {
+		t.Errorf("Dispatcher.queue() of members of a group differ, want the group's worker")
+	}
+	if dispatcher.queue(textEvent("", "user1", "")) != dispatcher.queue(textEvent("", "user1", "")) {
+		t.Errorf("Dispatcher.queue() of a user differ, want the user's worker")
+	}
+}
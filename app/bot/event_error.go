@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+)
+
+// EventError is the failure of one event in a batch.
+type EventError struct {
+	Index int
+	Event *chat.Event
+	Err   error
+}
+
+func (this *EventError) Error() string {
+	return fmt.Sprintf("event %d (%s from %s): %v", this.Index, this.Event.Type, sourceID(this.Event.Source), this.Err)
+}
+
+func (this *EventError) Unwrap() error {
+	return this.Err
+}
+
+// ResponseError holds the failures of the events of a batch that failed.
+// The other events were handled.
+type ResponseError struct {
+	Events int
+	Errors []*EventError
+}
+
+func (this *ResponseError) Error() string {
+	texts := []string{}
+	for _, err := range this.Errors {
+		texts = append(texts, err.Error())
+	}
+	return fmt.Sprintf("%d of %d events failed: %s", len(this.Errors), this.Events, strings.Join(texts, "; "))
+}
+
+// HandleEvent handles one webhook event, turning a panic into an error so
+// that it doesn't take other events down with it.
+func (this *DictBot) HandleEvent(event *chat.Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic while handling %s event: %v\n%s", event.Type, r, debug.Stack())
+		}
+	}()
+	return this.Router.Dispatch(event)
+}
@@ -0,0 +1,221 @@
+package bot
+
+import (
+	"log"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/i18n"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+// requesterID identifies who a lookup is made for. Group and room members
+// whose user ID is unavailable are all treated as the chat itself.
+func requesterID(source chat.Source) string {
+	if source.UserID != "" {
+		return source.UserID
+	}
+	return source.ChatID
+}
+
+func (this *DictBot) group(chatID string) store.Group {
+	if this.Groups != nil {
+		if group, err := this.Groups.Get(chatID); err == nil {
+			return group
+		}
+	}
+	return store.Group{
+		ID:   chatID,
+		Mode: store.GroupModeMention,
+	}
+}
+
+// addressed drops group messages that aren't meant for the bot, before they
+// count against the rate limits.
+func (this *DictBot) addressed(next Handler) Handler {
+	return func(event *chat.Event) error {
+		if event.Type == chat.EventTypeMessage && event.MessageType == chat.MessageTypeText && event.Source.ChatID != "" && !this.isAddressed(event) {
+			return nil
+		}
+		return next(event)
+	}
+}
+
+// isAddressed reports whether a group message is a command the group's mode
+// allows or a lookup meant for the bot.
+func (this *DictBot) isAddressed(event *chat.Event) bool {
+	mode := this.group(event.Source.ChatID).Mode
+	if name, _, isCommand := this.groupCommand(event); isCommand {
+		return allowsCommand(mode, name)
+	}
+	_, ok := this.groupLookupText(event, mode)
+	return ok
+}
+
+// allowsCommand reports whether a group in the given mode answers a command.
+// The mode and the admins who change it can always be managed.
+func allowsCommand(mode string, name string) bool {
+	return mode != store.GroupModeOff || name == "mode" || name == "admins"
+}
+
+// groupCommand parses the command of a group message, which may follow a
+// mention of the bot, as in "@Choo /mode all" on platforms where mentions are
+// the only way to reach the bot in a group.
+func (this *DictBot) groupCommand(event *chat.Event) (string, []string, bool) {
+	if name, args, isCommand := ParseCommand(event.Text); isCommand {
+		return name, args, true
+	}
+	if text, ok := this.stripMention(event); ok {
+		return ParseCommand(text)
+	}
+	return "", nil, false
+}
+
+// groupLookupText returns the text to look up from a group message and
+// whether the bot was addressed at all.
+func (this *DictBot) groupLookupText(event *chat.Event, mode string) (string, bool) {
+	if mode == store.GroupModeOff {
+		return "", false
+	}
+	text := strings.TrimSpace(event.Text)
+	if this.GroupPrefix != "" && strings.HasPrefix(text, this.GroupPrefix) {
+		return strings.TrimSpace(strings.TrimPrefix(text, this.GroupPrefix)), true
+	}
+	if text, ok := this.stripMention(event); ok {
+		return text, true
+	}
+	if mode == store.GroupModeAll {
+		return text, true
+	}
+	return "", false
+}
+
+func (this *DictBot) stripMention(event *chat.Event) (string, bool) {
+	// Mention positions are counted in UTF-16 code units
+	units := utf16.Encode([]rune(event.Text))
+	for _, mention := range event.Mentions {
+		if !this.isBot(mention.UserID) || mention.Index < 0 || mention.Index+mention.Length > len(units) {
+			continue
+		}
+		text := string(utf16.Decode(units[:mention.Index])) + " " + string(utf16.Decode(units[mention.Index+mention.Length:]))
+		return strings.Join(strings.Fields(text), " "), true
+	}
+	return "", false
+}
+
+func (this *DictBot) isBot(userID string) bool {
+	for _, botUserID := range this.BotUserIDs {
+		if botUserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *DictBot) isAdmin(group store.Group, userID string) bool {
+	if userID == "" {
+		return false
+	}
+	for _, admin := range this.Admins {
+		if admin == userID {
+			return true
+		}
+	}
+	return group.IsAdmin(userID)
+}
+
+// canManage reports whether a user may change a group's settings: bot admins,
+// the group's admins and the admins of the chat on its platform.
+func (this *DictBot) canManage(group store.Group, userID string) bool {
+	if this.isAdmin(group, userID) {
+		return true
+	}
+	if userID == "" {
+		return false
+	}
+	isGroupAdmin, err := this.Adapter.IsGroupAdmin(group.ID, userID)
+	if err != nil && err != chat.ErrNotSupported {
+		log.Println(err)
+	}
+	return isGroupAdmin
+}
+
+func (this *DictBot) handleModeCommand(event *chat.Event, chatID string, args []string) error {
+	group := this.group(chatID)
+	if len(args) == 0 {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.ModeStatus, group.Mode)))
+	}
+	mode := strings.ToLower(args[0])
+	if mode != store.GroupModeMention && mode != store.GroupModeAll && mode != store.GroupModeOff {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.UnknownMode, args[0])))
+	}
+	if !this.canManage(group, event.Source.UserID) {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.GroupAdminsOnly)))
+	}
+	if this.Groups == nil {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.GroupsUnavailable)))
+	}
+	group.Mode = mode
+	if err := this.Groups.Save(group); err != nil {
+		return err
+	}
+	replyMessage := this.text(event.Source, i18n.ModeMention, this.GroupPrefix)
+	if mode == store.GroupModeAll {
+		replyMessage = this.text(event.Source, i18n.ModeAll)
+	} else if mode == store.GroupModeOff {
+		replyMessage = this.text(event.Source, i18n.ModeOff)
+	}
+	return this.reply(event, chat.NewTextMessage(replyMessage))
+}
+
+// handleAdminsCommand lists a group's admins, or adds or removes the members
+// mentioned in the command or given by ID.
+func (this *DictBot) handleAdminsCommand(event *chat.Event, chatID string, args []string) error {
+	group := this.group(chatID)
+	if len(args) == 0 {
+		if len(group.Admins) == 0 {
+			return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.NoGroupAdmins)))
+		}
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.GroupAdminsList, strings.Join(group.Admins, ", "))))
+	}
+	action := strings.ToLower(args[0])
+	targets := []string{}
+	for _, mention := range event.Mentions {
+		if !this.isBot(mention.UserID) {
+			targets = append(targets, mention.UserID)
+		}
+	}
+	if len(targets) == 0 {
+		targets = args[1:]
+	}
+	if (action != "add" && action != "remove") || len(targets) == 0 {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.Usage, "/admins [add|remove] @member")))
+	}
+	if !this.canManage(group, event.Source.UserID) {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.ManageAdminsOnly)))
+	}
+	if this.Groups == nil {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.GroupsUnavailable)))
+	}
+	for _, target := range targets {
+		if action == "add" && !group.IsAdmin(target) {
+			group.Admins = append(group.Admins, target)
+		} else if action == "remove" {
+			admins := []string{}
+			for _, admin := range group.Admins {
+				if admin != target {
+					admins = append(admins, admin)
+				}
+			}
+			group.Admins = admins
+		}
+	}
+	if err := this.Groups.Save(group); err != nil {
+		return err
+	}
+	if len(group.Admins) == 0 {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.NoGroupAdmins)))
+	}
+	return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.GroupAdminsList, strings.Join(group.Admins, ", "))))
+}
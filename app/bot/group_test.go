@@ -0,0 +1,190 @@
+package bot
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/chat/slack"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+func TestDictBotGroupLookupText(t *testing.T) {
+	bot := &DictBot{BotUserIDs: []string{"bot"}, GroupPrefix: "?"}
+	mention := func(index int, length int, userID string) []chat.Mention {
+		return []chat.Mention{{UserID: userID, Index: index, Length: length}}
+	}
+	cases := []struct {
+		message chat.Event
+		mode    string
+		want    string
+		ok      bool
+	}{
+		{chat.Event{Text: "hello everyone"}, store.GroupModeMention, "", false},
+		{chat.Event{Text: "hello everyone"}, store.GroupModeAll, "hello everyone", true},
+		{chat.Event{Text: "?line"}, store.GroupModeMention, "line", true},
+		{chat.Event{Text: " ? line "}, store.GroupModeAll, "line", true},
+		{chat.Event{Text: "?line"}, store.GroupModeOff, "", false},
+		{chat.Event{Text: "@Choo line", Mentions: mention(0, 5, "bot")}, store.GroupModeMention, "line", true},
+		{chat.Event{Text: "square @Choo", Mentions: mention(7, 5, "bot")}, store.GroupModeMention, "square", true},
+		{chat.Event{Text: "😀 @Choo line", Mentions: mention(3, 5, "bot")}, store.GroupModeMention, "😀 line", true},
+		{chat.Event{Text: "@Choo", Mentions: mention(0, 5, "bot")}, store.GroupModeMention, "", true},
+		{chat.Event{Text: "@Friend line", Mentions: mention(0, 7, "friend")}, store.GroupModeMention, "", false},
+		{chat.Event{Text: "@Choo line", Mentions: mention(3, 50, "bot")}, store.GroupModeMention, "", false},
+	}
+	for _, c := range cases {
+		got, ok := bot.groupLookupText(&c.message, c.mode)
+		if got != c.want || ok != c.ok {
+			t.Errorf("DictBot.groupLookupText(%q, %q) == %q, %v, want %q, %v", c.message.Text, c.mode, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestRequesterID(t *testing.T) {
+	cases := []struct {
+		in   chat.Source
+		want string
+	}{
+		{chat.Source{Type: chat.SourceTypeUser, UserID: "user1"}, "user1"},
+		{chat.Source{Type: chat.SourceTypeGroup, ChatID: "group1", UserID: "user1"}, "user1"},
+		{chat.Source{Type: chat.SourceTypeGroup, ChatID: "group1"}, "group1"},
+		{chat.Source{Type: chat.SourceTypeRoom, ChatID: "room1"}, "room1"},
+	}
+	for _, c := range cases {
+		got := requesterID(c.in)
+		if got != c.want {
+			t.Errorf("requesterID(%+v) == %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestDictBotResponseGroup(t *testing.T) {
+	adapter := newFakeAdapter()
+	bot := NewDictBot(mockServiceController{}, adapter)
+	bot.Groups = store.NewMemoryGroupStore()
+	bot.Admins = []string{"owner"}
+	adapter.groupAdmins = []string{"chatadmin"}
+	send := func(userID string, text string) string {
+		before := len(adapter.Requests())
+		event := &chat.Event{
+			Type:        chat.EventTypeMessage,
+			MessageType: chat.MessageTypeText, Text: text,
+			Source:     chat.Source{Type: chat.SourceTypeGroup, ChatID: "group1", UserID: userID},
+			ReplyToken: "dummy",
+		}
+		if err := bot.Response([]*chat.Event{event}); err != nil {
+			t.Errorf("DictBot.Response(%q) == %v, want %v", text, err, nil)
+		}
+		requests := adapter.Requests()
+		if len(requests) == before {
+			return ""
+		}
+		return requests[len(requests)-1]
+	}
+	cases := []struct {
+		userID string
+		text   string
+		want   string
+	}{
+		{"user1", "line", ""},
+		{"user1", "?line", `"text":"dummy"`},
+		{"user1", "/mode", "The current mode is 'mention'."},
+		{"user1", "/mode loud", "Unknown mode 'loud'"},
+		{"user1", "/mode all", "only group admins can change the mode"},
+		{"user1", "/admins", "This group has no admins yet."},
+		{"user1", "/admins add user1", "only group admins can add or remove admins"},
+		{"owner", "/admins add user1", "Group admins: user1."},
+		{"user1", "/mode all", "I'll look up every message"},
+		{"user2", "/mode off", "only group admins can change the mode"},
+		{"user1", "line", `"text":"dummy"`},
+		{"chatadmin", "/mode off", "I'll stay quiet"},
+		{"user1", "?line", ""},
+		{"user1", "/admins add user2", "Group admins: user1, user2."},
+		{"user2", "/mode mention", "I'll only answer when mentioned"},
+		{"owner", "/admins remove user1 user2", "This group has no admins yet."},
+		{"user1", "/mode all", "only group admins can change the mode"},
+	}
+	for _, c := range cases {
+		got := send(c.userID, c.text)
+		if (c.want == "" && got != "") || !strings.Contains(got, c.want) {
+			t.Errorf("DictBot.Response(%q from %q) replied %q, want %q", c.text, c.userID, got, c.want)
+		}
+	}
+}
+
+func TestDictBotResponseGroupRateLimit(t *testing.T) {
+	adapter := newFakeAdapter()
+	bot := NewDictBot(mockServiceController{}, adapter)
+	bot.Groups = store.NewMemoryGroupStore()
+	send := func(text string) int {
+		before := len(adapter.Requests())
+		event := &chat.Event{
+			Type:        chat.EventTypeMessage,
+			MessageType: chat.MessageTypeText, Text: text,
+			Source:     chat.Source{Type: chat.SourceTypeGroup, ChatID: "group1", UserID: "user1"},
+			ReplyToken: "dummy",
+		}
+		bot.Response([]*chat.Event{event})
+		return len(adapter.Requests()) - before
+	}
+
+	// Chatter that isn't for the bot doesn't count against the limits
+	for i := 0; i < 100; i++ {
+		if n := send("line"); n != 0 {
+			t.Fatalf("DictBot.Response(%q) sent %d messages, want 0", "line", n)
+		}
+	}
+	if n := send("?line"); n != 1 || strings.Contains(adapter.Requests()[len(adapter.Requests())-1], "too fast") {
+		t.Errorf("DictBot.Response(%q) after chatter sent %d messages, want a lookup", "?line", n)
+	}
+
+	// In "off" mode even the rate limit notice stays quiet
+	bot.Groups.Save(store.Group{ID: "group1", Mode: store.GroupModeOff})
+	for i := 0; i < 30; i++ {
+		send("/mode")
+	}
+	if got := adapter.Requests(); strings.Contains(got[len(got)-1], "too fast") {
+		t.Errorf("DictBot.Response(%q) in off mode replied %q, want no rate limit notice", "/mode", got[len(got)-1])
+	}
+}
+
+func TestDictBotResponseSlackGroupOff(t *testing.T) {
+	adapter := newFakeAdapter()
+	bot := NewDictBot(mockServiceController{}, adapter)
+	bot.Groups = store.NewMemoryGroupStore()
+	bot.BotUserIDs = []string{slack.ID("B1")}
+	bot.Groups.Save(store.Group{ID: slack.ID("C1"), Mode: store.GroupModeOff})
+	adapter.groupAdmins = []string{slack.ID("U1")}
+	mention := func(text string) *chat.Event {
+		return slack.Event(&slack.EventCallback{Type: "event_callback", Event: &slack.MessageEvent{Type: "app_mention", User: "U1", Channel: "C1", Text: text}})
+	}
+	slash := func(command string, text string) *chat.Event {
+		return slack.SlashCommandEvent(url.Values{"command": {command}, "text": {text}, "channel_id": {"C1"}, "user_id": {"U1"}, "response_url": {"https://hooks.slack.com/1"}})
+	}
+	cases := []struct {
+		event *chat.Event
+		want  string
+	}{
+		{mention("<@B1> line"), ""},
+		{slash("/define", "line"), ""},
+		{mention("<@B1> /help"), ""},
+		{mention("<@B1> /mode"), "The current mode is 'off'."},
+		{slash("/mode", ""), "The current mode is 'off'."},
+		{mention("<@B1> /mode mention"), "I'll only answer when mentioned"},
+		{mention("<@B1> line"), `"text":"dummy"`},
+	}
+	for _, c := range cases {
+		before := len(adapter.Requests())
+		if err := bot.Response([]*chat.Event{c.event}); err != nil {
+			t.Errorf("DictBot.Response(%q) == %v, want %v", c.event.Text, err, nil)
+		}
+		got := ""
+		if requests := adapter.Requests(); len(requests) > before {
+			got = requests[len(requests)-1]
+		}
+		if (c.want == "" && got != "") || !strings.Contains(got, c.want) {
+			t.Errorf("DictBot.Response(%q) in an off channel replied %q, want %q", c.event.Text, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package bot
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/i18n"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+const (
+	ActionHistoryPage           = "hist"
+	ActionHistoryClear          = "hist_clear"
+	PreferenceWeeklySummary     = "weekly_summary"
+	preferenceLastWeeklySummary = "weekly_summary_last_push"
+	historyPageSize             = 10
+	recentWordsLimit            = 10
+	weeklySummaryHour           = 9
+	weeklySummaryTopWords       = 3
+)
+
+func (this *DictBot) registerHistoryCommands() {
+	this.Commands.Register(&Command{
+		Name:    "history",
+		Aliases: []string{"hist"},
+		Usage:   "/history [page|clear|summary [on|off]]",
+		Descriptions: map[string]string{
+			"en": "Show or clear the words you looked up",
+			"th": "ดูหรือล้างประวัติคำที่ค้นหา",
+		},
+		Handler: this.handleHistoryCommand,
+	})
+	this.Commands.Register(&Command{
+		Name:    "recent",
+		Aliases: []string{"r"},
+		Usage:   "/recent",
+		Descriptions: map[string]string{
+			"en": "Look up one of your recent words again",
+			"th": "ค้นหาคำที่เพิ่งค้นหาไปอีกครั้ง",
+		},
+		Handler: this.handleRecentCommand,
+	})
+	this.Router.HandlePostback(ActionHistoryPage, func(event *chat.Event, postback Postback) error {
+		page, _ := strconv.Atoi(postback.Word)
+		return this.showHistory(event, page)
+	})
+	this.Router.HandlePostback(ActionHistoryClear, this.handleHistoryClear)
+}
+
+// recordLookup adds a successful lookup to the user's history. Lookups of
+// members whose user ID is unknown can't be recorded.
+func (this *DictBot) recordLookup(source chat.Source, word string, found bool) {
+	if this.History == nil || source.UserID == "" || !found {
+		return
+	}
+	entry := store.HistoryEntry{Word: strings.ToLower(word), LookedUpAt: this.Clock.Now()}
+	if err := this.History.Add(source.UserID, entry); err != nil {
+		log.Println(err)
+	}
+}
+
+// historyUserID returns the user's ID, or "" after replying why the history
+// can't be used for this event.
+func (this *DictBot) historyUserID(event *chat.Event) (string, error) {
+	if this.History == nil {
+		return "", this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.HistoryUnavailable)))
+	}
+	if event.Source.UserID == "" {
+		return "", this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.HistoryNeedsFriend)))
+	}
+	return event.Source.UserID, nil
+}
+
+func (this *DictBot) handleHistoryCommand(event *chat.Event, args []string) error {
+	if len(args) == 0 {
+		return this.showHistory(event, 1)
+	}
+	switch strings.ToLower(args[0]) {
+	case "clear":
+		userID, err := this.historyUserID(event)
+		if userID == "" {
+			return err
+		}
+		_, total, err := this.History.List(userID, 0, 0)
+		if err != nil {
+			return err
+		}
+		if total == 0 {
+			return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.HistoryAlreadyEmpty)))
+		}
+		data := Postback{Action: ActionHistoryClear}.Encode()
+		button := chat.NewPostbackButton(this.text(event.Source, i18n.ClearHistory), data)
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.ConfirmClearHistory, total)).WithQuickReplies(button))
+	case "summary":
+		return this.handleSummaryCommand(event, args[1:])
+	}
+	page, err := strconv.Atoi(args[0])
+	if err != nil {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.Usage, "/history [page|clear|summary [on|off]]")))
+	}
+	return this.showHistory(event, page)
+}
+
+func (this *DictBot) showHistory(event *chat.Event, page int) error {
+	userID, err := this.historyUserID(event)
+	if userID == "" {
+		return err
+	}
+	if page < 1 {
+		page = 1
+	}
+	entries, total, err := this.History.List(userID, (page-1)*historyPageSize, historyPageSize)
+	if err != nil {
+		return err
+	}
+	if total == 0 {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.HistoryEmpty)))
+	}
+	pages := (total + historyPageSize - 1) / historyPageSize
+	if len(entries) == 0 {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.HistoryPages, pages)))
+	}
+	location := this.location(userID)
+	lines := []string{this.text(event.Source, i18n.HistoryPage, page, pages)}
+	for _, entry := range entries {
+		lines = append(lines, entry.LookedUpAt.In(location).Format("02 Jan 15:04")+" "+entry.Word)
+	}
+	buttons := []chat.Button{}
+	if page > 1 {
+		data := Postback{Action: ActionHistoryPage, Word: strconv.Itoa(page - 1)}.Encode()
+		buttons = append(buttons, chat.NewPostbackButton(this.text(event.Source, i18n.PreviousPage), data))
+	}
+	if page < pages {
+		data := Postback{Action: ActionHistoryPage, Word: strconv.Itoa(page + 1)}.Encode()
+		buttons = append(buttons, chat.NewPostbackButton(this.text(event.Source, i18n.NextPage), data))
+	}
+	message := chat.NewTextMessage(strings.Join(lines, "\n"))
+	if len(buttons) > 0 {
+		return this.reply(event, message.WithQuickReplies(buttons...))
+	}
+	return this.reply(event, message)
+}
+
+func (this *DictBot) handleHistoryClear(event *chat.Event, postback Postback) error {
+	userID, err := this.historyUserID(event)
+	if userID == "" {
+		return err
+	}
+	cleared, err := this.History.Clear(userID)
+	if err != nil {
+		return err
+	}
+	return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.HistoryCleared, cleared)))
+}
+
+func (this *DictBot) handleRecentCommand(event *chat.Event, args []string) error {
+	userID, err := this.historyUserID(event)
+	if userID == "" {
+		return err
+	}
+	words, err := this.History.RecentWords(userID, recentWordsLimit)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.HistoryEmpty)))
+	}
+	prefix := ""
+	if event.Source.ChatID != "" {
+		prefix = this.GroupPrefix
+	}
+	buttons := []chat.Button{}
+	for _, word := range words {
+		// Quick reply labels are limited to 20 characters
+		if len([]rune(word)) <= 20 {
+			buttons = append(buttons, chat.NewMessageButton(word, prefix+word))
+		}
+	}
+	message := chat.NewTextMessage(this.text(event.Source, i18n.RecentWords, strings.Join(words, "\n")))
+	if len(buttons) > 0 {
+		return this.reply(event, message.WithQuickReplies(buttons...))
+	}
+	return this.reply(event, message)
+}
+
+func (this *DictBot) handleSummaryCommand(event *chat.Event, args []string) error {
+	userID, err := this.historyUserID(event)
+	if userID == "" {
+		return err
+	}
+	if len(args) > 0 {
+		setting := strings.ToLower(args[0])
+		if setting != "on" && setting != "off" {
+			return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.Usage, "/history summary [on|off]")))
+		}
+		if err := this.setPreference(userID, PreferenceWeeklySummary, setting); err != nil {
+			return err
+		}
+		if setting == "off" {
+			return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.SummaryOff)))
+		}
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.SummaryOn)))
+	}
+	summary, err := this.weeklySummary(userID, this.Clock.Now())
+	if err != nil {
+		return err
+	}
+	if summary == "" {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.NoLookupsThisWeek)))
+	}
+	return this.reply(event, chat.NewTextMessage(summary))
+}
+
+// weeklySummary describes the user's lookups of the past 7 days, or returns
+// "" when there were none.
+func (this *DictBot) weeklySummary(userID string, now time.Time) (string, error) {
+	entries, err := this.History.Since(userID, now.AddDate(0, 0, -7))
+	if err != nil || len(entries) == 0 {
+		return "", err
+	}
+	counts := map[string]int{}
+	for _, entry := range entries {
+		counts[entry.Word]++
+	}
+	words := []string{}
+	for word := range counts {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if counts[words[i]] == counts[words[j]] {
+			return words[i] < words[j]
+		}
+		return counts[words[i]] > counts[words[j]]
+	})
+	source := chat.Source{Type: chat.SourceTypeUser, UserID: userID}
+	text := this.text(source, i18n.WeeklyLookup)
+	if len(entries) > 1 {
+		different := ""
+		if len(words) < len(entries) {
+			different = this.text(source, i18n.WeeklyDifferent, len(words))
+		}
+		text = this.text(source, i18n.WeeklyLookups, len(entries), different)
+	}
+	top := []string{}
+	for _, word := range words {
+		if len(top) == weeklySummaryTopWords || counts[word] < 2 {
+			break
+		}
+		top = append(top, word+" ("+strconv.Itoa(counts[word])+")")
+	}
+	if len(top) > 0 {
+		text += this.text(source, i18n.MostLookedUp, strings.Join(top, ", "))
+	}
+	return text, nil
+}
+
+// PushWeeklySummaries pushes each user who looked words up in the past week
+// a summary on Monday morning in their time zone, outside their quiet hours.
+// It is meant to be run by the scheduler every few minutes.
+func (this *DictBot) PushWeeklySummaries(now time.Time) error {
+	if this.History == nil || this.Registry == nil {
+		return nil
+	}
+	userIDs, err := this.History.Users(now.AddDate(0, 0, -7))
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if user, err := this.Registry.Get(userID); err == nil && !user.Active {
+			continue
+		}
+		if this.preference(userID, PreferenceWeeklySummary, "on") == "off" {
+			continue
+		}
+		local := now.In(this.location(userID))
+		if local.Weekday() != time.Monday || local.Hour() < weeklySummaryHour || InQuietHours(this.preference(userID, PreferenceQuietHours, defaultQuietHours), local.Hour()) {
+			continue
+		}
+		year, week := local.ISOWeek()
+		thisWeek := strconv.Itoa(year) + "-W" + strconv.Itoa(week)
+		if this.preference(userID, preferenceLastWeeklySummary, "") == thisWeek {
+			continue
+		}
+		summary, err := this.weeklySummary(userID, now)
+		if err != nil || summary == "" {
+			continue
+		}
+		if err := this.Adapter.Push(userID, chat.NewTextMessage(summary)); err != nil {
+			log.Println(err)
+			continue
+		}
+		if err := this.setPreference(userID, preferenceLastWeeklySummary, thisWeek); err != nil {
+			log.Println(err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/scheduler"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+func TestDictBotResponseHistory(t *testing.T) {
+	adapter := newFakeAdapter()
+	clock := scheduler.NewFakeClock(time.Date(2018, 11, 20, 3, 0, 0, 0, time.UTC))
+	bot := NewDictBot(mockServiceController{}, adapter)
+	bot.Clock = clock
+	bot.History = store.NewMemoryHistory()
+	send := func(source chat.Source, event *chat.Event) string {
+		event.Source = source
+		event.ReplyToken = "dummy"
+		if err := bot.Response([]*chat.Event{event}); err != nil {
+			t.Errorf("DictBot.Response(%v) == %v, want %v", event.Type, err, nil)
+		}
+		requests := adapter.Requests()
+		return requests[len(requests)-1]
+	}
+	text := func(text string) *chat.Event {
+		return &chat.Event{Type: chat.EventTypeMessage, MessageType: chat.MessageTypeText, Text: text}
+	}
+	user := chat.Source{Type: chat.SourceTypeUser, UserID: "user1"}
+	group := chat.Source{Type: chat.SourceTypeGroup, ChatID: "group1", UserID: "user1"}
+	anonymous := chat.Source{Type: chat.SourceTypeGroup, ChatID: "group1"}
+
+	if got := send(user, text("/history")); !strings.Contains(got, "You haven't looked anything up yet.") {
+		t.Errorf("DictBot.Response(/history) replied %q", got)
+	}
+	for _, word := range []string{"Serendipity", "line", "error_word", "serendipity"} {
+		send(user, text(word))
+		clock.Advance(time.Minute)
+	}
+	send(group, text("?square"))
+	send(anonymous, text("?circle"))
+	send(user, text("/def dot"))
+	// Stay under the rate limit
+	for i := 1; i < historyPageSize; i++ {
+		bot.History.Add("user1", store.HistoryEntry{Word: "dot", LookedUpAt: clock.Now()})
+	}
+
+	if got := send(user, text("/history")); !strings.Contains(got, `Your lookups (page 1/2):\n20 Nov 10:04 dot`) || !strings.Contains(got, `"data":"1|hist|2"`) {
+		t.Errorf("DictBot.Response(/history) replied %q", got)
+	}
+	postback := &chat.Event{Type: chat.EventTypePostback, Postback: Postback{Action: ActionHistoryPage, Word: "2"}.Encode()}
+	if got := send(user, postback); !strings.Contains(got, `20 Nov 10:04 square\n20 Nov 10:03 serendipity\n20 Nov 10:01 line\n20 Nov 10:00 serendipity"`) || !strings.Contains(got, `"data":"1|hist|1"`) {
+		t.Errorf("DictBot.Response(history page 2) replied %q", got)
+	}
+	if got := send(user, text("/recent")); !strings.Contains(got, `Your recent words:\ndot\nsquare\nserendipity\nline\n`) || !strings.Contains(got, `{"label":"square","text":"square"}`) {
+		t.Errorf("DictBot.Response(/recent) replied %q", got)
+	}
+	if got := send(group, text("/recent")); !strings.Contains(got, `{"label":"square","text":"?square"}`) {
+		t.Errorf("DictBot.Response(/recent) in a group replied %q", got)
+	}
+	if got := send(anonymous, text("/recent")); !strings.Contains(got, "Please add me as a friend") {
+		t.Errorf("DictBot.Response(/recent) without a user ID replied %q", got)
+	}
+	if got := send(user, text("/history summary")); !strings.Contains(got, `You looked up 14 words this week, 4 of them different.\nMost looked up: dot (10), serendipity (2).`) {
+		t.Errorf("DictBot.Response(/history summary) replied %q", got)
+	}
+	if got := send(user, text("/history clear")); !strings.Contains(got, "Clear all 14 lookups") || !strings.Contains(got, `"data":"1|hist_clear|"`) {
+		t.Errorf("DictBot.Response(/history clear) replied %q", got)
+	}
+	clear := &chat.Event{Type: chat.EventTypePostback, Postback: Postback{Action: ActionHistoryClear}.Encode()}
+	if got := send(user, clear); !strings.Contains(got, "Cleared 14 lookups from your history.") {
+		t.Errorf("DictBot.Response(clear history) replied %q", got)
+	}
+	if got := send(user, text("/history clear")); !strings.Contains(got, "Your history is already empty.") {
+		t.Errorf("DictBot.Response(/history clear) replied %q", got)
+	}
+}
+
+func TestDictBotPushWeeklySummaries(t *testing.T) {
+	adapter := newFakeAdapter()
+	// Sunday 18 Nov 2018, 09:30 in Bangkok
+	clock := scheduler.NewFakeClock(time.Date(2018, 11, 18, 2, 30, 0, 0, time.UTC))
+	bot := NewDictBot(mockServiceController{}, adapter)
+	bot.Clock = clock
+	bot.Registry = store.NewMemoryUserRegistry()
+	bot.History = store.NewMemoryHistory()
+	for _, userID := range []string{"user1", "off", "unfollowed"} {
+		bot.Registry.Follow(store.User{ID: userID})
+		bot.History.Add(userID, store.HistoryEntry{Word: "line", LookedUpAt: clock.Now()})
+	}
+	bot.Registry.SetPreference("off", PreferenceWeeklySummary, "off")
+	bot.Registry.Unfollow("unfollowed", clock.Now())
+	bot.History.Add("old", store.HistoryEntry{Word: "line", LookedUpAt: clock.Now().AddDate(0, 0, -8)})
+
+	pushes := func() []string {
+		pushes := []string{}
+		for _, request := range adapter.Requests() {
+			if strings.HasPrefix(request, "push") {
+				pushes = append(pushes, request)
+			}
+		}
+		return pushes
+	}
+	bot.PushWeeklySummaries(clock.Now())
+	if got := pushes(); len(got) != 0 {
+		t.Errorf("DictBot.PushWeeklySummaries() on Sunday pushed %q", got)
+	}
+	clock.Advance(23 * time.Hour)
+	bot.PushWeeklySummaries(clock.Now())
+	if got := pushes(); len(got) != 0 {
+		t.Errorf("DictBot.PushWeeklySummaries() before 09:00 pushed %q", got)
+	}
+	clock.Advance(time.Hour)
+	bot.PushWeeklySummaries(clock.Now())
+	bot.PushWeeklySummaries(clock.Now())
+	if got := pushes(); len(got) != 1 || !strings.Contains(got[0], `"to":"user1"`) || !strings.Contains(got[0], "You looked up 1 word this week.") {
+		t.Errorf("DictBot.PushWeeklySummaries() pushed %q", got)
+	}
+	clock.Advance(7 * 24 * time.Hour)
+	bot.History.Add("user1", store.HistoryEntry{Word: "dot", LookedUpAt: clock.Now()})
+	bot.PushWeeklySummaries(clock.Now())
+	if got := pushes(); len(got) != 2 {
+		t.Errorf("DictBot.PushWeeklySummaries() the week after pushed %d summaries, want %d", len(got), 2)
+	}
+}
@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+)
+
+func LoggingMiddleware(logger *log.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(event *chat.Event) error {
+			start := time.Now()
+			err := next(event)
+			if err != nil {
+				logger.Printf("event=%s source=%s took=%s error=%v", event.Type, sourceID(event.Source), time.Since(start), err)
+			} else {
+				logger.Printf("event=%s source=%s took=%s", event.Type, sourceID(event.Source), time.Since(start))
+			}
+			return err
+		}
+	}
+}
+
+func RecoveryMiddleware(next Handler) Handler {
+	return func(event *chat.Event) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("panic while handling %s event: %v\n%s", event.Type, r, debug.Stack())
+			}
+		}()
+		return next(event)
+	}
+}
+
+type RateLimiter struct {
+	max       int
+	interval  time.Duration
+	key       func(event *chat.Event) string
+	onLimited Handler
+	now       func() time.Time
+	events    map[string][]time.Time
+	lastSweep time.Time
+	eventsMux sync.Mutex
+}
+
+// NewUserRateLimiter allows each user at most max events per interval.
+// Events over the limit are passed to onLimited, which may be nil.
+func NewUserRateLimiter(max int, interval time.Duration, onLimited Handler) *RateLimiter {
+	return newRateLimiter(max, interval, func(event *chat.Event) string {
+		return event.Source.UserID
+	}, onLimited)
+}
+
+// NewChatRateLimiter allows each group or room at most max events per
+// interval, whoever sends them. One-to-one chats are not limited.
+func NewChatRateLimiter(max int, interval time.Duration, onLimited Handler) *RateLimiter {
+	return newRateLimiter(max, interval, func(event *chat.Event) string {
+		return event.Source.ChatID
+	}, onLimited)
+}
+
+func newRateLimiter(max int, interval time.Duration, key func(event *chat.Event) string, onLimited Handler) *RateLimiter {
+	return &RateLimiter{
+		max:       max,
+		interval:  interval,
+		key:       key,
+		onLimited: onLimited,
+		now:       time.Now,
+		events:    map[string][]time.Time{},
+	}
+}
+
+func (this *RateLimiter) Allow(key string) bool {
+	this.eventsMux.Lock()
+	defer this.eventsMux.Unlock()
+	now := this.now()
+	if now.Sub(this.lastSweep) >= this.interval {
+		for id, times := range this.events {
+			if len(times) == 0 || now.Sub(times[len(times)-1]) >= this.interval {
+				delete(this.events, id)
+			}
+		}
+		this.lastSweep = now
+	}
+	recent := []time.Time{}
+	for _, t := range this.events[key] {
+		if now.Sub(t) < this.interval {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= this.max {
+		this.events[key] = recent
+		return false
+	}
+	this.events[key] = append(recent, now)
+	return true
+}
+
+func (this *RateLimiter) Middleware(next Handler) Handler {
+	return func(event *chat.Event) error {
+		key := this.key(event)
+		if key == "" || this.Allow(key) {
+			return next(event)
+		}
+		if this.onLimited != nil {
+			return this.onLimited(event)
+		}
+		return nil
+	}
+}
+
+func sourceID(source chat.Source) string {
+	if source.ChatID != "" {
+		return string(source.Type) + ":" + source.ChatID + "/" + source.UserID
+	}
+	return source.UserID
+}
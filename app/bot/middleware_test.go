@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	buf := &bytes.Buffer{}
+	handler := LoggingMiddleware(log.New(buf, "", 0))(func(event *chat.Event) error {
+		return nil
+	})
+	event := &chat.Event{
+		Type:   chat.EventTypeMessage,
+		Source: chat.Source{Type: chat.SourceTypeGroup, ChatID: "group1", UserID: "user1"},
+	}
+	handler(event)
+	want := "event=message source=group:group1/user1"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("LoggingMiddleware logged %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	handler := RecoveryMiddleware(func(event *chat.Event) error {
+		panic("dummy")
+	})
+	err := handler(&chat.Event{Type: chat.EventTypeMessage})
+	want := "panic while handling message event: dummy"
+	if err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("RecoveryMiddleware() == %v, want %q", err, want)
+	}
+}
+
+func TestUserRateLimiter(t *testing.T) {
+	limited := 0
+	rateLimiter := NewUserRateLimiter(2, time.Minute, func(event *chat.Event) error {
+		limited++
+		return nil
+	})
+	now := time.Now()
+	rateLimiter.now = func() time.Time {
+		return now
+	}
+	handled := 0
+	handler := rateLimiter.Middleware(func(event *chat.Event) error {
+		handled++
+		return nil
+	})
+	user1 := &chat.Event{Source: chat.Source{UserID: "user1"}}
+	user2 := &chat.Event{Source: chat.Source{UserID: "user2"}}
+	for i := 0; i < 3; i++ {
+		handler(user1)
+	}
+	handler(user2)
+	if handled != 3 || limited != 1 {
+		t.Errorf("UserRateLimiter handled %d, limited %d, want %d, %d", handled, limited, 3, 1)
+	}
+
+	// The window slides
+	now = now.Add(time.Minute)
+	handler(user1)
+	if handled != 4 || limited != 1 {
+		t.Errorf("UserRateLimiter handled %d, limited %d, want %d, %d", handled, limited, 4, 1)
+	}
+	if len(rateLimiter.events) != 1 {
+		t.Errorf("UserRateLimiter tracks %d users, want %d", len(rateLimiter.events), 1)
+	}
+}
+
+func TestChatRateLimiter(t *testing.T) {
+	rateLimiter := NewChatRateLimiter(1, time.Minute, nil)
+	handled := 0
+	handler := rateLimiter.Middleware(func(event *chat.Event) error {
+		handled++
+		return nil
+	})
+	group := &chat.Event{Source: chat.Source{Type: chat.SourceTypeGroup, ChatID: "group1", UserID: "user1"}}
+	groupOtherUser := &chat.Event{Source: chat.Source{Type: chat.SourceTypeGroup, ChatID: "group1", UserID: "user2"}}
+	room := &chat.Event{Source: chat.Source{Type: chat.SourceTypeRoom, ChatID: "room1"}}
+	user := &chat.Event{Source: chat.Source{Type: chat.SourceTypeUser, UserID: "user1"}}
+	for _, event := range []*chat.Event{group, groupOtherUser, room, user, user} {
+		handler(event)
+	}
+	if handled != 4 {
+		t.Errorf("ChatRateLimiter handled %d, want %d", handled, 4)
+	}
+}
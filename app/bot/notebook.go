@@ -0,0 +1,270 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/i18n"
+	"github.com/choobot/choo-dict-bot/app/service"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+const (
+	ActionNotebookPage = "nb"
+	notebookPageSize   = 10
+	// LINE allows 5 messages of 5000 characters in one reply
+	maxReplyMessages      = 5
+	maxTextMessageLength  = 5000
+	maxLookupCacheEntries = 1000
+)
+
+type lookupResult struct {
+	definitions string
+	synonyms    []string
+}
+
+// lookupCache remembers recent lookups so saving a word right after looking
+// it up doesn't call the dictionary again.
+type lookupCache struct {
+	results    map[string]lookupResult
+	order      []string
+	resultsMux sync.Mutex
+}
+
+func newLookupCache() *lookupCache {
+	return &lookupCache{
+		results: map[string]lookupResult{},
+	}
+}
+
+func (this *lookupCache) add(word string, result lookupResult) {
+	this.resultsMux.Lock()
+	defer this.resultsMux.Unlock()
+	word = strings.ToLower(word)
+	if _, ok := this.results[word]; !ok {
+		this.order = append(this.order, word)
+	}
+	this.results[word] = result
+	if len(this.order) > maxLookupCacheEntries {
+		delete(this.results, this.order[0])
+		this.order = this.order[1:]
+	}
+}
+
+func (this *lookupCache) get(word string) (lookupResult, bool) {
+	this.resultsMux.Lock()
+	defer this.resultsMux.Unlock()
+	result, ok := this.results[strings.ToLower(word)]
+	return result, ok
+}
+
+// cachedLookup returns the definitions and synonyms of a word, calling the
+// dictionary on behalf of the source only when the word wasn't looked up
+// recently.
+func (this *DictBot) cachedLookup(source chat.Source, word string) (lookupResult, error) {
+	if result, ok := this.lookups.get(word); ok {
+		return result, nil
+	}
+	definitions, synonyms, err := this.ServiceController.FindDefinitionsAndSynonyms(requesterID(source), source.ChatID, word)
+	if err != nil {
+		return lookupResult{}, err
+	}
+	result := lookupResult{definitions: definitions, synonyms: splitSynonyms(synonyms)}
+	this.lookups.add(word, result)
+	return result, nil
+}
+
+func (this *DictBot) registerNotebookCommands() {
+	this.Commands.Register(&Command{
+		Name:    "save",
+		Aliases: []string{"keep"},
+		Usage:   "/save <word>",
+		Descriptions: map[string]string{
+			"en": "Save a word to your notebook",
+			"th": "บันทึกคำลงในสมุดคำศัพท์",
+		},
+		MinArgs: 1,
+		Handler: func(event *chat.Event, args []string) error {
+			return this.saveWord(event, args[0])
+		},
+	})
+	this.Commands.Register(&Command{
+		Name:    "notebook",
+		Aliases: []string{"nb", "words", "list"},
+		Usage:   "/notebook [page]",
+		Descriptions: map[string]string{
+			"en": "Show the words in your notebook",
+			"th": "ดูคำศัพท์ในสมุดคำศัพท์",
+		},
+		Handler: func(event *chat.Event, args []string) error {
+			page := 1
+			if len(args) > 0 {
+				page, _ = strconv.Atoi(args[0])
+			}
+			return this.showNotebook(event, page)
+		},
+	})
+	this.Commands.Register(&Command{
+		Name:    "remove",
+		Aliases: []string{"rm", "delete", "unsave"},
+		Usage:   "/remove <word>",
+		Descriptions: map[string]string{
+			"en": "Remove a word from your notebook",
+			"th": "ลบคำออกจากสมุดคำศัพท์",
+		},
+		MinArgs: 1,
+		Handler: func(event *chat.Event, args []string) error {
+			return this.removeWord(event, args[0])
+		},
+	})
+	this.Commands.Register(&Command{
+		Name:  "export",
+		Usage: "/export",
+		Descriptions: map[string]string{
+			"en": "Export your notebook as text",
+			"th": "ส่งออกสมุดคำศัพท์เป็นข้อความ",
+		},
+		Handler: func(event *chat.Event, args []string) error {
+			return this.exportNotebook(event)
+		},
+	})
+	this.Router.HandlePostback(ActionSave, func(event *chat.Event, postback Postback) error {
+		return this.saveWord(event, postback.Word)
+	})
+	this.Router.HandlePostback(ActionNotebookPage, func(event *chat.Event, postback Postback) error {
+		page, _ := strconv.Atoi(postback.Word)
+		return this.showNotebook(event, page)
+	})
+}
+
+// notebookUserID returns the user's ID, or "" after replying why the
+// notebook can't be used for this event.
+func (this *DictBot) notebookUserID(event *chat.Event) (string, error) {
+	if this.Notebook == nil {
+		return "", this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.NotebookUnavailable)))
+	}
+	if event.Source.UserID == "" {
+		return "", this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.NotebookNeedsFriend)))
+	}
+	return event.Source.UserID, nil
+}
+
+func (this *DictBot) saveWord(event *chat.Event, word string) error {
+	userID, err := this.notebookUserID(event)
+	if userID == "" {
+		return err
+	}
+	word = strings.ToLower(strings.Split(strings.TrimSpace(word), " ")[0])
+	result, err := this.cachedLookup(event.Source, word)
+	if err != nil {
+		return this.reply(event, chat.NewTextMessage(this.lookupErrorText(event.Source, err, i18n.NoDefinition, word)))
+	}
+	entry := store.NotebookEntry{
+		Word:        word,
+		Definitions: result.definitions,
+		Synonyms:    service.JoinWords(result.synonyms),
+		SavedAt:     this.Clock.Now(),
+	}
+	if err := this.Notebook.Save(userID, entry); err != nil {
+		return err
+	}
+	if this.Reviews != nil {
+		if _, err := this.Reviews.Get(userID, word); err == store.ErrCardNotFound {
+			if err := this.Reviews.Save(userID, NewReviewCard(word, entry.SavedAt)); err != nil {
+				return err
+			}
+		}
+	}
+	return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.SavedWord, word)))
+}
+
+func (this *DictBot) showNotebook(event *chat.Event, page int) error {
+	userID, err := this.notebookUserID(event)
+	if userID == "" {
+		return err
+	}
+	if page < 1 {
+		page = 1
+	}
+	entries, total, err := this.Notebook.List(userID, (page-1)*notebookPageSize, notebookPageSize)
+	if err != nil {
+		return err
+	}
+	if total == 0 {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.NotebookEmptyHint, this.text(event.Source, i18n.SaveToNotebook))))
+	}
+	pages := (total + notebookPageSize - 1) / notebookPageSize
+	if len(entries) == 0 {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.NotebookPages, pages)))
+	}
+	lines := []string{this.text(event.Source, i18n.NotebookPage, page, pages)}
+	for i, entry := range entries {
+		lines = append(lines, strconv.Itoa((page-1)*notebookPageSize+i+1)+". "+entry.Word+" - "+entry.Definitions)
+	}
+	buttons := []chat.Button{}
+	if page > 1 {
+		data := Postback{Action: ActionNotebookPage, Word: strconv.Itoa(page - 1)}.Encode()
+		buttons = append(buttons, chat.NewPostbackButton(this.text(event.Source, i18n.PreviousPage), data))
+	}
+	if page < pages {
+		data := Postback{Action: ActionNotebookPage, Word: strconv.Itoa(page + 1)}.Encode()
+		buttons = append(buttons, chat.NewPostbackButton(this.text(event.Source, i18n.NextPage), data))
+	}
+	message := chat.NewTextMessage(strings.Join(lines, "\n"))
+	if len(buttons) > 0 {
+		return this.reply(event, message.WithQuickReplies(buttons...))
+	}
+	return this.reply(event, message)
+}
+
+func (this *DictBot) removeWord(event *chat.Event, word string) error {
+	userID, err := this.notebookUserID(event)
+	if userID == "" {
+		return err
+	}
+	word = strings.ToLower(word)
+	if err := this.Notebook.Remove(userID, word); err == store.ErrEntryNotFound {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.NotInNotebook, word)))
+	} else if err != nil {
+		return err
+	}
+	if this.Reviews != nil {
+		if err := this.Reviews.Delete(userID, word); err != nil {
+			return err
+		}
+	}
+	return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.RemovedWord, word)))
+}
+
+func (this *DictBot) exportNotebook(event *chat.Event) error {
+	userID, err := this.notebookUserID(event)
+	if userID == "" {
+		return err
+	}
+	entries, err := this.Notebook.All(userID)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.NotebookEmpty)))
+	}
+	texts := []string{""}
+	for _, entry := range entries {
+		line := entry.Word + "\t" + entry.Definitions + "\t" + entry.Synonyms + "\n"
+		if len(texts[len(texts)-1])+len(line) > maxTextMessageLength {
+			texts = append(texts, "")
+		}
+		texts[len(texts)-1] += line
+	}
+	messages := []chat.Message{}
+	for i, text := range texts {
+		if i == maxReplyMessages-1 && len(texts) > maxReplyMessages {
+			messages = append(messages, chat.NewTextMessage(this.text(event.Source, i18n.NotebookTooLong)))
+			break
+		}
+		messages = append(messages, chat.NewTextMessage(strings.TrimSuffix(text, "\n")))
+	}
+	return this.reply(event, messages...)
+}
@@ -0,0 +1,115 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/service"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+type countingServiceController struct {
+	mockServiceController
+	lookups int
+}
+
+func (this *countingServiceController) FindDefinitionsAndSynonyms(userID string, chatID string, word string) (string, string, error) {
+	this.lookups++
+	return this.mockServiceController.FindDefinitionsAndSynonyms(userID, chatID, word)
+}
+
+func (this *countingServiceController) Lookup(userID string, chatID string, word string, options service.LookupOptions) (service.Entry, error) {
+	this.lookups++
+	return this.mockServiceController.Lookup(userID, chatID, word, options)
+}
+
+func TestLookupCache(t *testing.T) {
+	cache := newLookupCache()
+	cache.add("Line", lookupResult{definitions: "a long, narrow mark or band"})
+	if result, ok := cache.get("line"); !ok || result.definitions != "a long, narrow mark or band" {
+		t.Errorf("lookupCache.get(%q) == %+v, %v", "line", result, ok)
+	}
+	for i := 0; i < maxLookupCacheEntries; i++ {
+		cache.add("word"+strconv.Itoa(i), lookupResult{})
+	}
+	if _, ok := cache.get("line"); ok || len(cache.results) != maxLookupCacheEntries {
+		t.Errorf("lookupCache kept %d results, want %d", len(cache.results), maxLookupCacheEntries)
+	}
+}
+
+func TestDictBotResponseNotebook(t *testing.T) {
+	adapter := newFakeAdapter()
+	serviceController := &countingServiceController{}
+	bot := NewDictBot(serviceController, adapter)
+	bot.Notebook = store.NewMemoryNotebook()
+	send := func(event *chat.Event) string {
+		event.Source = chat.Source{Type: chat.SourceTypeUser, UserID: "user1"}
+		event.ReplyToken = "dummy"
+		if err := bot.Response([]*chat.Event{event}); err != nil {
+			t.Errorf("DictBot.Response(%v) == %v, want %v", event.Type, err, nil)
+		}
+		requests := adapter.Requests()
+		return requests[len(requests)-1]
+	}
+	text := func(text string) *chat.Event {
+		return &chat.Event{Type: chat.EventTypeMessage, MessageType: chat.MessageTypeText, Text: text}
+	}
+	postback := func(action string, word string) *chat.Event {
+		return &chat.Event{Type: chat.EventTypePostback, Postback: Postback{Action: action, Word: word}.Encode()}
+	}
+
+	if got := send(text("/notebook")); !strings.Contains(got, "Your notebook is empty.") {
+		t.Errorf("DictBot.Response(/notebook) replied %q", got)
+	}
+	if got := send(text("line")); !strings.Contains(got, `"data":"1|save|line"`) {
+		t.Errorf("DictBot.Response(line) replied %q, want save quick reply", got)
+	}
+	if got := send(postback(ActionSave, "line")); !strings.Contains(got, "Saved 'line' to your notebook.") {
+		t.Errorf("DictBot.Response(save) replied %q", got)
+	}
+	if serviceController.lookups != 1 {
+		t.Errorf("DictBot looked up %d times, want %d", serviceController.lookups, 1)
+	}
+	for i := 0; i < notebookPageSize; i++ {
+		send(text("/save word" + strconv.Itoa(i)))
+	}
+	if serviceController.lookups != 1+notebookPageSize {
+		t.Errorf("DictBot looked up %d times, want %d", serviceController.lookups, 1+notebookPageSize)
+	}
+	got := send(text("/notebook"))
+	if !strings.Contains(got, "Your notebook (page 1/2):") || !strings.Contains(got, `"data":"1|nb|2"`) || strings.Contains(got, "Previous page") {
+		t.Errorf("DictBot.Response(/notebook) replied %q", got)
+	}
+	got = send(postback(ActionNotebookPage, "2"))
+	if !strings.Contains(got, "page 2/2") || !strings.Contains(got, "11. line - dummy") || strings.Contains(got, "Next page") {
+		t.Errorf("DictBot.Response(notebook page 2) replied %q", got)
+	}
+	if got := send(text("/notebook 3")); !strings.Contains(got, "There are only 2 pages") {
+		t.Errorf("DictBot.Response(/notebook 3) replied %q", got)
+	}
+	if got := send(text("/export")); !strings.Contains(got, `line\tdummy\tdummy`) {
+		t.Errorf("DictBot.Response(/export) replied %q", got)
+	}
+	if got := send(text("/remove LINE")); !strings.Contains(got, "Removed 'line' from your notebook.") {
+		t.Errorf("DictBot.Response(/remove) replied %q", got)
+	}
+	if got := send(text("/remove line")); !strings.Contains(got, "'line' is not in your notebook.") {
+		t.Errorf("DictBot.Response(/remove) replied %q", got)
+	}
+}
+
+func TestDictBotResponseSaveWithoutSynonyms(t *testing.T) {
+	bot := NewDictBot(mockServiceController{}, newFakeAdapter())
+	bot.Notebook = store.NewMemoryNotebook()
+	source := chat.Source{Type: chat.SourceTypeUser, UserID: "user1"}
+	bot.Response([]*chat.Event{
+		{Type: chat.EventTypeMessage, MessageType: chat.MessageTypeText, Text: "lonely", Source: source, ReplyToken: "dummy"},
+		{Type: chat.EventTypePostback, Postback: Postback{Action: ActionSave, Word: "lonely"}.Encode(), Source: source, ReplyToken: "dummy"},
+	})
+	// The "no synonyms" reply is for the chat, not the notebook
+	if entry, err := bot.Notebook.Get("user1", "lonely"); err != nil || entry.Synonyms != "" {
+		t.Errorf("Notebook.Get(%q) == %+v, %v, want no synonyms", "lonely", entry, err)
+	}
+}
@@ -0,0 +1,48 @@
+package bot
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// Postback data is encoded as "<version>|<action>|<word>" to stay well under
+// the 300 characters LINE allows, e.g. "1|syn|line".
+const postbackVersion = "1"
+
+const (
+	ActionSynonyms       = "syn"
+	ActionAntonyms       = "ant"
+	ActionExamples       = "ex"
+	ActionPronunciations = "pron"
+	ActionSave           = "save"
+)
+
+const maxPostbackDataLength = 300
+
+type Postback struct {
+	Action string
+	Word   string
+}
+
+func (this Postback) Encode() string {
+	return postbackVersion + "|" + this.Action + "|" + url.PathEscape(this.Word)
+}
+
+func DecodePostback(data string) (Postback, error) {
+	parts := strings.SplitN(data, "|", 3)
+	if len(parts) != 3 {
+		return Postback{}, errors.New("malformed postback data: " + data)
+	}
+	if parts[0] != postbackVersion {
+		return Postback{}, errors.New("unsupported postback version: " + parts[0])
+	}
+	word, err := url.PathUnescape(parts[2])
+	if err != nil {
+		return Postback{}, err
+	}
+	return Postback{
+		Action: parts[1],
+		Word:   word,
+	}, nil
+}
@@ -0,0 +1,43 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPostbackEncode(t *testing.T) {
+	cases := []struct {
+		in   Postback
+		want string
+	}{
+		{Postback{Action: ActionSynonyms, Word: "line"}, "1|syn|line"},
+		{Postback{Action: ActionPronunciations, Word: "a|b c"}, "1|pron|a%7Cb%20c"},
+		{Postback{Action: ActionSave, Word: ""}, "1|save|"},
+	}
+	for _, c := range cases {
+		got := c.in.Encode()
+		if got != c.want {
+			t.Errorf("Postback.Encode(%v) == %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestDecodePostback(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Postback
+		err  string
+	}{
+		{"1|syn|line", Postback{Action: ActionSynonyms, Word: "line"}, ""},
+		{"1|pron|a%7Cb%20c", Postback{Action: ActionPronunciations, Word: "a|b c"}, ""},
+		{"2|syn|line", Postback{}, "unsupported postback version"},
+		{"line", Postback{}, "malformed postback data"},
+		{"1|syn|%zz", Postback{}, "invalid URL escape"},
+	}
+	for _, c := range cases {
+		got, err := DecodePostback(c.in)
+		if got != c.want || (c.err == "" && err != nil) || (c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err))) {
+			t.Errorf("DecodePostback(%q) == %v, %v, want %v, %q", c.in, got, err, c.want, c.err)
+		}
+	}
+}
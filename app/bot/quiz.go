@@ -0,0 +1,234 @@
+package bot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/i18n"
+	"github.com/choobot/choo-dict-bot/app/service"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+const (
+	ActionQuizAnswer = "qz"
+	ActionQuizNext   = "qz_next"
+	quizChoices      = 4
+)
+
+func (this *DictBot) registerQuizCommands() {
+	this.Commands.Register(&Command{
+		Name:  "quiz",
+		Usage: "/quiz [stats|stop]",
+		Descriptions: map[string]string{
+			"en": "Guess the word from its definition",
+			"th": "ทายคำศัพท์จากความหมาย",
+		},
+		Handler: this.handleQuizCommand,
+	})
+	this.Router.HandlePostback(ActionQuizAnswer, this.handleQuizAnswer)
+	this.Router.HandlePostback(ActionQuizNext, func(event *chat.Event, postback Postback) error {
+		return this.startQuiz(event)
+	})
+}
+
+// quizUserID returns the user's ID, or "" after replying why the quiz can't
+// be played for this event.
+func (this *DictBot) quizUserID(event *chat.Event) (string, error) {
+	if this.Quizzes == nil {
+		return "", this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.QuizUnavailable)))
+	}
+	if event.Source.UserID == "" {
+		return "", this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.QuizNeedsFriend)))
+	}
+	return event.Source.UserID, nil
+}
+
+func (this *DictBot) handleQuizCommand(event *chat.Event, args []string) error {
+	if len(args) == 0 {
+		return this.startQuiz(event)
+	}
+	userID, err := this.quizUserID(event)
+	if userID == "" {
+		return err
+	}
+	switch strings.ToLower(args[0]) {
+	case "stats":
+		stats, err := this.Quizzes.GetStats(userID)
+		if err != nil {
+			return err
+		}
+		if stats.Played == 0 {
+			return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.QuizNotPlayed)))
+		}
+		text := this.text(event.Source, i18n.QuizStats, stats.Correct, stats.Played, stats.Correct*100/stats.Played, stats.Streak, stats.BestStreak)
+		return this.reply(event, chat.NewTextMessage(text))
+	case "stop":
+		if err := this.Quizzes.DeleteSession(userID); err != nil {
+			return err
+		}
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.QuizStopped)))
+	}
+	return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.Usage, "/quiz [stats|stop]")))
+}
+
+func (this *DictBot) startQuiz(event *chat.Event) error {
+	userID, err := this.quizUserID(event)
+	if userID == "" {
+		return err
+	}
+	entries := []store.NotebookEntry{}
+	if this.Notebook != nil {
+		if entries, err = this.Notebook.All(userID); err != nil {
+			return err
+		}
+	}
+	// Half of the questions come from the user's notebook when it has words
+	var word, definitions string
+	var synonyms []string
+	if len(entries) > 0 && this.Random(2) == 0 {
+		entry := entries[this.Random(len(entries))]
+		word, definitions, synonyms = entry.Word, entry.Definitions, splitSynonyms(entry.Synonyms)
+	} else {
+		band := quizWords[this.Random(len(quizWords))]
+		word = band[this.Random(len(band))]
+		result, err := this.cachedLookup(event.Source, word)
+		if err != nil && !errors.Is(err, service.ErrNotFound) {
+			return this.reply(event, chat.NewTextMessage(this.errorText(event.Source, err)))
+		}
+		definitions, synonyms = result.definitions, result.synonyms
+	}
+	if definitions == "" {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.QuizNoQuestion)))
+	}
+	nonce, err := newQuizNonce()
+	if err != nil {
+		return err
+	}
+	session := store.QuizSession{
+		Nonce:   nonce,
+		Word:    word,
+		Choices: this.shuffle(append(this.quizDistractors(word, synonyms, entries), word)),
+		AskedAt: this.Clock.Now(),
+	}
+	if err := this.Quizzes.SaveSession(userID, session); err != nil {
+		return err
+	}
+	buttons := []chat.Button{}
+	for _, choice := range session.Choices {
+		data := Postback{Action: ActionQuizAnswer, Word: session.Nonce + ":" + choice}.Encode()
+		buttons = append(buttons, chat.NewPostbackButton(choice, data))
+	}
+	text := this.text(event.Source, i18n.QuizQuestion, maskWord(definitions, word))
+	return this.reply(event, chat.NewTextMessage(text).WithQuickReplies(buttons...))
+}
+
+// quizDistractors picks the wrong choices of a question. They are words as
+// common as the answer, or for notebook words outside the list, the user's
+// other saved words and their synonyms. The answer's own synonyms are never
+// picked since they would be right too.
+func (this *DictBot) quizDistractors(word string, synonyms []string, entries []store.NotebookEntry) []string {
+	excluded := map[string]bool{word: true}
+	for _, synonym := range synonyms {
+		excluded[strings.ToLower(synonym)] = true
+	}
+	candidates := quizBand(word)
+	if candidates == nil {
+		for _, entry := range entries {
+			candidates = append(candidates, entry.Word)
+			candidates = append(candidates, splitSynonyms(entry.Synonyms)...)
+		}
+	}
+	distractors := []string{}
+	for _, words := range [][]string{candidates, quizWords[1]} {
+		for _, candidate := range this.shuffle(append([]string{}, words...)) {
+			candidate = strings.ToLower(candidate)
+			if len(distractors) == quizChoices-1 {
+				return distractors
+			}
+			if excluded[candidate] || strings.Contains(candidate, " ") {
+				continue
+			}
+			excluded[candidate] = true
+			distractors = append(distractors, candidate)
+		}
+	}
+	return distractors
+}
+
+func (this *DictBot) handleQuizAnswer(event *chat.Event, postback Postback) error {
+	userID, err := this.quizUserID(event)
+	if userID == "" {
+		return err
+	}
+	parts := strings.SplitN(postback.Word, ":", 2)
+	session, err := this.Quizzes.GetSession(userID)
+	if err == store.ErrSessionNotFound || (err == nil && (len(parts) != 2 || parts[0] != session.Nonce)) {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.QuizOver)))
+	} else if err != nil {
+		return err
+	}
+	stats, err := this.Quizzes.GetStats(userID)
+	if err != nil {
+		return err
+	}
+	stats.Played++
+	text := ""
+	if parts[1] == session.Word {
+		stats.Correct++
+		stats.Streak++
+		if stats.Streak > stats.BestStreak {
+			stats.BestStreak = stats.Streak
+		}
+		text = this.text(event.Source, i18n.QuizCorrect, stats.Streak)
+	} else {
+		stats.Streak = 0
+		text = this.text(event.Source, i18n.QuizWrong, session.Word)
+	}
+	if err := this.Quizzes.DeleteSession(userID); err != nil {
+		return err
+	}
+	if err := this.Quizzes.SaveStats(userID, stats); err != nil {
+		return err
+	}
+	data := Postback{Action: ActionQuizNext}.Encode()
+	button := chat.NewPostbackButton(this.text(event.Source, i18n.NextQuestion), data)
+	return this.reply(event, chat.NewTextMessage(text).WithQuickReplies(button))
+}
+
+// newQuizNonce returns a random ID for a question.
+func newQuizNonce() (string, error) {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(nonce), nil
+}
+
+func (this *DictBot) shuffle(words []string) []string {
+	for i := len(words) - 1; i > 0; i-- {
+		j := this.Random(i + 1)
+		words[i], words[j] = words[j], words[i]
+	}
+	return words
+}
+
+// splitSynonyms splits the "a, b and c" lists of the dictionary service.
+func splitSynonyms(text string) []string {
+	words := []string{}
+	for _, word := range strings.Split(strings.Replace(text, " and ", ", ", -1), ", ") {
+		if word = strings.TrimSpace(word); word != "" {
+			words = append(words, word)
+		}
+	}
+	return words
+}
+
+// maskWord hides the word and its inflections, so the definition doesn't
+// give the answer away.
+func maskWord(text string, word string) string {
+	return regexp.MustCompile(`(?i)\b`+regexp.QuoteMeta(word)+`\w*`).ReplaceAllString(text, "____")
+}
@@ -0,0 +1,134 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+func TestMaskWord(t *testing.T) {
+	cases := []struct {
+		text string
+		word string
+		want string
+	}{
+		{"a long, narrow mark", "line", "a long, narrow mark"},
+		{"Lines and a line", "line", "____ and a ____"},
+		{"an outline", "line", "an outline"},
+		{"the cost (c++)", "c++", "the cost (____)"},
+	}
+	for _, c := range cases {
+		got := maskWord(c.text, c.word)
+		if got != c.want {
+			t.Errorf("maskWord(%q, %q) == %q, want %q", c.text, c.word, got, c.want)
+		}
+	}
+}
+
+func TestQuizDistractors(t *testing.T) {
+	bot := NewDictBot(mockServiceController{}, nil)
+	entries := []store.NotebookEntry{
+		{Word: "serendipity", Synonyms: "chance, fluke and luck"},
+		{Word: "petrichor", Synonyms: ""},
+		{Word: "sonder"},
+	}
+	cases := []struct {
+		word     string
+		synonyms []string
+		allowed  []string
+	}{
+		{"bridge", nil, quizWords[0]},
+		{"sonder", nil, []string{"serendipity", "petrichor", "chance", "fluke", "luck"}},
+		{"sonder", []string{"chance", "fluke"}, append([]string{"serendipity", "petrichor", "luck"}, quizWords[1]...)},
+	}
+	for _, c := range cases {
+		got := bot.quizDistractors(c.word, c.synonyms, entries)
+		if len(got) != quizChoices-1 {
+			t.Errorf("DictBot.quizDistractors(%q) == %q, want %d words", c.word, got, quizChoices-1)
+		}
+		seen := map[string]bool{c.word: true}
+		for _, distractor := range got {
+			if seen[distractor] || !strings.Contains(" "+strings.Join(c.allowed, " ")+" ", " "+distractor+" ") {
+				t.Errorf("DictBot.quizDistractors(%q) == %q, %q not allowed", c.word, got, distractor)
+			}
+			seen[distractor] = true
+		}
+	}
+}
+
+func TestDictBotResponseQuiz(t *testing.T) {
+	adapter := newFakeAdapter()
+	bot := NewDictBot(mockServiceController{}, adapter)
+	bot.Quizzes = store.NewMemoryQuizStore()
+	bot.Random = func(n int) int {
+		return 0
+	}
+	send := func(event *chat.Event) string {
+		event.Source = chat.Source{Type: chat.SourceTypeUser, UserID: "user1"}
+		event.ReplyToken = "dummy"
+		if err := bot.Response([]*chat.Event{event}); err != nil {
+			t.Errorf("DictBot.Response(%v) == %v, want %v", event.Type, err, nil)
+		}
+		requests := adapter.Requests()
+		return requests[len(requests)-1]
+	}
+	text := func(text string) *chat.Event {
+		return &chat.Event{Type: chat.EventTypeMessage, MessageType: chat.MessageTypeText, Text: text}
+	}
+	postback := func(action string, word string) *chat.Event {
+		return &chat.Event{Type: chat.EventTypePostback, Postback: Postback{Action: action, Word: word}.Encode()}
+	}
+
+	if got := send(text("/quiz stats")); !strings.Contains(got, "You haven't played yet") {
+		t.Errorf("DictBot.Response(/quiz stats) replied %q", got)
+	}
+	// answer is the data of a choice's button of the current question
+	answer := func(choice string) string {
+		session, _ := bot.Quizzes.GetSession("user1")
+		return session.Nonce + ":" + choice
+	}
+
+	got := send(text("/quiz"))
+	session, err := bot.Quizzes.GetSession("user1")
+	if err != nil || session.Word != "answer" || len(session.Choices) != quizChoices || session.Nonce == "" {
+		t.Errorf("QuizStore.GetSession(%q) == %+v, %v", "user1", session, err)
+	}
+	if !strings.Contains(got, `Which word means:`) || strings.Count(got, `"data":"1|qz|`+session.Nonce+`:`) != quizChoices || !strings.Contains(got, `"data":"1|qz|`+session.Nonce+`:answer"`) {
+		t.Errorf("DictBot.Response(/quiz) replied %q", got)
+	}
+	first := answer("answer")
+	if got := send(postback(ActionQuizAnswer, first)); !strings.Contains(got, "Correct! Your streak is 1.") || !strings.Contains(got, `"data":"1|qz_next|"`) {
+		t.Errorf("DictBot.Response(answer) replied %q", got)
+	}
+	if got := send(postback(ActionQuizAnswer, first)); !strings.Contains(got, "This question is over.") {
+		t.Errorf("DictBot.Response(answer again) replied %q", got)
+	}
+	send(postback(ActionQuizNext, ""))
+	if got := send(postback(ActionQuizAnswer, first)); !strings.Contains(got, "This question is over.") {
+		t.Errorf("DictBot.Response(old answer) replied %q", got)
+	}
+	// Asking again replaces the question, and the buttons of the one before
+	// don't count
+	old := answer("answer")
+	send(text("/quiz"))
+	if got := send(postback(ActionQuizAnswer, old)); !strings.Contains(got, "This question is over.") {
+		t.Errorf("DictBot.Response(answer of a replaced question) replied %q", got)
+	}
+	send(postback(ActionQuizAnswer, answer("answer")))
+	send(text("/quiz"))
+	if got := send(postback(ActionQuizAnswer, answer("wrong"))); !strings.Contains(got, "Not quite, the answer was 'answer'.") {
+		t.Errorf("DictBot.Response(wrong answer) replied %q", got)
+	}
+	if got := send(text("/quiz stats")); !strings.Contains(got, "2 of 3 questions correctly (66%)") || !strings.Contains(got, "Current streak: 0, best streak: 2.") {
+		t.Errorf("DictBot.Response(/quiz stats) replied %q", got)
+	}
+	send(text("/quiz"))
+	if got := send(text("/quiz stop")); !strings.Contains(got, "Quiz stopped.") {
+		t.Errorf("DictBot.Response(/quiz stop) replied %q", got)
+	}
+	if _, err := bot.Quizzes.GetSession("user1"); err != store.ErrSessionNotFound {
+		t.Errorf("QuizStore.GetSession(%q) after stop == %v, want %v", "user1", err, store.ErrSessionNotFound)
+	}
+}
@@ -0,0 +1,34 @@
+package bot
+
+// quizWords are grouped by how common they are, so the wrong choices of a
+// question are about as familiar as the answer.
+var quizWords = [][]string{
+	{
+		"answer", "bridge", "castle", "cloud", "doctor", "engine", "forest", "garden",
+		"harbor", "island", "jacket", "kitchen", "ladder", "market", "needle", "ocean",
+		"pencil", "pocket", "river", "shadow", "storm", "tunnel", "village", "window",
+	},
+	{
+		"ancient", "bargain", "candid", "diligent", "eager", "fragile", "genuine", "humble",
+		"immense", "jovial", "keen", "lenient", "modest", "nimble", "obscure", "prudent",
+		"reluctant", "sincere", "tedious", "urgent", "vacant", "vivid", "wary", "zealous",
+	},
+	{
+		"abate", "benevolent", "cacophony", "deleterious", "ephemeral", "fastidious", "garrulous", "hapless",
+		"impetuous", "juxtapose", "laconic", "magnanimous", "nefarious", "obfuscate", "pernicious", "quixotic",
+		"recalcitrant", "sanguine", "taciturn", "ubiquitous", "vacillate", "wistful", "xenophobia", "zenith",
+	},
+}
+
+// quizBand returns the words about as common as the given one, or nil when
+// the word isn't in the list.
+func quizBand(word string) []string {
+	for _, band := range quizWords {
+		for _, w := range band {
+			if w == word {
+				return band
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/i18n"
+	"github.com/choobot/choo-dict-bot/app/service"
+)
+
+func (this *DictBot) registerQuotaCommands() {
+	this.Commands.Register(&Command{
+		Name:    "quota",
+		Aliases: []string{"plan"},
+		Usage:   "/quota",
+		Descriptions: map[string]string{
+			"en": "Show your plan and the lookups you have left",
+			"th": "ดูแพ็กเกจและจำนวนครั้งที่ค้นหาได้อีก",
+		},
+		Handler: this.handleQuotaCommand,
+	})
+}
+
+func (this *DictBot) handleQuotaCommand(event *chat.Event, args []string) error {
+	if this.Plans == nil {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.NoQuota)))
+	}
+	now := this.Clock.Now()
+	allowance := this.Plans.Allowance(requesterID(event.Source), event.Source.ChatID)
+	if allowance.HourLeft < 0 && allowance.DayLeft < 0 {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.NoQuota)))
+	}
+	return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.QuotaStatus,
+		allowance.Tier.Name,
+		this.allowanceLeft(event.Source, allowance.HourLeft), remaining(allowance.HourReset.Sub(now)),
+		this.allowanceLeft(event.Source, allowance.DayLeft), remaining(allowance.DayReset.Sub(now)))))
+}
+
+func (this *DictBot) allowanceLeft(source chat.Source, left int) string {
+	if left < 0 {
+		return this.text(source, i18n.Unlimited)
+	}
+	return strconv.Itoa(left)
+}
+
+// remaining formats a duration in hours and minutes, rounding up.
+func remaining(d time.Duration) string {
+	d = (d + time.Minute - 1).Truncate(time.Minute)
+	if d < time.Minute {
+		d = time.Minute
+	}
+	return strings.TrimSuffix(d.String(), "0s")
+}
+
+// WarnQuota tells the bot admins that a dictionary provider is running out
+// of its quota.
+func (this *DictBot) WarnQuota(warning service.QuotaWarning) {
+	for _, admin := range this.Admins {
+		source := chat.Source{Type: chat.SourceTypeUser, UserID: admin}
+		text := this.text(source, i18n.QuotaWarning, warning.Provider, warning.Used, warning.Limit, warning.Period)
+		if warning.Used >= warning.Limit {
+			text += this.text(source, i18n.ProviderQuotaExhausted)
+		}
+		if err := this.Adapter.Push(admin, chat.NewTextMessage(text)); err != nil {
+			log.Println(err)
+		}
+	}
+}
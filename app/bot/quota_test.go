@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/controller"
+	"github.com/choobot/choo-dict-bot/app/scheduler"
+	"github.com/choobot/choo-dict-bot/app/service"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+func TestDictBotWarnQuota(t *testing.T) {
+	adapter := newFakeAdapter()
+	bot := NewDictBot(mockServiceController{}, adapter)
+	bot.Admins = []string{"admin1", "admin2"}
+	bot.WarnQuota(service.QuotaWarning{Provider: "oxford", Period: "month", Used: 2400, Limit: 3000})
+	bot.WarnQuota(service.QuotaWarning{Provider: "oxford", Period: "month", Used: 3000, Limit: 3000})
+	requests := adapter.Requests()
+	if len(requests) != 4 || !strings.Contains(requests[0], `"to":"admin1"`) || !strings.Contains(requests[1], `"to":"admin2"`) {
+		t.Fatalf("DictBot.WarnQuota() pushed %q, want a push to each admin", requests)
+	}
+	if want := "oxford has used 2400 of its 3000 calls this month."; !strings.Contains(requests[0], want) || strings.Contains(requests[0], "cache") {
+		t.Errorf("DictBot.WarnQuota() pushed %q, want %q", requests[0], want)
+	}
+	if want := "answered from the cache"; !strings.Contains(requests[2], want) {
+		t.Errorf("DictBot.WarnQuota() at the limit pushed %q, want %q", requests[2], want)
+	}
+}
+
+func TestDictBotQuotaCommand(t *testing.T) {
+	adapter := newFakeAdapter()
+	clock := scheduler.NewFakeClock(time.Date(2018, 11, 20, 10, 20, 0, 0, time.UTC))
+	bot := NewDictBot(mockServiceController{}, adapter)
+	bot.Clock = clock
+	send := func(userID string, chatID string) string {
+		before := len(adapter.Requests())
+		source := chat.Source{Type: chat.SourceTypeUser, UserID: userID}
+		if chatID != "" {
+			source.Type, source.ChatID = chat.SourceTypeGroup, chatID
+		}
+		bot.Response([]*chat.Event{{
+			Type:        chat.EventTypeMessage,
+			ReplyToken:  "token",
+			Source:      source,
+			MessageType: chat.MessageTypeText, Text: "/quota",
+		}})
+		requests := adapter.Requests()
+		return strings.Join(requests[before:], "\n")
+	}
+	if got, want := send("user1", ""), "There's no limit on your lookups."; !strings.Contains(got, want) {
+		t.Errorf("/quota without plans replied %q, want %q", got, want)
+	}
+	bot.Plans = controller.NewPlans(store.NewMemoryTierStore(), clock)
+	bot.Plans.Take("user1", "")
+	want := `Your plan: free\nLookups left this hour: 29 (resets in 40m)\nLookups left today: 99 (resets in 13h40m)`
+	if got := send("user1", ""); !strings.Contains(got, want) {
+		t.Errorf("/quota replied %q, want %q", got, want)
+	}
+	bot.Plans.Assign("user2", controller.TierStaff)
+	if got, want := send("user2", ""), "There's no limit on your lookups."; !strings.Contains(got, want) {
+		t.Errorf("/quota of staff replied %q, want %q", got, want)
+	}
+	bot.Plans.Assign("group1", controller.TierStaff)
+	if got, want := send("user1", "group1"), "There's no limit on your lookups."; !strings.Contains(got, want) {
+		t.Errorf("/quota in a staff group replied %q, want %q", got, want)
+	}
+	if got := send("user1", ""); !strings.Contains(got, "Your plan: free") {
+		t.Errorf("/quota after asking in a staff group replied %q, want the free plan", got)
+	}
+}
@@ -0,0 +1,31 @@
+package bot
+
+import (
+	"log"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+)
+
+// webhookEventTTL is how long handled events are remembered. LINE stops
+// redelivering an event well within it.
+const webhookEventTTL = 24 * time.Hour
+
+// dedup skips webhook events that have been handled already, as platforms
+// may deliver an event again.
+func (this *DictBot) dedup(next Handler) Handler {
+	return func(event *chat.Event) error {
+		if this.Events == nil || event.ID == "" {
+			return next(event)
+		}
+		claimed, err := this.Events.Claim(event.ID, this.Clock.Now(), webhookEventTTL)
+		if err != nil {
+			// Handling an event twice beats not handling it
+			log.Println(err)
+		} else if !claimed {
+			log.Printf("skipped duplicate webhook event %s", event.ID)
+			return nil
+		}
+		return next(event)
+	}
+}
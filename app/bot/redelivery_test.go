@@ -0,0 +1,44 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+func TestDictBotRedelivery(t *testing.T) {
+	adapter := newFakeAdapter()
+	bot := NewDictBot(mockServiceController{}, adapter)
+	event := func(eventID string, redelivery bool) *chat.Event {
+		return &chat.Event{
+			Type:        chat.EventTypeMessage,
+			ID:          eventID,
+			ReplyToken:  "token",
+			Redelivery:  redelivery,
+			Source:      chat.Source{Type: chat.SourceTypeUser, UserID: "user1"},
+			MessageType: chat.MessageTypeText,
+			Text:        "/quota",
+		}
+	}
+
+	// Without a store every event is handled
+	bot.Response([]*chat.Event{event("event1", false)})
+	bot.Response([]*chat.Event{event("event1", true)})
+	if got := adapter.Requests(); len(got) != 2 {
+		t.Errorf("DictBot.Response() of an event and its redelivery without a store sent %q, want two replies", got)
+	}
+
+	bot.Events = store.NewMemoryEventStore()
+	bot.Response([]*chat.Event{event("event2", false)})
+	bot.Response([]*chat.Event{event("event2", true)})
+	if got := adapter.Requests(); len(got) != 3 {
+		t.Errorf("DictBot.Response() of an event and its redelivery sent %q, want one reply", got[2:])
+	}
+	// Events without an ID can't be told apart
+	bot.Response([]*chat.Event{event("", false)})
+	bot.Response([]*chat.Event{event("", false)})
+	if got := adapter.Requests(); len(got) != 5 {
+		t.Errorf("DictBot.Response() of events without an ID sent %q, want a reply to each", got[3:])
+	}
+}
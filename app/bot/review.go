@@ -0,0 +1,366 @@
+package bot
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/i18n"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+const (
+	ActionReviewShow  = "rv_show"
+	ActionReviewAgain = "rv_again"
+	ActionReviewHard  = "rv_hard"
+	ActionReviewGood  = "rv_good"
+	ActionReviewEasy  = "rv_easy"
+)
+
+const (
+	PreferenceReview         = "review"
+	PreferenceTimezone       = "timezone"
+	PreferenceQuietHours     = "quiet_hours"
+	preferenceLastReviewPush = "review_last_push"
+	defaultTimezone          = "Asia/Bangkok"
+	defaultQuietHours        = "22-7"
+	maxDueCards              = 100
+)
+
+type Grade int
+
+const (
+	GradeAgain Grade = iota
+	GradeHard
+	GradeGood
+	GradeEasy
+)
+
+var reviewGrades = []struct {
+	action string
+	label  i18n.Code
+	grade  Grade
+}{
+	{ActionReviewAgain, i18n.GradeAgainLabel, GradeAgain},
+	{ActionReviewHard, i18n.GradeHardLabel, GradeHard},
+	{ActionReviewGood, i18n.GradeGoodLabel, GradeGood},
+	{ActionReviewEasy, i18n.GradeEasyLabel, GradeEasy},
+}
+
+func NewReviewCard(word string, now time.Time) store.ReviewCard {
+	return store.ReviewCard{
+		Word:       word,
+		EaseFactor: 2.5,
+		Due:        now.AddDate(0, 0, 1),
+	}
+}
+
+// NextReview reschedules a card with the SM-2 algorithm. The four answers
+// map to SM-2 qualities 1 (again), 3 (hard), 4 (good) and 5 (easy).
+func NextReview(card store.ReviewCard, grade Grade, now time.Time) store.ReviewCard {
+	quality := map[Grade]float64{GradeAgain: 1, GradeHard: 3, GradeGood: 4, GradeEasy: 5}[grade]
+	if quality < 3 {
+		card.Repetitions = 0
+		card.IntervalDays = 1
+	} else {
+		switch card.Repetitions {
+		case 0:
+			card.IntervalDays = 1
+		case 1:
+			card.IntervalDays = 6
+		default:
+			card.IntervalDays = int(math.Round(float64(card.IntervalDays) * card.EaseFactor))
+		}
+		card.Repetitions++
+	}
+	card.EaseFactor += 0.1 - (5-quality)*(0.08+(5-quality)*0.02)
+	if card.EaseFactor < 1.3 {
+		card.EaseFactor = 1.3
+	}
+	card.LastReviewed = now
+	card.Due = now.AddDate(0, 0, card.IntervalDays)
+	return card
+}
+
+// InQuietHours reports whether the hour falls in quiet hours written as
+// "start-end", e.g. "22-7". "off" or an invalid value means no quiet hours.
+func InQuietHours(quietHours string, hour int) bool {
+	parts := strings.Split(quietHours, "-")
+	if len(parts) != 2 {
+		return false
+	}
+	start, err1 := strconv.Atoi(parts[0])
+	end, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || start == end {
+		return false
+	}
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+func validQuietHours(quietHours string) bool {
+	parts := strings.Split(quietHours, "-")
+	if len(parts) != 2 {
+		return false
+	}
+	for _, part := range parts {
+		hour, err := strconv.Atoi(part)
+		if err != nil || hour < 0 || hour > 23 {
+			return false
+		}
+	}
+	return true
+}
+
+func (this *DictBot) registerReviewCommands() {
+	this.Commands.Register(&Command{
+		Name:  "review",
+		Usage: "/review [on|off]",
+		Descriptions: map[string]string{
+			"en": "Review your saved words now, or turn daily reviews on or off",
+			"th": "ทบทวนคำศัพท์ที่บันทึกไว้ หรือเปิด/ปิดการทบทวนรายวัน",
+		},
+		Handler: this.handleReviewCommand,
+	})
+	this.Commands.Register(&Command{
+		Name:    "timezone",
+		Aliases: []string{"tz"},
+		Usage:   "/timezone [zone]",
+		Descriptions: map[string]string{
+			"en": "Show or set your time zone, e.g. Asia/Bangkok",
+			"th": "ดูหรือตั้งเขตเวลา เช่น Asia/Bangkok",
+		},
+		Handler: this.handleTimezoneCommand,
+	})
+	this.Commands.Register(&Command{
+		Name:  "quiet",
+		Usage: "/quiet [22-7|off]",
+		Descriptions: map[string]string{
+			"en": "Show or set the hours I shouldn't send you reviews",
+			"th": "ดูหรือตั้งช่วงเวลาที่ไม่ต้องการให้ส่งการทบทวน",
+		},
+		Handler: this.handleQuietCommand,
+	})
+	this.Router.HandlePostback(ActionReviewShow, this.handleReviewShow)
+	for _, item := range reviewGrades {
+		grade := item.grade
+		this.Router.HandlePostback(item.action, func(event *chat.Event, postback Postback) error {
+			return this.handleReviewAnswer(event, postback.Word, grade)
+		})
+	}
+}
+
+func (this *DictBot) preference(userID string, key string, fallback string) string {
+	if this.Registry != nil {
+		if user, err := this.Registry.Get(userID); err == nil {
+			if value, ok := user.Preferences[key]; ok {
+				return value
+			}
+		}
+	}
+	return fallback
+}
+
+// setPreference stores a preference, registering users who followed the bot
+// before the registry existed.
+func (this *DictBot) setPreference(userID string, key string, value string) error {
+	if this.Registry == nil {
+		return nil
+	}
+	return this.Registry.SavePreference(userID, key, value)
+}
+
+func (this *DictBot) location(userID string) *time.Location {
+	location, err := time.LoadLocation(this.preference(userID, PreferenceTimezone, defaultTimezone))
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// reviewUserID returns the user's ID, or "" after replying why reviews
+// can't be used for this event.
+func (this *DictBot) reviewUserID(event *chat.Event) (string, error) {
+	if this.Reviews == nil || this.Notebook == nil {
+		return "", this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.ReviewsUnavailable)))
+	}
+	if event.Source.UserID == "" {
+		return "", this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.ReviewsNeedFriend)))
+	}
+	return event.Source.UserID, nil
+}
+
+func (this *DictBot) reviewCardMessage(source chat.Source, card store.ReviewCard, intro string) chat.Message {
+	data := Postback{Action: ActionReviewShow, Word: card.Word}.Encode()
+	button := chat.NewPostbackButton(this.text(source, i18n.ShowAnswer), data)
+	return chat.NewTextMessage(intro + this.text(source, i18n.ReviewQuestion, card.Word)).WithQuickReplies(button)
+}
+
+func (this *DictBot) handleReviewCommand(event *chat.Event, args []string) error {
+	userID, err := this.reviewUserID(event)
+	if userID == "" {
+		return err
+	}
+	if len(args) > 0 {
+		setting := strings.ToLower(args[0])
+		if setting != "on" && setting != "off" {
+			return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.Usage, "/review [on|off]")))
+		}
+		if err := this.setPreference(userID, PreferenceReview, setting); err != nil {
+			return err
+		}
+		if setting == "off" {
+			return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.ReviewsOff)))
+		}
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.ReviewsOn)))
+	}
+	cards, err := this.Reviews.Due(userID, this.Clock.Now(), maxDueCards)
+	if err != nil {
+		return err
+	}
+	if len(cards) == 0 {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.NothingToReview)))
+	}
+	return this.reply(event, this.reviewCardMessage(event.Source, cards[0], this.text(event.Source, i18n.WordsToReview, len(cards))))
+}
+
+func (this *DictBot) handleReviewShow(event *chat.Event, postback Postback) error {
+	userID, err := this.reviewUserID(event)
+	if userID == "" {
+		return err
+	}
+	entry, err := this.Notebook.Get(userID, postback.Word)
+	if err == store.ErrEntryNotFound {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.NoLongerInNotebook, postback.Word)))
+	} else if err != nil {
+		return err
+	}
+	buttons := []chat.Button{}
+	for _, grade := range reviewGrades {
+		data := Postback{Action: grade.action, Word: entry.Word}.Encode()
+		buttons = append(buttons, chat.NewPostbackButton(this.text(event.Source, grade.label), data))
+	}
+	answer := this.text(event.Source, i18n.ReviewAnswer, entry.Word, entry.Definitions)
+	if entry.Synonyms != "" {
+		answer += this.text(event.Source, i18n.ReviewSynonyms, entry.Synonyms)
+	}
+	answer += this.text(event.Source, i18n.HowWell)
+	return this.reply(event, chat.NewTextMessage(answer).WithQuickReplies(buttons...))
+}
+
+func (this *DictBot) handleReviewAnswer(event *chat.Event, word string, grade Grade) error {
+	userID, err := this.reviewUserID(event)
+	if userID == "" {
+		return err
+	}
+	card, err := this.Reviews.Get(userID, word)
+	if err == store.ErrCardNotFound {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.NoLongerInNotebook, word)))
+	} else if err != nil {
+		return err
+	}
+	now := this.Clock.Now()
+	card = NextReview(card, grade, now)
+	if err := this.Reviews.Save(userID, card); err != nil {
+		return err
+	}
+	text := this.text(event.Source, i18n.ReviewScheduled, word, card.IntervalDays)
+	cards, err := this.Reviews.Due(userID, now, maxDueCards)
+	if err != nil {
+		return err
+	}
+	if len(cards) == 0 {
+		return this.reply(event, chat.NewTextMessage(text+this.text(event.Source, i18n.ReviewsDone)))
+	}
+	return this.reply(event, chat.NewTextMessage(text), this.reviewCardMessage(event.Source, cards[0], this.text(event.Source, i18n.MoreToGo, len(cards))))
+}
+
+func (this *DictBot) handleTimezoneCommand(event *chat.Event, args []string) error {
+	userID, err := this.reviewUserID(event)
+	if userID == "" {
+		return err
+	}
+	if len(args) == 0 {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.TimezoneStatus, this.preference(userID, PreferenceTimezone, defaultTimezone))))
+	}
+	if _, err := time.LoadLocation(args[0]); err != nil || args[0] == "" || args[0] == "Local" {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.UnknownTimezone, args[0])))
+	}
+	if err := this.setPreference(userID, PreferenceTimezone, args[0]); err != nil {
+		return err
+	}
+	return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.TimezoneSet, args[0])))
+}
+
+func (this *DictBot) handleQuietCommand(event *chat.Event, args []string) error {
+	userID, err := this.reviewUserID(event)
+	if userID == "" {
+		return err
+	}
+	if len(args) == 0 {
+		quietHours := this.preference(userID, PreferenceQuietHours, defaultQuietHours)
+		if quietHours == "off" {
+			return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.QuietHoursOff)))
+		}
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.QuietHoursStatus, quietHours)))
+	}
+	quietHours := strings.ToLower(args[0])
+	if quietHours != "off" && !validQuietHours(quietHours) {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.Usage, "/quiet [22-7|off]")))
+	}
+	if err := this.setPreference(userID, PreferenceQuietHours, quietHours); err != nil {
+		return err
+	}
+	if quietHours == "off" {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.QuietHoursOff)))
+	}
+	return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.QuietHoursSet, quietHours)))
+}
+
+// PushDueReviews pushes one review reminder per day to each user with due
+// words, outside their quiet hours in their time zone. It is meant to be run
+// by the scheduler every few minutes.
+func (this *DictBot) PushDueReviews(now time.Time) error {
+	if this.Reviews == nil || this.Registry == nil {
+		return nil
+	}
+	userIDs, err := this.Reviews.DueUsers(now)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if user, err := this.Registry.Get(userID); err == nil && !user.Active {
+			continue
+		}
+		if this.preference(userID, PreferenceReview, "on") == "off" {
+			continue
+		}
+		local := now.In(this.location(userID))
+		if InQuietHours(this.preference(userID, PreferenceQuietHours, defaultQuietHours), local.Hour()) {
+			continue
+		}
+		today := local.Format("2006-01-02")
+		if this.preference(userID, preferenceLastReviewPush, "") == today {
+			continue
+		}
+		cards, err := this.Reviews.Due(userID, now, maxDueCards)
+		if err != nil || len(cards) == 0 {
+			continue
+		}
+		source := chat.Source{Type: chat.SourceTypeUser, UserID: userID}
+		message := this.reviewCardMessage(source, cards[0], this.text(source, i18n.TimeToReview, len(cards)))
+		if err := this.Adapter.Push(userID, message); err != nil {
+			log.Println(err)
+			continue
+		}
+		if err := this.setPreference(userID, preferenceLastReviewPush, today); err != nil {
+			log.Println(err)
+		}
+	}
+	return nil
+}
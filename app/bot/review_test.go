@@ -0,0 +1,204 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/scheduler"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+func TestNextReview(t *testing.T) {
+	now := time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC)
+	card := NewReviewCard("line", now)
+	cases := []struct {
+		grade        Grade
+		repetitions  int
+		intervalDays int
+		easeFactor   float64
+	}{
+		{GradeGood, 1, 1, 2.5},
+		{GradeGood, 2, 6, 2.5},
+		{GradeEasy, 3, 15, 2.6},
+		{GradeHard, 4, 39, 2.46},
+		{GradeAgain, 0, 1, 1.92},
+		{GradeGood, 1, 1, 1.92},
+	}
+	for _, c := range cases {
+		card = NextReview(card, c.grade, now)
+		if card.Repetitions != c.repetitions || card.IntervalDays != c.intervalDays || card.EaseFactor < c.easeFactor-0.001 || card.EaseFactor > c.easeFactor+0.001 || !card.Due.Equal(now.AddDate(0, 0, c.intervalDays)) || !card.LastReviewed.Equal(now) {
+			t.Errorf("NextReview(%v) == %+v, want repetitions %d, interval %d, ease %.2f", c.grade, card, c.repetitions, c.intervalDays, c.easeFactor)
+		}
+	}
+
+	// Ease factor never drops below 1.3
+	for i := 0; i < 10; i++ {
+		card = NextReview(card, GradeAgain, now)
+	}
+	if card.EaseFactor != 1.3 {
+		t.Errorf("NextReview() ease factor == %v, want %v", card.EaseFactor, 1.3)
+	}
+}
+
+func TestInQuietHours(t *testing.T) {
+	cases := []struct {
+		quietHours string
+		hour       int
+		want       bool
+	}{
+		{"22-7", 23, true},
+		{"22-7", 3, true},
+		{"22-7", 7, false},
+		{"22-7", 12, false},
+		{"13-15", 14, true},
+		{"13-15", 15, false},
+		{"off", 3, false},
+		{"5-5", 5, false},
+		{"a-b", 5, false},
+	}
+	for _, c := range cases {
+		got := InQuietHours(c.quietHours, c.hour)
+		if got != c.want {
+			t.Errorf("InQuietHours(%q, %d) == %v, want %v", c.quietHours, c.hour, got, c.want)
+		}
+	}
+}
+
+func TestDictBotPushDueReviews(t *testing.T) {
+	adapter := newFakeAdapter()
+	// 01:00 UTC is 08:00 in Bangkok and 20:00 the day before in New York
+	clock := scheduler.NewFakeClock(time.Date(2018, 11, 20, 1, 0, 0, 0, time.UTC))
+	bot := NewDictBot(mockServiceController{}, adapter)
+	bot.Clock = clock
+	bot.Registry = store.NewMemoryUserRegistry()
+	bot.Reviews = store.NewMemoryReviewStore()
+	bot.Notebook = store.NewMemoryNotebook()
+	users := []struct {
+		userID      string
+		preferences map[string]string
+	}{
+		{"bangkok", map[string]string{}},
+		{"quiet", map[string]string{PreferenceQuietHours: "6-9"}},
+		{"off", map[string]string{PreferenceReview: "off"}},
+		{"newyork", map[string]string{PreferenceTimezone: "America/New_York", PreferenceQuietHours: "21-8"}},
+	}
+	for _, user := range users {
+		bot.Registry.Follow(store.User{ID: user.userID, Preferences: user.preferences})
+		bot.Reviews.Save(user.userID, NewReviewCard("line", clock.Now().AddDate(0, 0, -1)))
+	}
+	bot.Reviews.Save("notdue", NewReviewCard("line", clock.Now()))
+	bot.Reviews.Save("unfollowed", NewReviewCard("line", clock.Now().AddDate(0, 0, -1)))
+	bot.Registry.Unfollow("unfollowed", clock.Now())
+
+	pushedTo := func() []string {
+		users := []string{}
+		for _, request := range adapter.Requests() {
+			if strings.HasPrefix(request, "push") {
+				users = append(users, strings.Split(strings.Split(request, `"to":"`)[1], `"`)[0])
+			}
+		}
+		return users
+	}
+	bot.PushDueReviews(clock.Now())
+	if got := pushedTo(); len(got) != 2 || got[0] != "bangkok" || got[1] != "newyork" {
+		t.Errorf("DictBot.PushDueReviews() pushed to %q, want %q", got, []string{"bangkok", "newyork"})
+	}
+
+	// Settings of users who left don't bring them back
+	bot.setPreference("unfollowed", PreferenceReview, "on")
+	if user, err := bot.Registry.Get("unfollowed"); err != nil || user.Active {
+		t.Errorf("UserRegistry.Get(%q) after DictBot.setPreference() == %+v, %v", "unfollowed", user, err)
+	}
+
+	// Only once a day
+	clock.Advance(2 * time.Hour)
+	bot.PushDueReviews(clock.Now())
+	if got := pushedTo(); len(got) != 3 || got[2] != "quiet" {
+		t.Errorf("DictBot.PushDueReviews() pushed to %q, want %q", got, []string{"bangkok", "newyork", "quiet"})
+	}
+
+	// New York's next day starts at 05:00 UTC, but it's still quiet there
+	clock.Advance(4 * time.Hour)
+	bot.PushDueReviews(clock.Now())
+	if got := pushedTo(); len(got) != 3 {
+		t.Errorf("DictBot.PushDueReviews() pushed to %q", got)
+	}
+	clock.Advance(8 * time.Hour)
+	bot.PushDueReviews(clock.Now())
+	if got := pushedTo(); len(got) != 4 || got[3] != "newyork" {
+		t.Errorf("DictBot.PushDueReviews() pushed to %q, want %q", got, []string{"bangkok", "newyork", "quiet", "newyork"})
+	}
+}
+
+func TestDictBotResponseReview(t *testing.T) {
+	adapter := newFakeAdapter()
+	clock := scheduler.NewFakeClock(time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC))
+	bot := NewDictBot(mockServiceController{}, adapter)
+	bot.Clock = clock
+	bot.Registry = store.NewMemoryUserRegistry()
+	bot.Reviews = store.NewMemoryReviewStore()
+	bot.Notebook = store.NewMemoryNotebook()
+	send := func(event *chat.Event) string {
+		event.Source = chat.Source{Type: chat.SourceTypeUser, UserID: "user1"}
+		event.ReplyToken = "dummy"
+		if err := bot.Response([]*chat.Event{event}); err != nil {
+			t.Errorf("DictBot.Response(%v) == %v, want %v", event.Type, err, nil)
+		}
+		requests := adapter.Requests()
+		return requests[len(requests)-1]
+	}
+	text := func(text string) *chat.Event {
+		return &chat.Event{Type: chat.EventTypeMessage, MessageType: chat.MessageTypeText, Text: text}
+	}
+	postback := func(action string, word string) *chat.Event {
+		return &chat.Event{Type: chat.EventTypePostback, Postback: Postback{Action: action, Word: word}.Encode()}
+	}
+
+	send(text("/save line"))
+	send(text("/save square"))
+	if got := send(text("/review")); !strings.Contains(got, "No words to review right now.") {
+		t.Errorf("DictBot.Response(/review) replied %q", got)
+	}
+	clock.Advance(24 * time.Hour)
+	if got := send(text("/review")); !strings.Contains(got, "2 word(s) to review.") || !strings.Contains(got, `"data":"1|rv_show|line"`) {
+		t.Errorf("DictBot.Response(/review) replied %q", got)
+	}
+	if got := send(postback(ActionReviewShow, "line")); !strings.Contains(got, "line: dummy") || !strings.Contains(got, `"data":"1|rv_good|line"`) {
+		t.Errorf("DictBot.Response(show answer) replied %q", got)
+	}
+	if got := send(postback(ActionReviewGood, "line")); !strings.Contains(got, "again in 1 day(s).") || !strings.Contains(got, "1 more to go.") || !strings.Contains(got, `"data":"1|rv_show|square"`) {
+		t.Errorf("DictBot.Response(good) replied %q", got)
+	}
+	if got := send(postback(ActionReviewAgain, "square")); !strings.Contains(got, "That's all for now, well done!") {
+		t.Errorf("DictBot.Response(again) replied %q", got)
+	}
+	send(text("/remove square"))
+	if got := send(postback(ActionReviewEasy, "square")); !strings.Contains(got, "'square' is no longer in your notebook.") {
+		t.Errorf("DictBot.Response(easy) replied %q", got)
+	}
+
+	cases := []struct {
+		text string
+		want string
+	}{
+		{"/review off", "Daily reviews are off."},
+		{"/review later", "Usage: /review [on|off]"},
+		{"/timezone", "Your time zone is Asia/Bangkok."},
+		{"/timezone Mars/Olympus", "Unknown time zone 'Mars/Olympus'"},
+		{"/timezone Europe/London", "Your time zone is now Europe/London."},
+		{"/quiet 25-7", "Usage: /quiet"},
+		{"/quiet 23-6", "Your quiet hours are now 23-6."},
+		{"/quiet", "Your quiet hours are 23-6."},
+	}
+	for _, c := range cases {
+		if got := send(text(c.text)); !strings.Contains(got, c.want) {
+			t.Errorf("DictBot.Response(%q) replied %q, want %q", c.text, got, c.want)
+		}
+	}
+	user, _ := bot.Registry.Get("user1")
+	if user.Preferences[PreferenceReview] != "off" || user.Preferences[PreferenceTimezone] != "Europe/London" {
+		t.Errorf("Registry.Get(%q) preferences == %v", "user1", user.Preferences)
+	}
+}
@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"github.com/choobot/choo-dict-bot/app/chat"
+)
+
+type Handler func(event *chat.Event) error
+
+type PostbackHandler func(event *chat.Event, postback Postback) error
+
+type Middleware func(next Handler) Handler
+
+type Router struct {
+	eventHandlers          map[chat.EventType]Handler
+	messageHandlers        map[chat.MessageType]Handler
+	postbackHandlers       map[string]PostbackHandler
+	unknownPostbackHandler Handler
+	middlewares            []Middleware
+}
+
+func NewRouter() *Router {
+	return &Router{
+		eventHandlers:    map[chat.EventType]Handler{},
+		messageHandlers:  map[chat.MessageType]Handler{},
+		postbackHandlers: map[string]PostbackHandler{},
+	}
+}
+
+// Handle registers a handler for a whole event type. For message events it
+// is used only when no handler is registered for the message subtype.
+func (this *Router) Handle(eventType chat.EventType, handler Handler) {
+	this.eventHandlers[eventType] = handler
+}
+
+func (this *Router) HandleMessage(messageType chat.MessageType, handler Handler) {
+	this.messageHandlers[messageType] = handler
+}
+
+func (this *Router) HandlePostback(action string, handler PostbackHandler) {
+	this.postbackHandlers[action] = handler
+}
+
+// HandleUnknownPostback registers the handler for postbacks that cannot be
+// decoded or have no registered action, e.g. buttons from an older version.
+func (this *Router) HandleUnknownPostback(handler Handler) {
+	this.unknownPostbackHandler = handler
+}
+
+// Use appends middlewares; the first one registered is the outermost.
+func (this *Router) Use(middlewares ...Middleware) {
+	this.middlewares = append(this.middlewares, middlewares...)
+}
+
+func (this *Router) Dispatch(event *chat.Event) error {
+	handler := this.handler(event)
+	if handler == nil {
+		return nil
+	}
+	for i := len(this.middlewares) - 1; i >= 0; i-- {
+		handler = this.middlewares[i](handler)
+	}
+	return handler(event)
+}
+
+func (this *Router) handler(event *chat.Event) Handler {
+	switch event.Type {
+	case chat.EventTypeMessage:
+		if handler, ok := this.messageHandlers[event.MessageType]; ok {
+			return handler
+		}
+	case chat.EventTypePostback:
+		postback, err := DecodePostback(event.Postback)
+		if err == nil {
+			if handler, ok := this.postbackHandlers[postback.Action]; ok {
+				return func(event *chat.Event) error {
+					return handler(event, postback)
+				}
+			}
+		}
+		return this.unknownPostbackHandler
+	}
+	return this.eventHandlers[event.Type]
+}
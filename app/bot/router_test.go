@@ -0,0 +1,82 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+)
+
+func TestRouterDispatch(t *testing.T) {
+	router := NewRouter()
+	handled := ""
+	router.Handle(chat.EventTypeMessage, func(event *chat.Event) error {
+		handled = "message"
+		return nil
+	})
+	router.HandleMessage(chat.MessageTypeText, func(event *chat.Event) error {
+		handled = "text"
+		return nil
+	})
+	router.Handle(chat.EventTypeFollow, func(event *chat.Event) error {
+		handled = "follow"
+		return errors.New("follow error")
+	})
+	router.HandlePostback(ActionSynonyms, func(event *chat.Event, postback Postback) error {
+		handled = "postback " + postback.Action + " " + postback.Word
+		return nil
+	})
+	router.HandleUnknownPostback(func(event *chat.Event) error {
+		handled = "unknown postback"
+		return nil
+	})
+	cases := []struct {
+		event chat.Event
+		want  string
+		err   error
+	}{
+		{chat.Event{Type: chat.EventTypeMessage, MessageType: chat.MessageTypeText, Text: "line"}, "text", nil},
+		{chat.Event{Type: chat.EventTypeMessage, MessageType: chat.MessageTypeSticker}, "message", nil},
+		{chat.Event{Type: chat.EventTypeFollow}, "follow", errors.New("follow error")},
+		{chat.Event{Type: chat.EventTypePostback, Postback: "1|syn|line"}, "postback syn line", nil},
+		{chat.Event{Type: chat.EventTypePostback, Postback: "1|ant|line"}, "unknown postback", nil},
+		{chat.Event{Type: chat.EventTypePostback, Postback: "garbage"}, "unknown postback", nil},
+		{chat.Event{Type: chat.EventType("beacon")}, "", nil},
+	}
+	for _, c := range cases {
+		handled = ""
+		err := router.Dispatch(&c.event)
+		if handled != c.want || (c.err == nil && err != nil) || (c.err != nil && (err == nil || err.Error() != c.err.Error())) {
+			t.Errorf("Router.Dispatch(%v) handled %q, %v, want %q, %v", c.event.Type, handled, err, c.want, c.err)
+		}
+	}
+}
+
+func TestRouterUse(t *testing.T) {
+	router := NewRouter()
+	order := ""
+	middleware := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(event *chat.Event) error {
+				order += name + ">"
+				return next(event)
+			}
+		}
+	}
+	router.Use(middleware("a"), middleware("b"))
+	router.Handle(chat.EventTypeJoin, func(event *chat.Event) error {
+		order += "handler"
+		return nil
+	})
+	router.Dispatch(&chat.Event{Type: chat.EventTypeJoin})
+	if order != "a>b>handler" {
+		t.Errorf("Router.Dispatch() ran %q, want %q", order, "a>b>handler")
+	}
+
+	// Middlewares are skipped for events without handler
+	order = ""
+	router.Dispatch(&chat.Event{Type: chat.EventTypeLeave})
+	if order != "" {
+		t.Errorf("Router.Dispatch() ran %q, want %q", order, "")
+	}
+}
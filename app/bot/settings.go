@@ -0,0 +1,283 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/i18n"
+	"github.com/choobot/choo-dict-bot/app/service"
+)
+
+const (
+	ActionSettingNext  = "set"
+	ActionSettingReset = "set_reset"
+	SettingSenses      = "senses"
+	SettingSynonyms    = "synonyms"
+	SettingExamples    = "examples"
+	SettingIPA         = "ipa"
+	SettingDialect     = "dialect"
+	SettingLanguage    = "language"
+	SettingTranslate   = "translate"
+)
+
+// Settings tailor lookups and replies to a user. They are stored as user
+// preferences named after the settings.
+type Settings struct {
+	Senses           int
+	SynonymsPerSense int
+	Examples         bool
+	IPA              bool
+	Dialect          string
+	Language         string
+	TranslateTo      string
+}
+
+func (this Settings) LookupOptions() service.LookupOptions {
+	options := service.LookupOptions{
+		Senses:           this.Senses,
+		SynonymsPerSense: this.SynonymsPerSense,
+		Examples:         this.Examples,
+		Pronunciation:    this.IPA,
+		Dialect:          this.Dialect,
+		TranslateTo:      this.TranslateTo,
+	}
+	if options.Dialect == "any" {
+		options.Dialect = ""
+	}
+	if options.TranslateTo == "off" {
+		options.TranslateTo = ""
+	}
+	return options
+}
+
+type setting struct {
+	name  string
+	label i18n.Code
+	// values are the allowed values, the first one is the default
+	values []string
+}
+
+var settings = []setting{
+	{SettingSenses, i18n.SensesLabel, []string{"1", "2", "3", "5"}},
+	{SettingSynonyms, i18n.SynonymsPerSenseLabel, []string{"5", "3", "10"}},
+	{SettingExamples, i18n.ExamplesSettingLabel, []string{"off", "on"}},
+	{SettingIPA, i18n.PronunciationLabel, []string{"off", "on"}},
+	{SettingDialect, i18n.DialectLabel, []string{"any", "gb", "us"}},
+	{SettingLanguage, i18n.ReplyLanguageLabel, []string{"en", "th"}},
+	{SettingTranslate, i18n.TranslateToLabel, []string{"off", "es", "de", "pt", "id", "ms"}},
+}
+
+var translationLanguages = map[string]i18n.Code{
+	"es": i18n.Spanish,
+	"de": i18n.German,
+	"pt": i18n.Portuguese,
+	"id": i18n.Indonesian,
+	"ms": i18n.Malay,
+}
+
+func findSetting(name string) (setting, bool) {
+	for _, s := range settings {
+		if s.name == name {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+func (this setting) valid(value string) bool {
+	for _, v := range this.values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (this *DictBot) registerSettingsCommands() {
+	this.Commands.Register(&Command{
+		Name:    "settings",
+		Aliases: []string{"set", "prefs"},
+		Usage:   "/settings [name value|reset]",
+		Descriptions: map[string]string{
+			"en": "Choose how much I tell you about a word",
+			"th": "ตั้งค่ารายละเอียดที่ต้องการเมื่อค้นหาคำ",
+		},
+		Handler: this.handleSettingsCommand,
+	})
+	this.Router.HandlePostback(ActionSettingNext, this.handleSettingNext)
+	this.Router.HandlePostback(ActionSettingReset, func(event *chat.Event, postback Postback) error {
+		return this.resetSettings(event)
+	})
+}
+
+// settingValue returns the user's value of a setting. The reply language
+// defaults to the language of the user's chat app when it is supported.
+func (this *DictBot) settingValue(userID string, s setting) string {
+	fallback := s.values[0]
+	if s.name == SettingLanguage && this.Registry != nil {
+		if user, err := this.Registry.Get(userID); err == nil && s.valid(user.Language) {
+			fallback = user.Language
+		}
+	}
+	if value := this.preference(userID, s.name, fallback); s.valid(value) {
+		return value
+	}
+	return fallback
+}
+
+func (this *DictBot) settings(userID string) Settings {
+	values := map[string]string{}
+	for _, s := range settings {
+		values[s.name] = s.values[0]
+		if userID != "" {
+			values[s.name] = this.settingValue(userID, s)
+		}
+	}
+	result := Settings{
+		Examples:    values[SettingExamples] == "on",
+		IPA:         values[SettingIPA] == "on",
+		Dialect:     values[SettingDialect],
+		Language:    values[SettingLanguage],
+		TranslateTo: values[SettingTranslate],
+	}
+	result.Senses, _ = strconv.Atoi(values[SettingSenses])
+	result.SynonymsPerSense, _ = strconv.Atoi(values[SettingSynonyms])
+	return result
+}
+
+// settingsUserID returns the user's ID, or "" after replying why settings
+// can't be changed for this event.
+func (this *DictBot) settingsUserID(event *chat.Event) (string, error) {
+	if this.Registry == nil {
+		return "", this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.SettingsUnavailable)))
+	}
+	if event.Source.UserID == "" {
+		return "", this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.SettingsNeedFriend)))
+	}
+	return event.Source.UserID, nil
+}
+
+func (this *DictBot) handleSettingsCommand(event *chat.Event, args []string) error {
+	userID, err := this.settingsUserID(event)
+	if userID == "" {
+		return err
+	}
+	if len(args) == 0 {
+		return this.reply(event, this.settingsMessage(event.Source))
+	}
+	if strings.ToLower(args[0]) == "reset" {
+		return this.resetSettings(event)
+	}
+	s, ok := findSetting(strings.ToLower(args[0]))
+	if !ok || len(args) < 2 || !s.valid(strings.ToLower(args[1])) {
+		names := []string{}
+		for _, s := range settings {
+			names = append(names, s.name+" ("+strings.Join(s.values, "|")+")")
+		}
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.SettingsUsage, strings.Join(names, "\n"))))
+	}
+	value := strings.ToLower(args[1])
+	if err := this.setPreference(userID, s.name, value); err != nil {
+		return err
+	}
+	return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.SettingChanged, this.text(event.Source, s.label), value)))
+}
+
+// handleSettingNext moves a setting to its next value and shows the menu
+// again.
+func (this *DictBot) handleSettingNext(event *chat.Event, postback Postback) error {
+	userID, err := this.settingsUserID(event)
+	if userID == "" {
+		return err
+	}
+	s, ok := findSetting(postback.Word)
+	if !ok {
+		return this.handleUnknownPostback(event)
+	}
+	current := this.settingValue(userID, s)
+	next := s.values[0]
+	for i, value := range s.values {
+		if value == current && i+1 < len(s.values) {
+			next = s.values[i+1]
+		}
+	}
+	if err := this.setPreference(userID, s.name, next); err != nil {
+		return err
+	}
+	return this.reply(event, this.settingsMessage(event.Source))
+}
+
+func (this *DictBot) resetSettings(event *chat.Event) error {
+	userID, err := this.settingsUserID(event)
+	if userID == "" {
+		return err
+	}
+	for _, s := range settings {
+		value := s.values[0]
+		if s.name == SettingLanguage {
+			// Back to the language of the user's chat app
+			value = ""
+		}
+		if err := this.setPreference(userID, s.name, value); err != nil {
+			return err
+		}
+	}
+	return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.SettingsReset)))
+}
+
+func (this *DictBot) settingsMessage(source chat.Source) chat.Message {
+	title := this.text(source, i18n.YourSettings)
+	card := &chat.Card{
+		Title: title,
+		Note:  this.text(source, i18n.TapToChange),
+		Buttons: []chat.Button{
+			chat.NewPostbackButton(this.text(source, i18n.ResetToDefaults), Postback{Action: ActionSettingReset}.Encode()),
+		},
+	}
+	lines := []string{title + ":"}
+	for _, s := range settings {
+		value := this.settingValue(source.UserID, s)
+		label := this.text(source, s.label)
+		lines = append(lines, this.text(source, i18n.SettingsLine, label, value))
+		data := Postback{Action: ActionSettingNext, Word: s.name}.Encode()
+		card.Rows = append(card.Rows, chat.CardRow{Label: label, Button: chat.NewPostbackButton(value, data)})
+	}
+	return chat.Message{Text: strings.Join(lines, "\n"), Card: card}
+}
+
+// renderEntry turns an entry into the texts of the definitions, the
+// translations and the synonyms replies. The translations are "" when the
+// user doesn't want them.
+func renderEntry(entry service.Entry, settings Settings) (string, string, string) {
+	if len(entry.Senses) == 0 {
+		return i18n.Text(settings.Language, i18n.NoDefinition, entry.Word), "", i18n.Text(settings.Language, i18n.NoSynonyms, entry.Word)
+	}
+	lines := []string{}
+	if settings.IPA && len(entry.Pronunciations) > 0 {
+		lines = append(lines, strings.Join(entry.Pronunciations, ", "))
+	}
+	if len(entry.Senses) == 1 && len(entry.Senses[0].Examples) == 0 {
+		lines = append(lines, entry.Senses[0].Definition)
+	} else {
+		for i, sense := range entry.Senses {
+			lines = append(lines, strconv.Itoa(i+1)+". "+sense.Definition)
+			for _, example := range sense.Examples {
+				lines = append(lines, i18n.Text(settings.Language, i18n.ExampleLine, example))
+			}
+		}
+	}
+	synonyms := i18n.Text(settings.Language, i18n.NoSynonyms, entry.Word)
+	if len(entry.Synonyms) > 0 {
+		synonyms = service.JoinWords(entry.Synonyms)
+	}
+	translations := ""
+	if settings.TranslateTo != "" && settings.TranslateTo != "off" {
+		language := i18n.Text(settings.Language, translationLanguages[settings.TranslateTo])
+		translations = i18n.Text(settings.Language, i18n.NoTranslation, language, entry.Word)
+		if len(entry.Translations) > 0 {
+			translations = i18n.Text(settings.Language, i18n.Translation, language, strings.Join(entry.Translations, ", "))
+		}
+	}
+	return strings.Join(lines, "\n"), translations, synonyms
+}
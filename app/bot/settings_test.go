@@ -0,0 +1,138 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/service"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+func TestRenderEntry(t *testing.T) {
+	entry := service.Entry{
+		Word: "line",
+		Senses: []service.Sense{
+			{Definition: "a long, narrow mark or band", Examples: []string{"a row of closely spaced dots"}},
+			{Definition: "a length of cord"},
+		},
+		Synonyms:       []string{"stroke", "band"},
+		Pronunciations: []string{"/lʌɪn/"},
+		Translations:   []string{"línea", "cuerda"},
+	}
+	cases := []struct {
+		entry                               service.Entry
+		settings                            Settings
+		definitions, translations, synonyms string
+	}{
+		{
+			service.Entry{Word: "line", Senses: []service.Sense{{Definition: "a long, narrow mark or band"}}, Synonyms: []string{"stroke"}},
+			Settings{Senses: 1},
+			"a long, narrow mark or band", "", "stroke",
+		},
+		{
+			entry,
+			Settings{Senses: 2, Examples: true, IPA: true, TranslateTo: "es"},
+			"/lʌɪn/\n1. a long, narrow mark or band\n   e.g. a row of closely spaced dots\n2. a length of cord", "Spanish: línea, cuerda", "band and stroke",
+		},
+		{
+			service.Entry{Word: "xyz"},
+			Settings{Senses: 1, TranslateTo: "es"},
+			"No definition for 'xyz'.", "", "No synonyms for 'xyz'.",
+		},
+		{
+			service.Entry{Word: "line", Senses: []service.Sense{{Definition: "a long, narrow mark or band"}}},
+			Settings{Senses: 1, TranslateTo: "de"},
+			"a long, narrow mark or band", "No German translation for 'line'.", "No synonyms for 'line'.",
+		},
+	}
+	for _, c := range cases {
+		definitions, translations, synonyms := renderEntry(c.entry, c.settings)
+		if definitions != c.definitions || translations != c.translations || synonyms != c.synonyms {
+			t.Errorf("renderEntry(%+v, %+v) == %q, %q, %q, want %q, %q, %q", c.entry, c.settings, definitions, translations, synonyms, c.definitions, c.translations, c.synonyms)
+		}
+	}
+}
+
+func TestDictBotSettings(t *testing.T) {
+	bot := NewDictBot(mockServiceController{}, nil)
+	bot.Registry = store.NewMemoryUserRegistry()
+	bot.Registry.Follow(store.User{ID: "user1", Language: "th"})
+	if got, want := bot.settings(""), (Settings{Senses: 1, SynonymsPerSense: 5, Dialect: "any", Language: "en", TranslateTo: "off"}); got != want {
+		t.Errorf("DictBot.settings(%q) == %+v, want %+v", "", got, want)
+	}
+	if got := bot.settings("user1"); got.Language != "th" {
+		t.Errorf("DictBot.settings(%q).Language == %q, want the profile language %q", "user1", got.Language, "th")
+	}
+	bot.Registry.SetPreference("user1", SettingSenses, "3")
+	bot.Registry.SetPreference("user1", SettingDialect, "gb")
+	bot.Registry.SetPreference("user1", SettingSynonyms, "100")
+	options := bot.settings("user1").LookupOptions()
+	if want := (service.LookupOptions{Senses: 3, SynonymsPerSense: 5, Dialect: "gb"}); options != want {
+		t.Errorf("DictBot.settings(%q).LookupOptions() == %+v, want %+v", "user1", options, want)
+	}
+}
+
+func TestDictBotResponseSettings(t *testing.T) {
+	adapter := newFakeAdapter()
+	bot := NewDictBot(mockServiceController{}, adapter)
+	bot.Registry = store.NewMemoryUserRegistry()
+	send := func(source chat.Source, event *chat.Event) string {
+		event.Source = source
+		event.ReplyToken = "dummy"
+		if err := bot.Response([]*chat.Event{event}); err != nil {
+			t.Errorf("DictBot.Response(%v) == %v, want %v", event.Type, err, nil)
+		}
+		requests := adapter.Requests()
+		return requests[len(requests)-1]
+	}
+	text := func(text string) *chat.Event {
+		return &chat.Event{Type: chat.EventTypeMessage, MessageType: chat.MessageTypeText, Text: text}
+	}
+	next := func(name string) *chat.Event {
+		return &chat.Event{Type: chat.EventTypePostback, Postback: Postback{Action: ActionSettingNext, Word: name}.Encode()}
+	}
+	user := chat.Source{Type: chat.SourceTypeUser, UserID: "user1"}
+	anonymous := chat.Source{Type: chat.SourceTypeGroup, ChatID: "group1"}
+
+	if got := send(user, text("/settings")); !strings.Contains(got, `"card":{"title":"Your settings"`) || !strings.Contains(got, `Senses: 1\n`) || !strings.Contains(got, `"data":"1|set|senses"`) {
+		t.Errorf("DictBot.Response(/settings) replied %q", got)
+	}
+	if got := send(user, next(SettingSenses)); !strings.Contains(got, `Senses: 2\n`) {
+		t.Errorf("DictBot.Response(next senses) replied %q", got)
+	}
+	if got := send(user, text("/settings examples on")); !strings.Contains(got, "Examples is now on.") {
+		t.Errorf("DictBot.Response(/settings examples on) replied %q", got)
+	}
+	if got := send(user, text("/settings translate es")); !strings.Contains(got, "Translate to is now es.") {
+		t.Errorf("DictBot.Response(/settings translate es) replied %q", got)
+	}
+	if got := send(user, text("/settings senses 4")); !strings.Contains(got, "where the settings are:") {
+		t.Errorf("DictBot.Response(/settings senses 4) replied %q", got)
+	}
+	got := send(user, text("line"))
+	if !strings.Contains(got, `1. dummy\n   e.g. example of line\n2. dummy`) || !strings.Contains(got, `"text":"Spanish: es line"`) {
+		t.Errorf("DictBot.Response(line) with settings replied %q", got)
+	}
+	for i := 0; i < 4; i++ {
+		send(user, next(SettingTranslate))
+	}
+	if got := send(user, next(SettingTranslate)); !strings.Contains(got, `Translate to: off`) {
+		t.Errorf("DictBot.Response(next translate) didn't wrap around, replied %q", got)
+	}
+	if got := send(user, text("/settings language th")); !strings.Contains(got, "ตั้งภาษาที่ใช้ตอบเป็น th แล้ว") {
+		t.Errorf("DictBot.Response(/settings language th) replied %q", got)
+	}
+	if got := send(user, text("/help")); !strings.Contains(got, "ความหมายและคำพ้องความหมายของคำ") {
+		t.Errorf("DictBot.Response(/help) in Thai replied %q", got)
+	}
+	if got := send(user, text("/settings reset")); !strings.Contains(got, "Your settings are back to the defaults.") {
+		t.Errorf("DictBot.Response(/settings reset) replied %q", got)
+	}
+	if got := send(user, text("line")); !strings.Contains(got, `"text":"dummy"`) || strings.Contains(got, "Spanish") {
+		t.Errorf("DictBot.Response(line) after reset replied %q", got)
+	}
+	if got := send(anonymous, text("/settings")); !strings.Contains(got, "Please add me as a friend") {
+		t.Errorf("DictBot.Response(/settings) without a user ID replied %q", got)
+	}
+}
@@ -0,0 +1,321 @@
+package bot
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/i18n"
+	"github.com/choobot/choo-dict-bot/app/service"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+const (
+	LevelCurated = "curated"
+	LevelEasy    = "easy"
+	LevelMedium  = "medium"
+	LevelHard    = "hard"
+	// LINE accepts up to 500 user IDs in one multicast
+	maxMulticastRecipients = 500
+	wordOfTheDayRequester  = "word_of_the_day"
+	// Today somewhere in the world is one of three dates
+	maxWordOfTheDayCacheDays = 3
+)
+
+var DefaultCuratedWords = []string{
+	"serendipity", "eloquent", "resilient", "meticulous", "ambiguous", "pragmatic", "ubiquitous", "candid",
+	"tenacious", "whimsical", "lucid", "benevolent", "frugal", "gregarious", "nostalgia", "ephemeral",
+	"audacious", "placid", "zeal", "wanderlust", "quaint", "mellifluous", "sagacious", "vivacious",
+}
+
+// wordOfTheDayBands maps difficulty levels to the quiz word bands.
+var wordOfTheDayBands = map[string]int{
+	LevelEasy:   0,
+	LevelMedium: 1,
+	LevelHard:   2,
+}
+
+func (this *DictBot) registerWordOfTheDayCommands() {
+	this.Commands.Register(&Command{
+		Name:    "wotd",
+		Aliases: []string{"daily", "wordoftheday"},
+		Usage:   "/wotd [on|off|curated|easy|medium|hard]",
+		Descriptions: map[string]string{
+			"en": "Today's word, or get a new word every morning",
+			"th": "คำศัพท์ประจำวัน หรือรับคำศัพท์ใหม่ทุกเช้า",
+		},
+		Handler: this.handleWordOfTheDayCommand,
+	})
+	this.Router.Handle(chat.EventTypeLeave, this.handleLeave)
+}
+
+// WordOfTheDay returns the word of a level for a date, the same for every
+// subscriber of the level.
+func (this *DictBot) WordOfTheDay(level string, day time.Time) string {
+	words := this.CuratedWords
+	if band, ok := wordOfTheDayBands[level]; ok {
+		words = quizWords[band]
+	}
+	if len(words) == 0 {
+		return ""
+	}
+	days := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60)
+	return words[days%int64(len(words))]
+}
+
+// wordOfTheDay is what the dictionary says about the word of a day.
+type wordOfTheDay struct {
+	definitions    string
+	examples       string
+	pronunciations string
+}
+
+// wordOfTheDayCache keeps the words of the last few days, so that the
+// dictionary is asked once a day per word however many people ask for it.
+type wordOfTheDayCache struct {
+	days    map[string]map[string]wordOfTheDay
+	daysMux sync.Mutex
+}
+
+func newWordOfTheDayCache() *wordOfTheDayCache {
+	return &wordOfTheDayCache{
+		days: map[string]map[string]wordOfTheDay{},
+	}
+}
+
+func (this *wordOfTheDayCache) add(day string, word string, entry wordOfTheDay) {
+	this.daysMux.Lock()
+	defer this.daysMux.Unlock()
+	if this.days[day] == nil {
+		this.days[day] = map[string]wordOfTheDay{}
+	}
+	this.days[day][word] = entry
+	// Subscribers across time zones can be a day apart, older days are done
+	for len(this.days) > maxWordOfTheDayCacheDays {
+		oldest := day
+		for other := range this.days {
+			if other < oldest {
+				oldest = other
+			}
+		}
+		delete(this.days, oldest)
+	}
+}
+
+func (this *wordOfTheDayCache) get(day string, word string) (wordOfTheDay, bool) {
+	this.daysMux.Lock()
+	defer this.daysMux.Unlock()
+	entry, ok := this.days[day][word]
+	return entry, ok
+}
+
+// wordOfTheDayMessage returns the message of the word of a day in a
+// language, calling the dictionary on behalf of the requester only the first
+// time that day.
+func (this *DictBot) wordOfTheDayMessage(requester chat.Source, language string, word string, day string) (chat.Message, error) {
+	entry, ok := this.wordsOfTheDay.get(day, word)
+	if !ok {
+		definitions, _, err := this.ServiceController.FindDefinitionsAndSynonyms(requesterID(requester), requester.ChatID, word)
+		if err != nil {
+			return chat.Message{}, err
+		}
+		examples, err := this.ServiceController.FindExamples(requesterID(requester), requester.ChatID, word)
+		if err != nil && !errors.Is(err, service.ErrNotFound) {
+			return chat.Message{}, err
+		}
+		pronunciations, err := this.ServiceController.FindPronunciations(requesterID(requester), requester.ChatID, word)
+		if err != nil && !errors.Is(err, service.ErrNotFound) {
+			return chat.Message{}, err
+		}
+		entry = wordOfTheDay{definitions: definitions, examples: examples, pronunciations: pronunciations}
+		this.wordsOfTheDay.add(day, word, entry)
+	}
+	text := i18n.Text(language, i18n.WordOfTheDay, word)
+	if entry.pronunciations != "" {
+		text += "\n" + entry.pronunciations
+	}
+	text += "\n\n" + entry.definitions
+	if entry.examples != "" {
+		text += "\n\n" + i18n.Text(language, i18n.WordOfTheDayExamples, entry.examples)
+	}
+	return chat.NewTextMessage(text).WithQuickReplies(this.QuickReplies(language, word)...), nil
+}
+
+func (this *DictBot) handleWordOfTheDayCommand(event *chat.Event, args []string) error {
+	if this.Subscriptions == nil {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.WordOfTheDayUnavailable)))
+	}
+	id, inGroup := event.Source.ChatID, true
+	if id == "" {
+		id, inGroup = event.Source.UserID, false
+	}
+	subscription, err := this.Subscriptions.Get(id)
+	subscribed := err == nil
+	if err != nil && err != store.ErrSubscriptionNotFound {
+		return err
+	}
+	if len(args) == 0 {
+		level := LevelCurated
+		if subscribed {
+			level = subscription.Level
+		}
+		local := this.Clock.Now().In(this.location(id))
+		message, err := this.wordOfTheDayMessage(event.Source, this.language(event.Source), this.WordOfTheDay(level, local), local.Format("2006-01-02"))
+		if err != nil {
+			return this.reply(event, chat.NewTextMessage(this.errorText(event.Source, err)))
+		}
+		return this.reply(event, message)
+	}
+	setting := strings.ToLower(args[0])
+	if setting == "broadcast" {
+		return this.broadcastWordOfTheDay(event)
+	}
+	_, knownLevel := wordOfTheDayBands[setting]
+	if setting != "on" && setting != "off" && setting != LevelCurated && !knownLevel {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.Usage, "/wotd [on|off|curated|easy|medium|hard]")))
+	}
+	if group := this.group(id); inGroup && len(group.Admins) > 0 && !this.isAdmin(group, event.Source.UserID) {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.WordOfTheDayAdminsOnly)))
+	}
+	if setting == "off" {
+		if err := this.Subscriptions.Unsubscribe(id); err != nil && err != store.ErrSubscriptionNotFound {
+			return err
+		}
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.WordOfTheDayOff)))
+	}
+	if !subscribed {
+		subscription = store.Subscription{ChatID: id, Group: inGroup, Level: LevelCurated, SubscribedAt: this.Clock.Now()}
+	}
+	if setting != "on" {
+		subscription.Level = setting
+	}
+	if err := this.Subscriptions.Subscribe(subscription); err != nil {
+		return err
+	}
+	return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.WordOfTheDayOn, subscription.Level)))
+}
+
+// broadcastWordOfTheDay sends today's curated word to every friend of the
+// bot, subscribed or not. Only bot admins can do this.
+func (this *DictBot) broadcastWordOfTheDay(event *chat.Event) error {
+	if !this.isAdmin(store.Group{}, event.Source.UserID) {
+		return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.BroadcastAdminsOnly)))
+	}
+	local := this.Clock.Now().In(this.location(""))
+	// Broadcasts reach everyone, so they are in the default language
+	message, err := this.wordOfTheDayMessage(event.Source, i18n.English, this.WordOfTheDay(LevelCurated, local), local.Format("2006-01-02"))
+	if err != nil {
+		return this.reply(event, chat.NewTextMessage(this.errorText(event.Source, err)))
+	}
+	if err := this.Adapter.Broadcast(message); err != nil {
+		return err
+	}
+	return this.reply(event, chat.NewTextMessage(this.text(event.Source, i18n.Broadcasted)))
+}
+
+func (this *DictBot) handleLeave(event *chat.Event) error {
+	if this.Subscriptions == nil {
+		return nil
+	}
+	if err := this.Subscriptions.Unsubscribe(event.Source.ChatID); err != nil && err != store.ErrSubscriptionNotFound {
+		return err
+	}
+	return nil
+}
+
+// SendWordOfTheDay sends the word of the day to subscribers whose local
+// time has passed WordOfTheDayHour and who haven't got today's word yet.
+// Users are batched into multicasts by language, groups and rooms get a push
+// each. It is meant to be run by the scheduler every few minutes.
+func (this *DictBot) SendWordOfTheDay(now time.Time) error {
+	if this.Subscriptions == nil {
+		return nil
+	}
+	subscriptions, err := this.Subscriptions.List()
+	if err != nil {
+		return err
+	}
+	type batch struct {
+		word     string
+		day      string
+		language string
+		users    []string
+		groups   []string
+	}
+	batches := map[string]*batch{}
+	for _, subscription := range subscriptions {
+		if !subscription.Group && this.Registry != nil {
+			if user, err := this.Registry.Get(subscription.ChatID); err == nil && !user.Active {
+				continue
+			}
+		}
+		local := now.In(this.location(subscription.ChatID))
+		day := local.Format("2006-01-02")
+		if local.Hour() < this.WordOfTheDayHour || subscription.LastSentOn == day {
+			continue
+		}
+		word := this.WordOfTheDay(subscription.Level, local)
+		if word == "" {
+			continue
+		}
+		source := chat.Source{Type: chat.SourceTypeUser, UserID: subscription.ChatID}
+		if subscription.Group {
+			source = chat.Source{Type: chat.SourceTypeGroup, ChatID: subscription.ChatID}
+		}
+		language := this.language(source)
+		key := word + " " + day + " " + language
+		if batches[key] == nil {
+			batches[key] = &batch{word: word, day: day, language: language}
+		}
+		if subscription.Group {
+			batches[key].groups = append(batches[key].groups, subscription.ChatID)
+		} else {
+			batches[key].users = append(batches[key].users, subscription.ChatID)
+		}
+	}
+	if this.Plans != nil {
+		// The scheduled send is the bot's own, not any user's
+		this.Plans.Exempt(wordOfTheDayRequester)
+	}
+	keys := []string{}
+	for key := range batches {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	requester := chat.Source{Type: chat.SourceTypeUser, UserID: wordOfTheDayRequester}
+	for _, key := range keys {
+		batch := batches[key]
+		message, err := this.wordOfTheDayMessage(requester, batch.language, batch.word, batch.day)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		for start := 0; start < len(batch.users); start += maxMulticastRecipients {
+			end := start + maxMulticastRecipients
+			if end > len(batch.users) {
+				end = len(batch.users)
+			}
+			if err := this.Adapter.Multicast(batch.users[start:end], message); err != nil {
+				log.Println(err)
+				continue
+			}
+			if err := this.Subscriptions.MarkSent(batch.users[start:end], batch.day); err != nil {
+				log.Println(err)
+			}
+		}
+		for _, groupID := range batch.groups {
+			if err := this.Adapter.Push(groupID, message); err != nil {
+				log.Println(err)
+				continue
+			}
+			if err := this.Subscriptions.MarkSent([]string{groupID}, batch.day); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+	return nil
+}
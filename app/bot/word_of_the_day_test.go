@@ -0,0 +1,229 @@
+package bot
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/i18n"
+	"github.com/choobot/choo-dict-bot/app/scheduler"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+func TestDictBotWordOfTheDay(t *testing.T) {
+	bot := NewDictBot(mockServiceController{}, nil)
+	bangkok, _ := time.LoadLocation("Asia/Bangkok")
+	cases := []struct {
+		level string
+		day   time.Time
+		want  string
+	}{
+		{LevelCurated, time.Date(2018, 11, 20, 0, 0, 0, 0, time.UTC), DefaultCuratedWords[23]},
+		{LevelCurated, time.Date(2018, 11, 20, 23, 59, 0, 0, bangkok), DefaultCuratedWords[23]},
+		{LevelCurated, time.Date(2018, 11, 21, 6, 0, 0, 0, time.UTC), DefaultCuratedWords[0]},
+		{"unknown", time.Date(2018, 11, 21, 6, 0, 0, 0, time.UTC), DefaultCuratedWords[0]},
+		{LevelEasy, time.Date(2018, 11, 20, 8, 0, 0, 0, time.UTC), quizWords[0][23]},
+		{LevelHard, time.Date(2018, 11, 22, 8, 0, 0, 0, time.UTC), quizWords[2][1]},
+	}
+	for _, c := range cases {
+		got := bot.WordOfTheDay(c.level, c.day)
+		if got != c.want {
+			t.Errorf("DictBot.WordOfTheDay(%q, %v) == %q, want %q", c.level, c.day, got, c.want)
+		}
+	}
+}
+
+// requesterServiceController records who asks for definitions, and runs
+// out of quota for "limited".
+type requesterServiceController struct {
+	mockServiceController
+	requesters []string
+}
+
+func (this *requesterServiceController) FindDefinitionsAndSynonyms(userID string, chatID string, word string) (string, string, error) {
+	this.requesters = append(this.requesters, userID)
+	if userID == "limited" {
+		return "", "", i18n.NewError(i18n.RequestLimit)
+	}
+	return this.mockServiceController.FindDefinitionsAndSynonyms(userID, chatID, word)
+}
+
+func TestWordOfTheDayCache(t *testing.T) {
+	cache := newWordOfTheDayCache()
+	cache.add("2018-11-19", "line", wordOfTheDay{definitions: "a long, narrow mark or band"})
+	if entry, ok := cache.get("2018-11-19", "line"); !ok || entry.definitions != "a long, narrow mark or band" {
+		t.Errorf("wordOfTheDayCache.get(%q, %q) == %+v, %v", "2018-11-19", "line", entry, ok)
+	}
+	if _, ok := cache.get("2018-11-20", "line"); ok {
+		t.Errorf("wordOfTheDayCache.get(%q, %q) found the word of another day", "2018-11-20", "line")
+	}
+	for _, day := range []string{"2018-11-20", "2018-11-21", "2018-11-22"} {
+		cache.add(day, "line", wordOfTheDay{})
+	}
+	if _, ok := cache.get("2018-11-19", "line"); ok || len(cache.days) != maxWordOfTheDayCacheDays {
+		t.Errorf("wordOfTheDayCache kept %d days, want %d", len(cache.days), maxWordOfTheDayCacheDays)
+	}
+}
+
+func TestDictBotResponseWordOfTheDayRequester(t *testing.T) {
+	adapter := newFakeAdapter()
+	serviceController := &requesterServiceController{}
+	clock := scheduler.NewFakeClock(time.Date(2018, 11, 20, 1, 0, 0, 0, time.UTC))
+	bot := NewDictBot(serviceController, adapter)
+	bot.Clock = clock
+	bot.Registry = store.NewMemoryUserRegistry()
+	bot.Subscriptions = store.NewMemorySubscriptionStore()
+	bot.Registry.Follow(store.User{ID: "limited", Language: i18n.Thai})
+	send := func(userID string) string {
+		event := &chat.Event{Type: chat.EventTypeMessage, Source: chat.Source{Type: chat.SourceTypeUser, UserID: userID}, ReplyToken: "dummy", MessageType: chat.MessageTypeText, Text: "/wotd"}
+		bot.Response([]*chat.Event{event})
+		requests := adapter.Requests()
+		return requests[len(requests)-1]
+	}
+
+	// Users over their quota get told so in their language
+	if got, want := send("limited"), i18n.Text(i18n.Thai, i18n.RequestLimit); !strings.Contains(got, want) {
+		t.Errorf("DictBot.Response(/wotd) over the quota replied %q, want %q", got, want)
+	}
+	// The first user to ask is charged, everyone else that day gets the copy
+	send("user1")
+	send("user2")
+	bot.Subscriptions.Subscribe(store.Subscription{ChatID: "user3", Level: LevelCurated})
+	bot.SendWordOfTheDay(clock.Now().Add(time.Hour))
+	if got, want := strings.Join(serviceController.requesters, ","), "limited,user1"; got != want {
+		t.Errorf("DictBot.Response(/wotd) asked the dictionary for %q, want %q", got, want)
+	}
+	// The next day's scheduled send is the bot's own
+	clock.Advance(24 * time.Hour)
+	bot.SendWordOfTheDay(clock.Now().Add(7 * time.Hour))
+	if got, want := strings.Join(serviceController.requesters, ","), "limited,user1,"+wordOfTheDayRequester; got != want {
+		t.Errorf("DictBot.SendWordOfTheDay() asked the dictionary for %q, want %q", got, want)
+	}
+}
+
+func TestDictBotSendWordOfTheDay(t *testing.T) {
+	adapter := newFakeAdapter()
+	// 00:30 UTC is 07:30 in Bangkok and 19:30 the day before in New York
+	clock := scheduler.NewFakeClock(time.Date(2018, 11, 20, 0, 30, 0, 0, time.UTC))
+	bot := NewDictBot(mockServiceController{}, adapter)
+	bot.Clock = clock
+	bot.Registry = store.NewMemoryUserRegistry()
+	bot.Subscriptions = store.NewMemorySubscriptionStore()
+	for i := 0; i < maxMulticastRecipients+1; i++ {
+		bot.Subscriptions.Subscribe(store.Subscription{ChatID: "user" + strconv.Itoa(i), Level: LevelCurated})
+	}
+	bot.Subscriptions.Subscribe(store.Subscription{ChatID: "group1", Group: true, Level: LevelHard})
+	bot.Subscriptions.Subscribe(store.Subscription{ChatID: "unfollowed", Level: LevelCurated})
+	bot.Subscriptions.Subscribe(store.Subscription{ChatID: "newyork", Level: LevelCurated})
+	bot.Registry.Follow(store.User{ID: "unfollowed"})
+	bot.Registry.Unfollow("unfollowed", clock.Now())
+	bot.Registry.Follow(store.User{ID: "newyork", Preferences: map[string]string{PreferenceTimezone: "America/New_York"}})
+
+	sent := func() []string {
+		requests := []string{}
+		for _, request := range adapter.Requests() {
+			if strings.HasPrefix(request, "") {
+				requests = append(requests, request)
+			}
+		}
+		return requests
+	}
+	// New York is still on the 19th, so it gets the word of that day
+	bot.SendWordOfTheDay(clock.Now())
+	got := sent()
+	if len(got) != 1 || !strings.HasPrefix(got[0], "multicast") || !strings.Contains(got[0], `"to":["newyork"]`) || !strings.Contains(got[0], "Word of the day: "+DefaultCuratedWords[22]) {
+		t.Fatalf("DictBot.SendWordOfTheDay() before 08:00 in Bangkok sent %q", got)
+	}
+
+	clock.Advance(30 * time.Minute)
+	bot.SendWordOfTheDay(clock.Now())
+	got = sent()
+	if len(got) != 4 {
+		t.Fatalf("DictBot.SendWordOfTheDay() sent %d requests, want %d", len(got), 4)
+	}
+	if !strings.HasPrefix(got[1], "multicast") || strings.Count(got[1], `"user`) != maxMulticastRecipients || !strings.Contains(got[1], "Word of the day: "+DefaultCuratedWords[23]+`\npronunciations of `) {
+		t.Errorf("DictBot.SendWordOfTheDay() sent %q", got[1])
+	}
+	if !strings.HasPrefix(got[2], "multicast") || strings.Count(got[2], `"user`) != 1 || strings.Contains(got[2], "unfollowed") {
+		t.Errorf("DictBot.SendWordOfTheDay() sent %q", got[2])
+	}
+	if !strings.HasPrefix(got[3], "push") || !strings.Contains(got[3], `"to":"group1"`) || !strings.Contains(got[3], "Word of the day: "+quizWords[2][23]) || !strings.Contains(got[3], `examples of `+quizWords[2][23]) {
+		t.Errorf("DictBot.SendWordOfTheDay() sent %q", got[3])
+	}
+
+	clock.Advance(time.Hour)
+	bot.SendWordOfTheDay(clock.Now())
+	if got := sent(); len(got) != 4 {
+		t.Errorf("DictBot.SendWordOfTheDay() sent the word of the day twice: %q", got[4:])
+	}
+	// 13:00 UTC is 08:00 of the 20th in New York
+	clock.Advance(11 * time.Hour)
+	bot.SendWordOfTheDay(clock.Now())
+	if got := sent(); len(got) != 5 || !strings.Contains(got[4], `"to":["newyork"]`) || !strings.Contains(got[4], "Word of the day: "+DefaultCuratedWords[23]) {
+		t.Errorf("DictBot.SendWordOfTheDay() sent %q", got[4:])
+	}
+}
+
+func TestDictBotResponseWordOfTheDay(t *testing.T) {
+	adapter := newFakeAdapter()
+	bot := NewDictBot(mockServiceController{}, adapter)
+	bot.Clock = scheduler.NewFakeClock(time.Date(2018, 11, 20, 1, 0, 0, 0, time.UTC))
+	bot.Groups = store.NewMemoryGroupStore()
+	bot.Subscriptions = store.NewMemorySubscriptionStore()
+	bot.Admins = []string{"admin"}
+	bot.Groups.Save(store.Group{ID: "group1", Mode: store.GroupModeMention, Admins: []string{"user1"}})
+	send := func(source chat.Source, text string) string {
+		event := &chat.Event{Type: chat.EventTypeMessage, Source: source, ReplyToken: "dummy", MessageType: chat.MessageTypeText, Text: text}
+		if err := bot.Response([]*chat.Event{event}); err != nil {
+			t.Errorf("DictBot.Response(%q) == %v, want %v", text, err, nil)
+		}
+		requests := adapter.Requests()
+		return requests[len(requests)-1]
+	}
+	user := chat.Source{Type: chat.SourceTypeUser, UserID: "user1"}
+	member := chat.Source{Type: chat.SourceTypeGroup, ChatID: "group1", UserID: "user2"}
+	admin := chat.Source{Type: chat.SourceTypeGroup, ChatID: "group1", UserID: "user1"}
+
+	cases := []struct {
+		source chat.Source
+		text   string
+		want   string
+	}{
+		{user, "/wotd", "Word of the day: " + DefaultCuratedWords[23]},
+		{user, "/wotd sometimes", "Usage: /wotd"},
+		{user, "/wotd on", "I'll send the curated word of the day"},
+		{user, "/wotd hard", "I'll send the hard word of the day"},
+		{user, "/wotd", "Word of the day: " + quizWords[2][23]},
+		{user, "/wotd on", "I'll send the hard word of the day"},
+		{member, "/wotd on", "only group admins"},
+		{admin, "/wotd easy", "I'll send the easy word of the day"},
+		{user, "/wotd broadcast", "only bot admins"},
+	}
+	for _, c := range cases {
+		if got := send(c.source, c.text); !strings.Contains(got, c.want) {
+			t.Errorf("DictBot.Response(%q) replied %q, want %q", c.text, got, c.want)
+		}
+	}
+	if subscription, err := bot.Subscriptions.Get("group1"); err != nil || !subscription.Group || subscription.Level != LevelEasy {
+		t.Errorf("SubscriptionStore.Get(%q) == %+v, %v", "group1", subscription, err)
+	}
+
+	send(chat.Source{Type: chat.SourceTypeUser, UserID: "admin"}, "/wotd broadcast")
+	requests := adapter.Requests()
+	if got := requests[len(requests)-2]; !strings.HasPrefix(got, "broadcast") || !strings.Contains(got, "Word of the day: "+DefaultCuratedWords[23]) {
+		t.Errorf("DictBot.Response(/wotd broadcast) sent %q", got)
+	}
+
+	if got := send(user, "/wotd off"); !strings.Contains(got, "no more words of the day") {
+		t.Errorf("DictBot.Response(/wotd off) replied %q", got)
+	}
+	if _, err := bot.Subscriptions.Get("user1"); err != store.ErrSubscriptionNotFound {
+		t.Errorf("SubscriptionStore.Get(%q) == %v, want %v", "user1", err, store.ErrSubscriptionNotFound)
+	}
+	bot.Response([]*chat.Event{{Type: chat.EventTypeLeave, Source: chat.Source{Type: chat.SourceTypeGroup, ChatID: "group1"}}})
+	if _, err := bot.Subscriptions.Get("group1"); err != store.ErrSubscriptionNotFound {
+		t.Errorf("SubscriptionStore.Get(%q) after leave == %v, want %v", "group1", err, store.ErrSubscriptionNotFound)
+	}
+}
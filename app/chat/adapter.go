@@ -0,0 +1,27 @@
+package chat
+
+import (
+	"errors"
+)
+
+var ErrNotSupported = errors.New("not supported by the platform")
+
+type Profile struct {
+	DisplayName string
+	PictureURL  string
+	// Language is the language of the user's app, or "" when unknown
+	Language string
+}
+
+// Adapter sends messages on a chat platform. Adapters return
+// ErrNotSupported for what their platform can't do.
+type Adapter interface {
+	Reply(event *Event, messages ...Message) error
+	Push(to string, messages ...Message) error
+	Multicast(to []string, messages ...Message) error
+	Broadcast(messages ...Message) error
+	Profile(userID string) (Profile, error)
+	// IsGroupAdmin reports whether a user administers a group or room on the
+	// platform itself.
+	IsGroupAdmin(chatID string, userID string) (bool, error)
+}
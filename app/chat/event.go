@@ -0,0 +1,73 @@
+package chat
+
+import (
+	"time"
+)
+
+type EventType string
+
+const (
+	EventTypeMessage  EventType = "message"
+	EventTypePostback EventType = "postback"
+	EventTypeJoin     EventType = "join"
+	EventTypeLeave    EventType = "leave"
+	EventTypeFollow   EventType = "follow"
+	EventTypeUnfollow EventType = "unfollow"
+)
+
+type MessageType string
+
+const (
+	MessageTypeText     MessageType = "text"
+	MessageTypeImage    MessageType = "image"
+	MessageTypeVideo    MessageType = "video"
+	MessageTypeAudio    MessageType = "audio"
+	MessageTypeFile     MessageType = "file"
+	MessageTypeLocation MessageType = "location"
+	MessageTypeSticker  MessageType = "sticker"
+)
+
+type SourceType string
+
+const (
+	SourceTypeUser  SourceType = "user"
+	SourceTypeGroup SourceType = "group"
+	SourceTypeRoom  SourceType = "room"
+)
+
+// Source is who an event comes from. ChatID is the group or room, and is
+// "" in one-to-one chats. UserID may be "" in groups and rooms when the
+// platform doesn't tell who the member is.
+type Source struct {
+	Type   SourceType
+	UserID string
+	ChatID string
+}
+
+// Mention is a mention of a user in a message's text. Positions are counted
+// in UTF-16 code units.
+type Mention struct {
+	UserID string
+	Index  int
+	Length int
+}
+
+// Event is something that happened in a chat, translated from the webhook
+// of a platform by its adapter.
+type Event struct {
+	Type EventType
+	// ID identifies the event on the platform, so that an event delivered
+	// twice can be told apart. It is "" when the platform has no such ID.
+	ID         string
+	Source     Source
+	Timestamp  time.Time
+	Redelivery bool
+	// ReplyToken is what the adapter needs to reply to the event. It is ""
+	// for events that can't be replied to.
+	ReplyToken  string
+	MessageType MessageType
+	Text        string
+	Mentions    []Mention
+	// Postback is the data of the button that was tapped.
+	Postback string
+}
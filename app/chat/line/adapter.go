@@ -0,0 +1,211 @@
+package line
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+// Adapter runs the bot on the LINE Messaging API.
+type Adapter struct {
+	Client *linebot.Client
+	// PushOnRedelivery pushes replies to redelivered events whose reply
+	// token has expired. Push messages count against the LINE plan.
+	PushOnRedelivery bool
+}
+
+func NewAdapter(client *linebot.Client) *Adapter {
+	return &Adapter{
+		Client: client,
+	}
+}
+
+// ParseRequest checks the signature of a webhook request and returns its
+// events. It fails with linebot.ErrInvalidSignature for a bad signature.
+func (this *Adapter) ParseRequest(r *http.Request) ([]*chat.Event, error) {
+	lineEvents, err := this.Client.ParseRequest(r)
+	if err != nil {
+		return nil, err
+	}
+	events := []*chat.Event{}
+	for _, event := range lineEvents {
+		events = append(events, Event(event))
+	}
+	return events, nil
+}
+
+func (this *Adapter) Reply(event *chat.Event, messages ...chat.Message) error {
+	_, err := this.Client.ReplyMessage(event.ReplyToken, sendingMessages(messages)...).Do()
+	if err != nil && this.PushOnRedelivery && event.Redelivery && invalidReplyToken(err) {
+		to := event.Source.ChatID
+		if to == "" {
+			to = event.Source.UserID
+		}
+		return this.Push(to, messages...)
+	}
+	return err
+}
+
+func (this *Adapter) Push(to string, messages ...chat.Message) error {
+	_, err := this.Client.PushMessage(to, sendingMessages(messages)...).Do()
+	return err
+}
+
+func (this *Adapter) Multicast(to []string, messages ...chat.Message) error {
+	_, err := this.Client.Multicast(to, sendingMessages(messages)...).Do()
+	return err
+}
+
+func (this *Adapter) Broadcast(messages ...chat.Message) error {
+	_, err := this.Client.BroadcastMessage(sendingMessages(messages)...).Do()
+	return err
+}
+
+// IsGroupAdmin is not supported, as LINE groups and rooms have no admins.
+func (this *Adapter) IsGroupAdmin(chatID string, userID string) (bool, error) {
+	return false, chat.ErrNotSupported
+}
+
+func (this *Adapter) Profile(userID string) (chat.Profile, error) {
+	profile, err := this.Client.GetProfile(userID).Do()
+	if err != nil {
+		return chat.Profile{}, err
+	}
+	return chat.Profile{
+		DisplayName: profile.DisplayName,
+		PictureURL:  profile.PictureURL,
+		Language:    profile.Language,
+	}, nil
+}
+
+// Event translates a LINE webhook event.
+func Event(event *linebot.Event) *chat.Event {
+	result := &chat.Event{
+		Type:       chat.EventType(event.Type),
+		ID:         event.WebhookEventID,
+		Timestamp:  event.Timestamp,
+		Redelivery: event.DeliveryContext.IsRedelivery,
+		ReplyToken: event.ReplyToken,
+	}
+	if source := event.Source; source != nil {
+		result.Source = chat.Source{Type: chat.SourceType(source.Type), UserID: source.UserID}
+		switch source.Type {
+		case linebot.EventSourceTypeGroup:
+			result.Source.ChatID = source.GroupID
+		case linebot.EventSourceTypeRoom:
+			result.Source.ChatID = source.RoomID
+		}
+	}
+	if event.Postback != nil {
+		result.Postback = event.Postback.Data
+	}
+	switch message := event.Message.(type) {
+	case *linebot.TextMessage:
+		result.MessageType, result.Text = chat.MessageTypeText, message.Text
+		if message.Mention != nil {
+			for _, mentionee := range message.Mention.Mentionees {
+				result.Mentions = append(result.Mentions, chat.Mention{UserID: mentionee.UserID, Index: mentionee.Index, Length: mentionee.Length})
+			}
+		}
+	case *linebot.ImageMessage:
+		result.MessageType = chat.MessageTypeImage
+	case *linebot.VideoMessage:
+		result.MessageType = chat.MessageTypeVideo
+	case *linebot.AudioMessage:
+		result.MessageType = chat.MessageTypeAudio
+	case *linebot.FileMessage:
+		result.MessageType = chat.MessageTypeFile
+	case *linebot.LocationMessage:
+		result.MessageType = chat.MessageTypeLocation
+	case *linebot.StickerMessage:
+		result.MessageType = chat.MessageTypeSticker
+	}
+	return result
+}
+
+func sendingMessages(messages []chat.Message) []linebot.SendingMessage {
+	result := []linebot.SendingMessage{}
+	for _, message := range messages {
+		result = append(result, sendingMessage(message))
+	}
+	return result
+}
+
+func sendingMessage(message chat.Message) linebot.SendingMessage {
+	var quickReplies *linebot.QuickReplyItems
+	if len(message.QuickReplies) > 0 {
+		buttons := []*linebot.QuickReplyButton{}
+		for _, button := range message.QuickReplies {
+			buttons = append(buttons, linebot.NewQuickReplyButton("", action(button, true).(linebot.QuickReplyAction)))
+		}
+		quickReplies = linebot.NewQuickReplyItems(buttons...)
+	}
+	if message.Card != nil {
+		return linebot.NewFlexMessage(message.Text, bubble(message.Card)).WithQuickReplies(quickReplies)
+	}
+	return linebot.NewTextMessage(message.Text).WithQuickReplies(quickReplies)
+}
+
+// action turns a button into a LINE action. Tapped quick replies show their
+// label in the chat, as if the user had sent it. Both kinds of action it
+// returns are also quick reply actions.
+func action(button chat.Button, echo bool) linebot.TemplateAction {
+	if button.Data == "" {
+		return linebot.NewMessageAction(button.Label, button.Text)
+	}
+	displayText := ""
+	if echo {
+		displayText = button.Label
+	}
+	return linebot.NewPostbackAction(button.Label, button.Data, "", displayText, "", "")
+}
+
+func bubble(card *chat.Card) *linebot.BubbleContainer {
+	rows := []linebot.FlexComponent{
+		&linebot.TextComponent{Type: linebot.FlexComponentTypeText, Text: card.Title, Weight: linebot.FlexTextWeightTypeBold, Size: linebot.FlexTextSizeTypeLg},
+	}
+	labelFlex, valueFlex := 4, 2
+	for _, row := range card.Rows {
+		rows = append(rows, &linebot.BoxComponent{
+			Type:   linebot.FlexComponentTypeBox,
+			Layout: linebot.FlexBoxLayoutTypeHorizontal,
+			Contents: []linebot.FlexComponent{
+				&linebot.TextComponent{Type: linebot.FlexComponentTypeText, Text: row.Label, Flex: &labelFlex, Gravity: linebot.FlexComponentGravityTypeCenter, Size: linebot.FlexTextSizeTypeSm},
+				&linebot.ButtonComponent{Type: linebot.FlexComponentTypeButton, Flex: &valueFlex, Style: linebot.FlexButtonStyleTypeSecondary, Height: linebot.FlexButtonHeightTypeSm, Action: action(row.Button, false)},
+			},
+		})
+	}
+	bubble := &linebot.BubbleContainer{
+		Type: linebot.FlexContainerTypeBubble,
+		Body: &linebot.BoxComponent{
+			Type:     linebot.FlexComponentTypeBox,
+			Layout:   linebot.FlexBoxLayoutTypeVertical,
+			Spacing:  linebot.FlexComponentSpacingTypeSm,
+			Contents: rows,
+		},
+	}
+	footer := []linebot.FlexComponent{}
+	if card.Note != "" {
+		footer = append(footer, &linebot.TextComponent{Type: linebot.FlexComponentTypeText, Text: card.Note, Size: linebot.FlexTextSizeTypeXs, Color: "#888888"})
+	}
+	for _, button := range card.Buttons {
+		footer = append(footer, &linebot.ButtonComponent{Type: linebot.FlexComponentTypeButton, Style: linebot.FlexButtonStyleTypeLink, Action: action(button, false)})
+	}
+	if len(footer) > 0 {
+		bubble.Footer = &linebot.BoxComponent{
+			Type:     linebot.FlexComponentTypeBox,
+			Layout:   linebot.FlexBoxLayoutTypeVertical,
+			Contents: footer,
+		}
+	}
+	return bubble
+}
+
+func invalidReplyToken(err error) bool {
+	var apiErr *linebot.APIError
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest && apiErr.Response != nil &&
+		strings.Contains(strings.ToLower(apiErr.Response.Message), "reply token")
+}
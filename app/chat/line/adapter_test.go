@@ -0,0 +1,195 @@
+package line
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/line/line-bot-sdk-go/linebot"
+)
+
+type fakeLineServer struct {
+	*httptest.Server
+	requestsMux sync.Mutex
+	requests    []string
+}
+
+func (this *fakeLineServer) Requests() []string {
+	this.requestsMux.Lock()
+	defer this.requestsMux.Unlock()
+	requests := this.requests
+	this.requests = nil
+	return requests
+}
+
+// newFakeLineServer records requests as "<path> <body>". Replies with the
+// token "expired" fail as LINE fails them once a reply token has expired.
+func newFakeLineServer(t *testing.T) (*fakeLineServer, *linebot.Client) {
+	server := &fakeLineServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		server.requestsMux.Lock()
+		server.requests = append(server.requests, r.URL.Path+" "+string(body))
+		server.requestsMux.Unlock()
+		if r.URL.Path == "/v2/bot/message/reply" && strings.Contains(string(body), `"replyToken":"expired"`) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"Invalid reply token"}`))
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/v2/bot/profile/") {
+			w.Write([]byte(`{"userId":"user1","displayName":"Choo","language":"th"}`))
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+	client, err := linebot.New("secret", "token", linebot.WithEndpointBase(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, client
+}
+
+func TestEvent(t *testing.T) {
+	cases := []struct {
+		in   linebot.Event
+		want chat.Event
+	}{
+		{
+			linebot.Event{
+				Type:            linebot.EventTypeMessage,
+				WebhookEventID:  "event1",
+				ReplyToken:      "token",
+				DeliveryContext: linebot.DeliveryContext{IsRedelivery: true},
+				Source:          &linebot.EventSource{Type: linebot.EventSourceTypeGroup, GroupID: "group1", UserID: "user1"},
+				Message:         &linebot.TextMessage{Text: "@Choo line", Mention: &linebot.Mention{Mentionees: []*linebot.Mentionee{{Index: 0, Length: 5, UserID: "bot"}}}},
+			},
+			chat.Event{
+				Type:        chat.EventTypeMessage,
+				ID:          "event1",
+				ReplyToken:  "token",
+				Redelivery:  true,
+				Source:      chat.Source{Type: chat.SourceTypeGroup, ChatID: "group1", UserID: "user1"},
+				MessageType: chat.MessageTypeText,
+				Text:        "@Choo line",
+				Mentions:    []chat.Mention{{UserID: "bot", Index: 0, Length: 5}},
+			},
+		},
+		{
+			linebot.Event{Type: linebot.EventTypeMessage, Source: &linebot.EventSource{Type: linebot.EventSourceTypeRoom, RoomID: "room1"}, Message: &linebot.StickerMessage{}},
+			chat.Event{Type: chat.EventTypeMessage, Source: chat.Source{Type: chat.SourceTypeRoom, ChatID: "room1"}, MessageType: chat.MessageTypeSticker},
+		},
+		{
+			linebot.Event{Type: linebot.EventTypePostback, Source: &linebot.EventSource{Type: linebot.EventSourceTypeUser, UserID: "user1"}, Postback: &linebot.Postback{Data: "1|syn|line"}},
+			chat.Event{Type: chat.EventTypePostback, Source: chat.Source{Type: chat.SourceTypeUser, UserID: "user1"}, Postback: "1|syn|line"},
+		},
+		{
+			linebot.Event{Type: linebot.EventTypeBeacon},
+			chat.Event{Type: chat.EventType("beacon")},
+		},
+	}
+	for _, c := range cases {
+		got := Event(&c.in)
+		if got.Type != c.want.Type || got.ID != c.want.ID || got.ReplyToken != c.want.ReplyToken || got.Redelivery != c.want.Redelivery || got.Source != c.want.Source ||
+			got.MessageType != c.want.MessageType || got.Text != c.want.Text || got.Postback != c.want.Postback || len(got.Mentions) != len(c.want.Mentions) {
+			t.Errorf("Event(%v) == %+v, want %+v", c.in.Type, got, c.want)
+			continue
+		}
+		for i := range c.want.Mentions {
+			if got.Mentions[i] != c.want.Mentions[i] {
+				t.Errorf("Event(%v) mentions == %+v, want %+v", c.in.Type, got.Mentions, c.want.Mentions)
+			}
+		}
+	}
+}
+
+func TestAdapterSend(t *testing.T) {
+	server, client := newFakeLineServer(t)
+	defer server.Close()
+	adapter := NewAdapter(client)
+	event := &chat.Event{ReplyToken: "token", Source: chat.Source{Type: chat.SourceTypeUser, UserID: "user1"}}
+
+	message := chat.NewTextMessage("line").WithQuickReplies(chat.NewPostbackButton("Synonyms", "1|syn|line"), chat.NewMessageButton("dot", "?dot"))
+	if err := adapter.Reply(event, message); err != nil {
+		t.Errorf("Adapter.Reply() == %v, want %v", err, nil)
+	}
+	requests := server.Requests()
+	for _, want := range []string{`"replyToken":"token"`, `"type":"text","text":"line"`, `"type":"postback","label":"Synonyms","data":"1|syn|line","displayText":"Synonyms"`, `"type":"message","label":"dot","text":"?dot"`} {
+		if len(requests) != 1 || !strings.HasPrefix(requests[0], "/v2/bot/message/reply") || !strings.Contains(requests[0], want) {
+			t.Errorf("Adapter.Reply() sent %q, want %q", requests, want)
+		}
+	}
+
+	card := chat.Message{Text: "Your settings:\nSenses: 1", Card: &chat.Card{
+		Title:   "Your settings",
+		Rows:    []chat.CardRow{{Label: "Senses", Button: chat.NewPostbackButton("1", "1|set|senses")}},
+		Note:    "Tap a value to change it.",
+		Buttons: []chat.Button{chat.NewPostbackButton("Reset to defaults", "1|set_reset|")},
+	}}
+	if err := adapter.Push("user1", card); err != nil {
+		t.Errorf("Adapter.Push() == %v, want %v", err, nil)
+	}
+	requests = server.Requests()
+	for _, want := range []string{`"to":"user1"`, `"type":"flex","altText":"Your settings:\nSenses: 1"`, `"text":"Senses"`, `"label":"1","data":"1|set|senses"`, `"text":"Tap a value to change it."`, `"data":"1|set_reset|"`} {
+		if len(requests) != 1 || !strings.HasPrefix(requests[0], "/v2/bot/message/push") || !strings.Contains(requests[0], want) {
+			t.Errorf("Adapter.Push() of a card sent %q, want %q", requests, want)
+		}
+	}
+
+	adapter.Multicast([]string{"user1", "user2"}, chat.NewTextMessage("hello"))
+	adapter.Broadcast(chat.NewTextMessage("hello"))
+	requests = server.Requests()
+	if len(requests) != 2 || !strings.Contains(requests[0], `"to":["user1","user2"]`) || !strings.HasPrefix(requests[1], "/v2/bot/message/broadcast") {
+		t.Errorf("Adapter.Multicast() and Adapter.Broadcast() sent %q", requests)
+	}
+
+	profile, err := adapter.Profile("user1")
+	if err != nil || profile.DisplayName != "Choo" || profile.Language != "th" {
+		t.Errorf("Adapter.Profile(%q) == %+v, %v", "user1", profile, err)
+	}
+}
+
+func TestAdapterRedelivery(t *testing.T) {
+	server, client := newFakeLineServer(t)
+	defer server.Close()
+	adapter := NewAdapter(client)
+	// Reply tokens of redelivered events may have expired
+	event := func(redelivery bool) *chat.Event {
+		return &chat.Event{
+			ReplyToken: "expired",
+			Redelivery: redelivery,
+			Source:     chat.Source{Type: chat.SourceTypeGroup, ChatID: "group1", UserID: "user1"},
+		}
+	}
+	sent := func() []string {
+		paths := []string{}
+		for _, request := range server.Requests() {
+			paths = append(paths, strings.Split(request, " ")[0])
+		}
+		return paths
+	}
+
+	if err := adapter.Reply(event(true), chat.NewTextMessage("line")); err == nil {
+		t.Errorf("Adapter.Reply() of a redelivery with an expired token == %v, want an error", err)
+	}
+	if got := sent(); len(got) != 1 {
+		t.Errorf("Adapter.Reply() of a redelivery with an expired token sent %q, want the reply only", got)
+	}
+
+	adapter.PushOnRedelivery = true
+	if err := adapter.Reply(event(true), chat.NewTextMessage("line")); err != nil {
+		t.Errorf("Adapter.Reply() of a redelivery with push on == %v, want %v", err, nil)
+	}
+	requests := server.Requests()
+	if len(requests) != 2 || !strings.HasPrefix(requests[1], "/v2/bot/message/push") || !strings.Contains(requests[1], `"to":"group1"`) {
+		t.Errorf("Adapter.Reply() of a redelivery with push on sent %q, want a reply and then a push to the group", requests)
+	}
+	// Only redeliveries are pushed
+	adapter.Reply(event(false), chat.NewTextMessage("line"))
+	if got := sent(); len(got) != 1 {
+		t.Errorf("Adapter.Reply() of a first delivery with an expired token sent %q, want the reply only", got)
+	}
+}
@@ -0,0 +1,50 @@
+package chat
+
+// Message is a message sent by the bot. Platforms that can't show a card
+// send the text instead, so the text should stand on its own. The title
+// heads the text where the platform can format it and is left out elsewhere.
+type Message struct {
+	Title        string   `json:"title,omitempty"`
+	Text         string   `json:"text"`
+	QuickReplies []Button `json:"quick_replies,omitempty"`
+	Card         *Card    `json:"card,omitempty"`
+}
+
+// Button sends Data back in a postback event when it is tapped, or when it
+// has no data, sends Text as a message from the user.
+type Button struct {
+	Label string `json:"label"`
+	Data  string `json:"data,omitempty"`
+	Text  string `json:"text,omitempty"`
+}
+
+// Card lays a message out as a title, rows of a label and a button, and a
+// note and buttons at the bottom.
+type Card struct {
+	Title   string    `json:"title"`
+	Rows    []CardRow `json:"rows"`
+	Note    string    `json:"note,omitempty"`
+	Buttons []Button  `json:"buttons,omitempty"`
+}
+
+type CardRow struct {
+	Label  string `json:"label"`
+	Button Button `json:"button"`
+}
+
+func NewTextMessage(text string) Message {
+	return Message{Text: text}
+}
+
+func (this Message) WithQuickReplies(buttons ...Button) Message {
+	this.QuickReplies = buttons
+	return this
+}
+
+func NewPostbackButton(label string, data string) Button {
+	return Button{Label: label, Data: data}
+}
+
+func NewMessageButton(label string, text string) Button {
+	return Button{Label: label, Text: text}
+}
@@ -0,0 +1,106 @@
+package chat
+
+import (
+	"strings"
+)
+
+// Mux sends through the adapter of each ID's platform, so that the bot can
+// run on several platforms at once. IDs of a platform start with the prefix
+// it is handled under, such as "tg:", and IDs without a known prefix go to
+// the default adapter.
+type Mux struct {
+	Default  Adapter
+	prefixes []string
+	adapters map[string]Adapter
+}
+
+func NewMux(defaultAdapter Adapter) *Mux {
+	return &Mux{
+		Default:  defaultAdapter,
+		adapters: map[string]Adapter{},
+	}
+}
+
+// Handle sends IDs with the prefix through the adapter. All platforms must be
+// handled before the Mux is used, it doesn't lock.
+func (this *Mux) Handle(prefix string, adapter Adapter) {
+	if _, ok := this.adapters[prefix]; !ok {
+		this.prefixes = append(this.prefixes, prefix)
+	}
+	this.adapters[prefix] = adapter
+}
+
+// Adapter returns the adapter of the platform an ID belongs to.
+func (this *Mux) Adapter(id string) Adapter {
+	for _, prefix := range this.prefixes {
+		if strings.HasPrefix(id, prefix) {
+			return this.adapters[prefix]
+		}
+	}
+	return this.Default
+}
+
+func (this *Mux) Reply(event *Event, messages ...Message) error {
+	id := event.Source.ChatID
+	if id == "" {
+		id = event.Source.UserID
+	}
+	return this.Adapter(id).Reply(event, messages...)
+}
+
+func (this *Mux) Push(to string, messages ...Message) error {
+	return this.Adapter(to).Push(to, messages...)
+}
+
+// Multicast sends to the users of each platform in turn and returns the
+// first error after trying them all.
+func (this *Mux) Multicast(to []string, messages ...Message) error {
+	adapters := []Adapter{}
+	recipients := map[Adapter][]string{}
+	for _, id := range to {
+		adapter := this.Adapter(id)
+		if _, ok := recipients[adapter]; !ok {
+			adapters = append(adapters, adapter)
+		}
+		recipients[adapter] = append(recipients[adapter], id)
+	}
+	var result error
+	for _, adapter := range adapters {
+		if err := adapter.Multicast(recipients[adapter], messages...); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+// Broadcast sends to every platform that can broadcast. It fails with
+// ErrNotSupported only when none of them can.
+func (this *Mux) Broadcast(messages ...Message) error {
+	var result error = ErrNotSupported
+	for _, adapter := range append([]Adapter{this.Default}, this.all()...) {
+		err := adapter.Broadcast(messages...)
+		if err == ErrNotSupported {
+			continue
+		}
+		if result == ErrNotSupported || (result == nil && err != nil) {
+			result = err
+		}
+	}
+	return result
+}
+
+func (this *Mux) Profile(userID string) (Profile, error) {
+	return this.Adapter(userID).Profile(userID)
+}
+
+func (this *Mux) IsGroupAdmin(chatID string, userID string) (bool, error) {
+	return this.Adapter(chatID).IsGroupAdmin(chatID, userID)
+}
+
+func (this *Mux) all() []Adapter {
+	adapters := []Adapter{}
+	for _, prefix := range this.prefixes {
+		adapters = append(adapters, this.adapters[prefix])
+	}
+	return adapters
+}
@@ -0,0 +1,77 @@
+package chat
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type recordingAdapter struct {
+	name      string
+	broadcast error
+	sent      *[]string
+}
+
+func (this recordingAdapter) record(kind string, to ...string) error {
+	*this.sent = append(*this.sent, this.name+" "+kind+" "+strings.Join(to, ","))
+	return nil
+}
+
+func (this recordingAdapter) Reply(event *Event, messages ...Message) error {
+	return this.record("reply", event.Source.UserID)
+}
+
+func (this recordingAdapter) Push(to string, messages ...Message) error {
+	return this.record("push", to)
+}
+
+func (this recordingAdapter) Multicast(to []string, messages ...Message) error {
+	return this.record("multicast", to...)
+}
+
+func (this recordingAdapter) Broadcast(messages ...Message) error {
+	if this.broadcast != nil {
+		return this.broadcast
+	}
+	return this.record("broadcast")
+}
+
+func (this recordingAdapter) Profile(userID string) (Profile, error) {
+	return Profile{DisplayName: this.name}, this.record("profile", userID)
+}
+
+func (this recordingAdapter) IsGroupAdmin(chatID string, userID string) (bool, error) {
+	return false, this.record("admin", chatID, userID)
+}
+
+func TestMux(t *testing.T) {
+	sent := []string{}
+	mux := NewMux(recordingAdapter{name: "line", sent: &sent})
+	mux.Handle("tg:", recordingAdapter{name: "telegram", broadcast: ErrNotSupported, sent: &sent})
+
+	mux.Reply(&Event{Source: Source{Type: SourceTypeUser, UserID: "user1"}})
+	mux.Reply(&Event{Source: Source{Type: SourceTypeGroup, ChatID: "tg:-5", UserID: "tg:1"}})
+	mux.Push("tg:1")
+	mux.Multicast([]string{"user1", "tg:1", "user2"})
+	profile, _ := mux.Profile("tg:1")
+	mux.IsGroupAdmin("tg:-5", "tg:1")
+	want := []string{"line reply user1", "telegram reply tg:1", "telegram push tg:1", "line multicast user1,user2", "telegram multicast tg:1", "telegram profile tg:1", "telegram admin tg:-5,tg:1"}
+	if strings.Join(sent, "|") != strings.Join(want, "|") || profile.DisplayName != "telegram" {
+		t.Errorf("Mux sent %q, want %q", sent, want)
+	}
+
+	// Platforms that can't broadcast are skipped
+	sent = sent[:0]
+	if err := mux.Broadcast(); err != nil || len(sent) != 1 || sent[0] != "line broadcast " {
+		t.Errorf("Mux.Broadcast() == %v and sent %q, want a broadcast on LINE", err, sent)
+	}
+	failed := errors.New("failed")
+	mux.Default = recordingAdapter{name: "line", broadcast: failed, sent: &sent}
+	if err := mux.Broadcast(); err != failed {
+		t.Errorf("Mux.Broadcast() == %v, want %v", err, failed)
+	}
+	mux.Default = recordingAdapter{name: "line", broadcast: ErrNotSupported, sent: &sent}
+	if err := mux.Broadcast(); err != ErrNotSupported {
+		t.Errorf("Mux.Broadcast() without a platform that can == %v, want %v", err, ErrNotSupported)
+	}
+}
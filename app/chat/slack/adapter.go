@@ -0,0 +1,404 @@
+package slack
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/scheduler"
+)
+
+// IDPrefix starts the IDs of Slack users and channels, which tells them
+// apart from the IDs of other platforms.
+const IDPrefix = "slack:"
+
+const (
+	// Slack refuses requests signed longer ago, as they may be replayed
+	maxRequestAge = 5 * time.Minute
+	// Slack refuses longer texts in blocks
+	maxSectionLength = 3000
+	maxHeaderLength  = 150
+	maxButtonLength  = 75
+	maxValueLength   = 2000
+)
+
+var ErrInvalidSignature = errors.New("slack: invalid signature")
+
+// mentionPattern matches user mentions in the text of events, which Slack
+// writes as <@U123> or <@U123|name>.
+var mentionPattern = regexp.MustCompile(`<@([A-Z0-9]+)(\|[^>]*)?>`)
+
+// Adapter runs the bot on Slack. Slash commands, taps on buttons and events
+// all come to one request URL. Replies to slash commands and taps are only
+// shown to the user, while replies to mentions are posted in the channel.
+type Adapter struct {
+	Token         string
+	SigningSecret string
+	Endpoint      string
+	Client        *http.Client
+	Clock         scheduler.Clock
+}
+
+func NewAdapter(token string, signingSecret string) *Adapter {
+	return &Adapter{
+		Token:         token,
+		SigningSecret: signingSecret,
+		Endpoint:      "https://slack.com/api",
+		Client:        &http.Client{Timeout: 10 * time.Second},
+		Clock:         scheduler.RealClock{},
+	}
+}
+
+// ID turns the ID of a Slack user or channel into the bot's ID for them.
+func ID(id string) string {
+	return IDPrefix + id
+}
+
+// AuthTest returns the bot's own user ID.
+func (this *Adapter) AuthTest() (string, error) {
+	var result authTest
+	err := this.call("auth.test", url.Values{}, &result)
+	return result.UserID, err
+}
+
+// URLVerification answers the challenge Slack sends when the request URL is
+// set, and passes other requests on.
+func (this *Adapter) URLVerification(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := this.readBody(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var callback EventCallback
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") && json.Unmarshal(body, &callback) == nil && callback.Type == "url_verification" {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(callback.Challenge))
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ParseRequest checks the signature of a request and returns its events. It
+// fails with ErrInvalidSignature for a bad or old signature.
+func (this *Adapter) ParseRequest(r *http.Request) ([]*chat.Event, error) {
+	body, err := this.readBody(r)
+	if err != nil {
+		return nil, err
+	}
+	events := []*chat.Event{}
+	var event *chat.Event
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var callback EventCallback
+		if err := json.Unmarshal(body, &callback); err != nil {
+			return nil, err
+		}
+		event = Event(&callback)
+		if event != nil {
+			// Slack sends events again when it got no response in time
+			event.Redelivery = r.Header.Get("X-Slack-Retry-Num") != ""
+		}
+	} else {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		if payload := form.Get("payload"); payload != "" {
+			var interaction Interaction
+			if err := json.Unmarshal([]byte(payload), &interaction); err != nil {
+				return nil, err
+			}
+			event = InteractionEvent(&interaction)
+		} else {
+			event = SlashCommandEvent(form)
+		}
+	}
+	if event != nil {
+		if event.Timestamp.IsZero() {
+			event.Timestamp = this.Clock.Now()
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// readBody reads the body of a request and checks its signature.
+func (this *Adapter) readBody(r *http.Request) ([]byte, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	timestamp := r.Header.Get("X-Slack-Request-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	if age := this.Clock.Now().Sub(time.Unix(seconds, 0)); age > maxRequestAge || age < -maxRequestAge {
+		return nil, ErrInvalidSignature
+	}
+	mac := hmac.New(sha256.New, []byte(this.SigningSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	signature := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(signature), []byte(r.Header.Get("X-Slack-Signature"))) {
+		return nil, ErrInvalidSignature
+	}
+	return body, nil
+}
+
+// SlashCommandEvent turns a slash command into a message of the command, so
+// that "/define line" runs the bot's define command.
+func SlashCommandEvent(form url.Values) *chat.Event {
+	return &chat.Event{
+		Type:        chat.EventTypeMessage,
+		Source:      source(form.Get("channel_id"), form.Get("user_id")),
+		ReplyToken:  url.Values{"response_url": {form.Get("response_url")}}.Encode(),
+		MessageType: chat.MessageTypeText,
+		Text:        strings.TrimSpace(form.Get("command") + " " + form.Get("text")),
+	}
+}
+
+// InteractionEvent turns a tap on a button into a postback, or for buttons
+// that send messages, into a message.
+func InteractionEvent(interaction *Interaction) *chat.Event {
+	if interaction.Type != "block_actions" || len(interaction.Actions) == 0 {
+		return nil
+	}
+	action := interaction.Actions[0]
+	event := &chat.Event{
+		Source:     source(interaction.Channel.ID, interaction.User.ID),
+		ReplyToken: url.Values{"response_url": {interaction.ResponseURL}}.Encode(),
+	}
+	if strings.HasPrefix(action.ActionID, "message:") {
+		event.Type, event.MessageType, event.Text = chat.EventTypeMessage, chat.MessageTypeText, action.Value
+	} else {
+		event.Type, event.Postback = chat.EventTypePostback, action.Value
+	}
+	return event
+}
+
+// Event translates an event of the Events API, or returns nil for events the
+// bot ignores.
+func Event(callback *EventCallback) *chat.Event {
+	if callback.Type != "event_callback" || callback.Event == nil || callback.Event.Type != "app_mention" {
+		return nil
+	}
+	message := callback.Event
+	text, mentions := parseText(message.Text)
+	event := &chat.Event{
+		Type:        chat.EventTypeMessage,
+		ID:          ID(callback.EventID),
+		Source:      source(message.Channel, message.User),
+		ReplyToken:  url.Values{"channel": {message.Channel}, "thread_ts": {message.ThreadTS}}.Encode(),
+		MessageType: chat.MessageTypeText,
+		Text:        text,
+		Mentions:    mentions,
+	}
+	if callback.EventTime != 0 {
+		event.Timestamp = time.Unix(callback.EventTime, 0)
+	}
+	return event
+}
+
+// source tells direct messages, whose channel IDs start with D, from
+// channels.
+func source(channelID string, userID string) chat.Source {
+	if channelID == "" || strings.HasPrefix(channelID, "D") {
+		return chat.Source{Type: chat.SourceTypeUser, UserID: ID(userID)}
+	}
+	return chat.Source{Type: chat.SourceTypeGroup, ChatID: ID(channelID), UserID: ID(userID)}
+}
+
+// parseText unescapes the text of an event and returns it with its user
+// mentions, counted in UTF-16 code units like on other platforms.
+func parseText(text string) (string, []chat.Mention) {
+	result := []uint16{}
+	mentions := []chat.Mention{}
+	last := 0
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		result = append(result, utf16.Encode([]rune(unescape(text[last:match[0]])))...)
+		mention := utf16.Encode([]rune(text[match[0]:match[1]]))
+		mentions = append(mentions, chat.Mention{UserID: ID(text[match[2]:match[3]]), Index: len(result), Length: len(mention)})
+		result = append(result, mention...)
+		last = match[1]
+	}
+	result = append(result, utf16.Encode([]rune(unescape(text[last:])))...)
+	return string(utf16.Decode(result)), mentions
+}
+
+func unescape(text string) string {
+	return strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&").Replace(text)
+}
+
+func escape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// Reply sends all messages in one, as a response URL takes few responses.
+func (this *Adapter) Reply(event *chat.Event, messages ...chat.Message) error {
+	token, _ := url.ParseQuery(event.ReplyToken)
+	text, blocks := render(messages)
+	if responseURL := token.Get("response_url"); responseURL != "" {
+		return this.respond(responseURL, response{ResponseType: "ephemeral", Text: text, Blocks: blocks})
+	}
+	return this.call("chat.postMessage", postMessage{Channel: token.Get("channel"), Text: text, Blocks: blocks, ThreadTS: token.Get("thread_ts")}, nil)
+}
+
+// Push sends to a channel, or to a user as a direct message.
+func (this *Adapter) Push(to string, messages ...chat.Message) error {
+	text, blocks := render(messages)
+	return this.call("chat.postMessage", postMessage{Channel: strings.TrimPrefix(to, IDPrefix), Text: text, Blocks: blocks}, nil)
+}
+
+// Multicast pushes to each user in turn, as Slack has no multicast, and
+// returns the first error after trying them all.
+func (this *Adapter) Multicast(to []string, messages ...chat.Message) error {
+	var result error
+	for _, id := range to {
+		if err := this.Push(id, messages...); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+func (this *Adapter) Broadcast(messages ...chat.Message) error {
+	return chat.ErrNotSupported
+}
+
+func (this *Adapter) Profile(userID string) (chat.Profile, error) {
+	var info userInfo
+	params := url.Values{"user": {strings.TrimPrefix(userID, IDPrefix)}, "include_locale": {"true"}}
+	if err := this.call("users.info", params, &info); err != nil {
+		return chat.Profile{}, err
+	}
+	profile := chat.Profile{
+		DisplayName: info.User.Profile.DisplayName,
+		PictureURL:  info.User.Profile.Image72,
+		// Locales are like "en-US"
+		Language: strings.Split(info.User.Locale, "-")[0],
+	}
+	if profile.DisplayName == "" {
+		profile.DisplayName = info.User.RealName
+	}
+	return profile, nil
+}
+
+// IsGroupAdmin reports whether a user is an admin or owner of the workspace,
+// as Slack channels have no admins of their own.
+func (this *Adapter) IsGroupAdmin(chatID string, userID string) (bool, error) {
+	var info userInfo
+	if err := this.call("users.info", url.Values{"user": {strings.TrimPrefix(userID, IDPrefix)}}, &info); err != nil {
+		return false, err
+	}
+	return info.User.IsAdmin || info.User.IsOwner, nil
+}
+
+// render lays messages out in Block Kit, with their texts joined as the
+// text of notifications.
+func render(messages []chat.Message) (string, []Block) {
+	texts := []string{}
+	blocks := []Block{}
+	for _, message := range messages {
+		if message.Title != "" {
+			texts = append(texts, message.Title)
+		}
+		texts = append(texts, message.Text)
+		if message.Card != nil {
+			blocks = append(blocks, card(message.Card)...)
+		} else {
+			if message.Title != "" {
+				blocks = append(blocks, Block{Type: "header", Text: plainText(message.Title, maxHeaderLength)})
+			}
+			for _, part := range split(message.Text, maxSectionLength) {
+				// Slack refuses empty sections
+				if strings.TrimSpace(part) == "" {
+					continue
+				}
+				blocks = append(blocks, Block{Type: "section", Text: &Text{Type: "mrkdwn", Text: escape(part)}})
+			}
+		}
+		if actions := buttons(message.QuickReplies); len(actions) > 0 {
+			blocks = append(blocks, Block{Type: "actions", Elements: actions})
+		}
+	}
+	return strings.Join(texts, "\n"), blocks
+}
+
+// card shows each row as its label with its button beside it.
+func card(card *chat.Card) []Block {
+	blocks := []Block{{Type: "header", Text: plainText(card.Title, maxHeaderLength)}}
+	for _, row := range card.Rows {
+		block := Block{Type: "section", Text: &Text{Type: "mrkdwn", Text: "*" + escape(row.Label) + "*"}}
+		if accessory := buttons([]chat.Button{row.Button}); len(accessory) > 0 {
+			button := accessory[0].(Button)
+			block.Accessory = &button
+		}
+		blocks = append(blocks, block)
+	}
+	if card.Note != "" {
+		blocks = append(blocks, Block{Type: "context", Elements: []interface{}{Text{Type: "mrkdwn", Text: escape(card.Note)}}})
+	}
+	if actions := buttons(card.Buttons); len(actions) > 0 {
+		blocks = append(blocks, Block{Type: "actions", Elements: actions})
+	}
+	return blocks
+}
+
+// buttons leaves out buttons whose value Slack would refuse. The kind of a
+// button is kept in its action ID, which must be unique in its block.
+func buttons(buttons []chat.Button) []interface{} {
+	elements := []interface{}{}
+	for i, button := range buttons {
+		kind, value := "postback:", button.Data
+		if button.Data == "" {
+			kind, value = "message:", button.Text
+		}
+		if value == "" || len(value) > maxValueLength {
+			continue
+		}
+		elements = append(elements, Button{Type: "button", Text: *plainText(button.Label, maxButtonLength), ActionID: kind + strconv.Itoa(i), Value: value})
+	}
+	return elements
+}
+
+func plainText(text string, limit int) *Text {
+	if runes := []rune(text); len(runes) > limit {
+		text = string(runes[:limit-1]) + "…"
+	}
+	return &Text{Type: "plain_text", Text: text}
+}
+
+// split cuts text into parts of at most limit characters, at line breaks
+// where it can.
+func split(text string, limit int) []string {
+	parts := []string{}
+	runes := []rune(text)
+	for len(runes) > limit {
+		end := limit
+		for i := end - 1; i > 0; i-- {
+			if runes[i] == '\n' {
+				end = i + 1
+				break
+			}
+		}
+		parts = append(parts, strings.TrimSuffix(string(runes[:end]), "\n"))
+		runes = runes[end:]
+	}
+	return append(parts, string(runes))
+}
@@ -0,0 +1,324 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/scheduler"
+)
+
+type fakeSlackServer struct {
+	*httptest.Server
+	requestsMux sync.Mutex
+	requests    []string
+}
+
+func (this *fakeSlackServer) Requests() []string {
+	this.requestsMux.Lock()
+	defer this.requestsMux.Unlock()
+	requests := this.requests
+	this.requests = nil
+	return requests
+}
+
+// newFakeSlackServer serves the Web API under /api and response URLs under
+// /response, and records requests as "<path> <body>" with HTML unescaped.
+// Messages to the channel C403 fail as they do when the bot isn't in it.
+func newFakeSlackServer(t *testing.T) (*fakeSlackServer, *Adapter) {
+	server := &fakeSlackServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		unescaped := strings.NewReplacer(`\u003c`, "<", `\u003e`, ">", `\u0026`, "&").Replace(string(body))
+		server.requestsMux.Lock()
+		server.requests = append(server.requests, r.URL.Path+" "+unescaped)
+		server.requestsMux.Unlock()
+		if r.Header.Get("Authorization") != "Bearer token" && strings.HasPrefix(r.URL.Path, "/api/") {
+			w.Write([]byte(`{"ok":false,"error":"invalid_auth"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/api/chat.postMessage":
+			if strings.Contains(string(body), `"channel":"C403"`) {
+				w.Write([]byte(`{"ok":false,"error":"not_in_channel"}`))
+				return
+			}
+			w.Write([]byte(`{"ok":true}`))
+		case "/api/auth.test":
+			w.Write([]byte(`{"ok":true,"user_id":"U0BOT"}`))
+		case "/api/users.info":
+			if strings.Contains(string(body), "user=U0ADMIN") {
+				w.Write([]byte(`{"ok":true,"user":{"real_name":"Admin","is_admin":true}}`))
+				return
+			}
+			w.Write([]byte(`{"ok":true,"user":{"real_name":"Choo Choo","locale":"th-TH","profile":{"display_name":"","image_72":"https://example.com/choo.png"}}}`))
+		case "/response":
+			w.Write([]byte("ok"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	adapter := NewAdapter("token", "secret")
+	adapter.Endpoint = server.URL + "/api"
+	adapter.Clock = scheduler.NewFakeClock(time.Unix(1000000, 0))
+	return server, adapter
+}
+
+// signedRequest signs a request as Slack does, at the given time.
+func signedRequest(body string, contentType string, at time.Time, secret string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/slack", strings.NewReader(body))
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+	r.Header.Set("Content-Type", contentType)
+	r.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return r
+}
+
+func TestAdapterParseRequest(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	adapter := NewAdapter("token", "secret")
+	adapter.Clock = scheduler.NewFakeClock(now)
+	form := "application/x-www-form-urlencoded"
+	command := url.Values{"command": {"/define"}, "text": {"line"}, "channel_id": {"C1"}, "user_id": {"U1"}, "response_url": {"https://hooks.slack.com/1"}}.Encode()
+
+	for _, r := range []*http.Request{
+		signedRequest(command, form, now, "wrong"),
+		signedRequest(command, form, now.Add(-maxRequestAge-time.Second), "secret"),
+	} {
+		if _, err := adapter.ParseRequest(r); err != ErrInvalidSignature {
+			t.Errorf("Adapter.ParseRequest() of a bad or old request == %v, want %v", err, ErrInvalidSignature)
+		}
+	}
+
+	cases := []struct {
+		body        string
+		contentType string
+		retry       bool
+		want        *chat.Event
+	}{
+		{
+			command,
+			form,
+			false,
+			&chat.Event{Type: chat.EventTypeMessage, ReplyToken: "response_url=https%3A%2F%2Fhooks.slack.com%2F1", Source: chat.Source{Type: chat.SourceTypeGroup, ChatID: "slack:C1", UserID: "slack:U1"}, MessageType: chat.MessageTypeText, Text: "/define line"},
+		},
+		{
+			"payload=" + url.QueryEscape(`{"type":"block_actions","user":{"id":"U1"},"channel":{"id":"D1"},"response_url":"https://hooks.slack.com/2","actions":[{"action_id":"postback:0","value":"1|syn|line"}]}`),
+			form,
+			false,
+			&chat.Event{Type: chat.EventTypePostback, ReplyToken: "response_url=https%3A%2F%2Fhooks.slack.com%2F2", Source: chat.Source{Type: chat.SourceTypeUser, UserID: "slack:U1"}, Postback: "1|syn|line"},
+		},
+		{
+			"payload=" + url.QueryEscape(`{"type":"block_actions","user":{"id":"U1"},"channel":{"id":"D1"},"response_url":"https://hooks.slack.com/3","actions":[{"action_id":"message:0","value":"/def dot"}]}`),
+			form,
+			false,
+			&chat.Event{Type: chat.EventTypeMessage, ReplyToken: "response_url=https%3A%2F%2Fhooks.slack.com%2F3", Source: chat.Source{Type: chat.SourceTypeUser, UserID: "slack:U1"}, MessageType: chat.MessageTypeText, Text: "/def dot"},
+		},
+		{
+			`{"type":"event_callback","event_id":"Ev1","event":{"type":"app_mention","user":"U1","text":"<@U0BOT> fish &amp; chips","ts":"1.1","channel":"C1"}}`,
+			"application/json",
+			true,
+			&chat.Event{Type: chat.EventTypeMessage, ID: "slack:Ev1", Redelivery: true, ReplyToken: "channel=C1&thread_ts=", Source: chat.Source{Type: chat.SourceTypeGroup, ChatID: "slack:C1", UserID: "slack:U1"}, MessageType: chat.MessageTypeText, Text: "<@U0BOT> fish & chips"},
+		},
+		{
+			`{"type":"event_callback","event_id":"Ev2","event":{"type":"reaction_added","user":"U1"}}`,
+			"application/json",
+			false,
+			nil,
+		},
+	}
+	for _, c := range cases {
+		r := signedRequest(c.body, c.contentType, now, "secret")
+		if c.retry {
+			r.Header.Set("X-Slack-Retry-Num", "1")
+		}
+		events, err := adapter.ParseRequest(r)
+		if err != nil || (c.want == nil) != (len(events) == 0) || len(events) > 1 {
+			t.Errorf("Adapter.ParseRequest(%q) == %+v, %v, want %+v", c.body, events, err, c.want)
+			continue
+		}
+		if c.want == nil {
+			continue
+		}
+		got := events[0]
+		if got.Type != c.want.Type || got.ID != c.want.ID || got.Redelivery != c.want.Redelivery || got.ReplyToken != c.want.ReplyToken || got.Source != c.want.Source ||
+			got.MessageType != c.want.MessageType || got.Text != c.want.Text || got.Postback != c.want.Postback || got.Timestamp.IsZero() {
+			t.Errorf("Adapter.ParseRequest(%q) == %+v, want %+v", c.body, got, c.want)
+		}
+	}
+}
+
+func TestParseText(t *testing.T) {
+	cases := []struct {
+		in       string
+		want     string
+		mentions []chat.Mention
+	}{
+		{"line", "line", []chat.Mention{}},
+		{"<@U0BOT> line", "<@U0BOT> line", []chat.Mention{{UserID: "slack:U0BOT", Index: 0, Length: 8}}},
+		// Positions count UTF-16 code units of the unescaped text
+		{"a &lt; 😀 <@U1|choo> b", "a < 😀 <@U1|choo> b", []chat.Mention{{UserID: "slack:U1", Index: 7, Length: 10}}},
+	}
+	for _, c := range cases {
+		got, mentions := parseText(c.in)
+		if got != c.want || len(mentions) != len(c.mentions) {
+			t.Errorf("parseText(%q) == %q, %+v, want %q, %+v", c.in, got, mentions, c.want, c.mentions)
+			continue
+		}
+		for i := range mentions {
+			if mentions[i] != c.mentions[i] {
+				t.Errorf("parseText(%q) mentions == %+v, want %+v", c.in, mentions, c.mentions)
+			}
+		}
+	}
+}
+
+func TestAdapterURLVerification(t *testing.T) {
+	adapter := NewAdapter("token", "secret")
+	adapter.Clock = scheduler.NewFakeClock(time.Unix(1000000, 0))
+	passed := false
+	handler := adapter.URLVerification(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		passed = string(body) == "text=line"
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, signedRequest(`{"type":"url_verification","challenge":"abc"}`, "application/json", time.Unix(1000000, 0), "secret"))
+	if w.Body.String() != "abc" || passed {
+		t.Errorf("URLVerification() answered %q, want %q", w.Body.String(), "abc")
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, signedRequest(`{"type":"url_verification","challenge":"abc"}`, "application/json", time.Unix(1000000, 0), "wrong"))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("URLVerification() of a bad signature answered %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), signedRequest("text=line", "application/x-www-form-urlencoded", time.Unix(1000000, 0), "secret"))
+	if !passed {
+		t.Errorf("URLVerification() didn't pass a slash command on with its body")
+	}
+}
+
+func TestAdapterSend(t *testing.T) {
+	server, adapter := newFakeSlackServer(t)
+	defer server.Close()
+
+	botUserID, err := adapter.AuthTest()
+	if err != nil || botUserID != "U0BOT" {
+		t.Errorf("Adapter.AuthTest() == %q, %v, want %q", botUserID, err, "U0BOT")
+	}
+	server.Requests()
+
+	// Definitions are laid out in blocks, in one response
+	event := &chat.Event{ReplyToken: url.Values{"response_url": {server.URL + "/response"}}.Encode(), Source: chat.Source{Type: chat.SourceTypeUser, UserID: "slack:U1"}}
+	messages := []chat.Message{
+		{Title: "line", Text: "a long <thin> mark"},
+		chat.NewTextMessage("dot, dash").WithQuickReplies(chat.NewPostbackButton("Synonyms", "1|syn|line"), chat.NewMessageButton("dot", "/def dot")),
+	}
+	if err := adapter.Reply(event, messages...); err != nil {
+		t.Errorf("Adapter.Reply() == %v, want %v", err, nil)
+	}
+	requests := server.Requests()
+	for _, want := range []string{
+		`"response_type":"ephemeral"`,
+		`"text":"line\na long <thin> mark\ndot, dash"`,
+		`{"type":"header","text":{"type":"plain_text","text":"line"}}`,
+		`{"type":"section","text":{"type":"mrkdwn","text":"a long &lt;thin&gt; mark"}}`,
+		`{"type":"actions","elements":[{"type":"button","text":{"type":"plain_text","text":"Synonyms"},"action_id":"postback:0","value":"1|syn|line"},{"type":"button","text":{"type":"plain_text","text":"dot"},"action_id":"message:1","value":"/def dot"}]}`,
+	} {
+		if len(requests) != 1 || !strings.HasPrefix(requests[0], "/response ") || !strings.Contains(requests[0], want) {
+			t.Errorf("Adapter.Reply() sent %q, want %q", requests, want)
+		}
+	}
+
+	// Mentions are answered in the channel, in their thread
+	event = &chat.Event{ReplyToken: "channel=C1&thread_ts=1.1", Source: chat.Source{Type: chat.SourceTypeGroup, ChatID: "slack:C1", UserID: "slack:U1"}}
+	adapter.Reply(event, chat.NewTextMessage("line"))
+	requests = server.Requests()
+	if len(requests) != 1 || !strings.HasPrefix(requests[0], "/api/chat.postMessage ") || !strings.Contains(requests[0], `"channel":"C1"`) || !strings.Contains(requests[0], `"thread_ts":"1.1"`) {
+		t.Errorf("Adapter.Reply() to a mention sent %q, want a message in the thread", requests)
+	}
+
+	card := chat.Message{Text: "Your settings:\nSenses: 1", Card: &chat.Card{
+		Title:   "Your settings",
+		Rows:    []chat.CardRow{{Label: "Senses", Button: chat.NewPostbackButton("1", "1|set|senses")}},
+		Note:    "Tap a value to change it.",
+		Buttons: []chat.Button{chat.NewPostbackButton("Reset to defaults", "1|set_reset|")},
+	}}
+	if err := adapter.Push("slack:U1", card); err != nil {
+		t.Errorf("Adapter.Push() == %v, want %v", err, nil)
+	}
+	requests = server.Requests()
+	for _, want := range []string{
+		`"channel":"U1"`,
+		`{"type":"section","text":{"type":"mrkdwn","text":"*Senses*"},"accessory":{"type":"button","text":{"type":"plain_text","text":"1"},"action_id":"postback:0","value":"1|set|senses"}}`,
+		`{"type":"context","elements":[{"type":"mrkdwn","text":"Tap a value to change it."}]}`,
+		`"value":"1|set_reset|"`,
+	} {
+		if len(requests) != 1 || !strings.Contains(requests[0], want) {
+			t.Errorf("Adapter.Push() of a card sent %q, want %q", requests, want)
+		}
+	}
+
+	if err := adapter.Multicast([]string{"slack:C403", "slack:U1"}, chat.NewTextMessage("hello")); err == nil {
+		t.Errorf("Adapter.Multicast() to a channel without the bot == %v, want an error", err)
+	}
+	if requests = server.Requests(); len(requests) != 2 {
+		t.Errorf("Adapter.Multicast() sent %q, want a message to each", requests)
+	}
+	if err := adapter.Broadcast(chat.NewTextMessage("hello")); err != chat.ErrNotSupported {
+		t.Errorf("Adapter.Broadcast() == %v, want %v", err, chat.ErrNotSupported)
+	}
+
+	profile, err := adapter.Profile("slack:U1")
+	if err != nil || profile.DisplayName != "Choo Choo" || profile.Language != "th" || profile.PictureURL == "" {
+		t.Errorf("Adapter.Profile(%q) == %+v, %v", "slack:U1", profile, err)
+	}
+	if requests = server.Requests(); len(requests) != 1 || !strings.Contains(requests[0], "user=U1") {
+		t.Errorf("Adapter.Profile() sent %q, want the user as a form", requests)
+	}
+	for _, c := range []struct {
+		userID string
+		want   bool
+	}{
+		{"slack:U0ADMIN", true},
+		{"slack:U1", false},
+	} {
+		if got, err := adapter.IsGroupAdmin("slack:C1", c.userID); got != c.want || err != nil {
+			t.Errorf("Adapter.IsGroupAdmin(%q, %q) == %v, %v, want %v", "slack:C1", c.userID, got, err, c.want)
+		}
+	}
+
+	adapter.Token = "wrong"
+	if _, err := adapter.AuthTest(); err == nil || err.Error() != "slack: auth.test: invalid_auth" {
+		t.Errorf("Adapter.AuthTest() with a wrong token == %v, want %q", err, "slack: auth.test: invalid_auth")
+	}
+}
+
+func TestSplit(t *testing.T) {
+	cases := []struct {
+		in    string
+		limit int
+		want  []string
+	}{
+		{"line", 10, []string{"line"}},
+		{"a line\nand a dot", 10, []string{"a line", "and a dot"}},
+		{"linelineline", 5, []string{"linel", "ineli", "ne"}},
+	}
+	for _, c := range cases {
+		if got := split(c.in, c.limit); strings.Join(got, "|") != strings.Join(c.want, "|") {
+			t.Errorf("split(%q, %d) == %q, want %q", c.in, c.limit, got, c.want)
+		}
+	}
+}
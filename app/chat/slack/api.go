@@ -0,0 +1,178 @@
+package slack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// The parts of the Slack Web API, Events API and Block Kit that the bot
+// uses, see https://api.slack.com/
+
+// EventCallback is a request of the Events API.
+type EventCallback struct {
+	// Type is "event_callback", or "url_verification" when Slack checks the
+	// request URL
+	Type      string        `json:"type"`
+	Challenge string        `json:"challenge,omitempty"`
+	TeamID    string        `json:"team_id,omitempty"`
+	EventID   string        `json:"event_id,omitempty"`
+	EventTime int64         `json:"event_time,omitempty"`
+	Event     *MessageEvent `json:"event,omitempty"`
+}
+
+type MessageEvent struct {
+	Type     string `json:"type"`
+	User     string `json:"user"`
+	Text     string `json:"text"`
+	TS       string `json:"ts"`
+	ThreadTS string `json:"thread_ts,omitempty"`
+	Channel  string `json:"channel"`
+}
+
+// Interaction is the payload sent when a user taps a button.
+type Interaction struct {
+	Type        string          `json:"type"`
+	User        IDObject        `json:"user"`
+	Channel     IDObject        `json:"channel"`
+	ResponseURL string          `json:"response_url"`
+	Actions     []ActionPayload `json:"actions"`
+}
+
+type IDObject struct {
+	ID string `json:"id"`
+}
+
+type ActionPayload struct {
+	ActionID string `json:"action_id"`
+	Value    string `json:"value"`
+}
+
+type Block struct {
+	Type      string        `json:"type"`
+	Text      *Text         `json:"text,omitempty"`
+	Accessory *Button       `json:"accessory,omitempty"`
+	Elements  []interface{} `json:"elements,omitempty"`
+}
+
+type Text struct {
+	// Type is "plain_text" or "mrkdwn"
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type Button struct {
+	Type     string `json:"type"`
+	Text     Text   `json:"text"`
+	ActionID string `json:"action_id"`
+	Value    string `json:"value"`
+}
+
+type postMessage struct {
+	Channel  string  `json:"channel"`
+	Text     string  `json:"text"`
+	Blocks   []Block `json:"blocks,omitempty"`
+	ThreadTS string  `json:"thread_ts,omitempty"`
+}
+
+// response is posted to the response URL of a slash command or a tap.
+type response struct {
+	ResponseType string  `json:"response_type"`
+	Text         string  `json:"text"`
+	Blocks       []Block `json:"blocks,omitempty"`
+}
+
+type userInfo struct {
+	User struct {
+		RealName string `json:"real_name"`
+		Locale   string `json:"locale"`
+		IsAdmin  bool   `json:"is_admin"`
+		IsOwner  bool   `json:"is_owner"`
+		Profile  struct {
+			DisplayName string `json:"display_name"`
+			Image72     string `json:"image_72"`
+		} `json:"profile"`
+	} `json:"user"`
+}
+
+type authTest struct {
+	UserID string `json:"user_id"`
+}
+
+type APIError struct {
+	Method string
+	Code   string
+}
+
+func (this *APIError) Error() string {
+	return fmt.Sprintf("slack: %s: %s", this.Method, this.Code)
+}
+
+// call calls a Web API method with params sent as a form when they are
+// url.Values, which methods that read require, or as JSON otherwise.
+func (this *Adapter) call(method string, params interface{}, result interface{}) error {
+	var req *http.Request
+	if form, ok := params.(url.Values); ok {
+		var err error
+		req, err = http.NewRequest(http.MethodPost, this.Endpoint+"/"+method, strings.NewReader(form.Encode()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		body, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		req, err = http.NewRequest(http.MethodPost, this.Endpoint+"/"+method, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	req.Header.Set("Authorization", "Bearer "+this.Token)
+	res, err := this.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return fmt.Errorf("slack: %s: %s", method, res.Status)
+	}
+	if !status.OK {
+		return &APIError{Method: method, Code: status.Error}
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(body, result)
+}
+
+// respond posts to the response URL of a slash command or a tap.
+func (this *Adapter) respond(responseURL string, params response) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	res, err := this.Client.Post(responseURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack: response URL: %s", res.Status)
+	}
+	return nil
+}
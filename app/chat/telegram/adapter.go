@@ -0,0 +1,404 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+)
+
+// IDPrefix starts the IDs of Telegram users and chats, which tells them
+// apart from the IDs of other platforms.
+const IDPrefix = "tg:"
+
+const (
+	// Telegram refuses longer messages and callback data
+	maxTextLength         = 4096
+	maxCallbackDataLength = 64
+	// Quick replies are laid out in rows of this many buttons
+	keyboardWidth = 3
+)
+
+var ErrInvalidSecret = errors.New("telegram: invalid secret token")
+
+// Adapter runs the bot on the Telegram Bot API.
+type Adapter struct {
+	Token    string
+	Endpoint string
+	Client   *http.Client
+	// Secret is the secret token the webhook was set with. Webhook requests
+	// without it are refused unless it is empty.
+	Secret string
+	// Bot is the bot's own user, as returned by GetMe. It tells which
+	// mentions and commands in groups are meant for the bot.
+	Bot User
+}
+
+func NewAdapter(token string) *Adapter {
+	return &Adapter{
+		Token:    token,
+		Endpoint: "https://api.telegram.org",
+		// Long polling holds requests for up to pollTimeout
+		Client: &http.Client{Timeout: pollTimeout + 10*time.Second},
+	}
+}
+
+// ID turns the ID of a Telegram user or chat into the bot's ID for them.
+func ID(id int64) string {
+	return IDPrefix + strconv.FormatInt(id, 10)
+}
+
+func (this *Adapter) GetMe() (User, error) {
+	var user User
+	err := this.call(context.Background(), "getMe", struct{}{}, &user)
+	return user, err
+}
+
+// ParseRequest checks the secret token of a webhook request and returns its
+// events. It fails with ErrInvalidSecret for a bad token.
+func (this *Adapter) ParseRequest(r *http.Request) ([]*chat.Event, error) {
+	defer r.Body.Close()
+	secret := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+	if this.Secret != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(this.Secret)) != 1 {
+		return nil, ErrInvalidSecret
+	}
+	var update Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		return nil, err
+	}
+	events := []*chat.Event{}
+	if event := this.Event(&update); event != nil {
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (this *Adapter) Reply(event *chat.Event, messages ...chat.Message) error {
+	chatID, messageID, callbackID := parseReplyToken(event.ReplyToken)
+	var result error
+	if callbackID != "" {
+		// Stops the spinner on the tapped button
+		result = this.call(context.Background(), "answerCallbackQuery", answerCallbackQuery{CallbackQueryID: callbackID}, nil)
+	}
+	// Replies in groups quote the message they answer
+	if event.Source.ChatID == "" {
+		messageID = 0
+	}
+	if err := this.send(chatID, messageID, messages); err != nil {
+		return err
+	}
+	return result
+}
+
+func (this *Adapter) Push(to string, messages ...chat.Message) error {
+	return this.send(strings.TrimPrefix(to, IDPrefix), 0, messages)
+}
+
+// Multicast pushes to each user in turn, as Telegram has no multicast, and
+// returns the first error after trying them all.
+func (this *Adapter) Multicast(to []string, messages ...chat.Message) error {
+	var result error
+	for _, id := range to {
+		if err := this.Push(id, messages...); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+func (this *Adapter) Broadcast(messages ...chat.Message) error {
+	return chat.ErrNotSupported
+}
+
+// Profile has no language, as Telegram only tells it along with updates.
+func (this *Adapter) Profile(userID string) (chat.Profile, error) {
+	var user Chat
+	if err := this.call(context.Background(), "getChat", getChat{ChatID: strings.TrimPrefix(userID, IDPrefix)}, &user); err != nil {
+		return chat.Profile{}, err
+	}
+	return chat.Profile{
+		DisplayName: strings.TrimSpace(user.FirstName + " " + user.LastName),
+	}, nil
+}
+
+// IsGroupAdmin reports whether a user is the creator or an administrator of
+// a group.
+func (this *Adapter) IsGroupAdmin(chatID string, userID string) (bool, error) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(userID, IDPrefix), 10, 64)
+	if err != nil {
+		return false, err
+	}
+	var member ChatMember
+	if err := this.call(context.Background(), "getChatMember", getChatMember{ChatID: strings.TrimPrefix(chatID, IDPrefix), UserID: id}, &member); err != nil {
+		return false, err
+	}
+	return member.Status == "creator" || member.Status == "administrator", nil
+}
+
+// Event translates an update, or returns nil for updates the bot ignores.
+func (this *Adapter) Event(update *Update) *chat.Event {
+	event := &chat.Event{ID: ID(update.UpdateID)}
+	switch {
+	case update.Message != nil:
+		message := update.Message
+		event.Type = chat.EventTypeMessage
+		event.Timestamp = time.Unix(message.Date, 0)
+		event.Source = source(message.Chat, message.From)
+		event.ReplyToken = replyToken(message.Chat.ID, message.MessageID, "")
+		event.MessageType = messageType(message)
+		if event.MessageType == "" {
+			return nil
+		}
+		if event.MessageType == chat.MessageTypeText {
+			text, mentions, ok := this.text(message)
+			if !ok {
+				return nil
+			}
+			event.Text, event.Mentions = text, mentions
+		}
+	case update.CallbackQuery != nil:
+		query := update.CallbackQuery
+		// Buttons are on the bot's messages, so the chat is known but for
+		// very old messages
+		from := Chat{ID: query.From.ID, Type: "private"}
+		if query.Message != nil {
+			from = query.Message.Chat
+		}
+		event.Type = chat.EventTypePostback
+		event.Source = source(from, &query.From)
+		event.ReplyToken = replyToken(from.ID, 0, query.ID)
+		event.Postback = query.Data
+	case update.MyChatMember != nil:
+		member := update.MyChatMember
+		joined, left := isMember(member.NewChatMember.Status), isMember(member.OldChatMember.Status)
+		if joined == left {
+			return nil
+		}
+		private := member.Chat.Type == "private"
+		switch {
+		case joined && private:
+			event.Type = chat.EventTypeFollow
+		case joined:
+			event.Type = chat.EventTypeJoin
+		case private:
+			event.Type = chat.EventTypeUnfollow
+		default:
+			event.Type = chat.EventTypeLeave
+		}
+		event.Timestamp = time.Unix(member.Date, 0)
+		event.Source = source(member.Chat, &member.From)
+		event.ReplyToken = replyToken(member.Chat.ID, 0, "")
+	default:
+		return nil
+	}
+	return event
+}
+
+func isMember(status string) bool {
+	return status == "creator" || status == "administrator" || status == "member" || status == "restricted"
+}
+
+func source(from Chat, user *User) chat.Source {
+	result := chat.Source{Type: chat.SourceTypeUser, UserID: ID(from.ID)}
+	if from.Type != "private" {
+		result = chat.Source{Type: chat.SourceTypeGroup, ChatID: ID(from.ID)}
+		if user != nil {
+			result.UserID = ID(user.ID)
+		}
+	}
+	return result
+}
+
+func messageType(message *Message) chat.MessageType {
+	switch {
+	case message.Text != "":
+		return chat.MessageTypeText
+	case message.Photo != nil:
+		return chat.MessageTypeImage
+	case message.Video != nil || message.VideoNote != nil:
+		return chat.MessageTypeVideo
+	case message.Audio != nil || message.Voice != nil:
+		return chat.MessageTypeAudio
+	case message.Document != nil:
+		return chat.MessageTypeFile
+	case message.Location != nil:
+		return chat.MessageTypeLocation
+	case message.Sticker != nil:
+		return chat.MessageTypeSticker
+	}
+	return ""
+}
+
+// text returns the text of a message and its mentions, with the bot's name
+// taken off commands like "/def@ChooDictBot line". It returns false for
+// commands meant for other bots.
+func (this *Adapter) text(message *Message) (string, []chat.Mention, bool) {
+	units := utf16.Encode([]rune(message.Text))
+	text := units
+	mentions := []chat.Mention{}
+	removed := 0
+	for _, entity := range message.Entities {
+		if entity.Offset < 0 || entity.Offset+entity.Length > len(units) {
+			continue
+		}
+		part := string(utf16.Decode(units[entity.Offset : entity.Offset+entity.Length]))
+		switch entity.Type {
+		case "bot_command":
+			at := strings.Index(part, "@")
+			if entity.Offset != 0 || at < 0 {
+				continue
+			}
+			if !strings.EqualFold(part[at+1:], this.Bot.Username) {
+				return "", nil, false
+			}
+			end := entity.Offset + entity.Length
+			removed = entity.Length - len(utf16.Encode([]rune(part[:at])))
+			text = append(units[:end-removed:end-removed], units[end:]...)
+		case "mention":
+			if this.Bot.Username != "" && strings.EqualFold(part, "@"+this.Bot.Username) {
+				mentions = append(mentions, chat.Mention{UserID: ID(this.Bot.ID), Index: entity.Offset - removed, Length: entity.Length})
+			}
+		case "text_mention":
+			if entity.User != nil {
+				mentions = append(mentions, chat.Mention{UserID: ID(entity.User.ID), Index: entity.Offset - removed, Length: entity.Length})
+			}
+		}
+	}
+	return string(utf16.Decode(text)), mentions, true
+}
+
+// replyToken keeps what replies need: the chat, the message to quote and
+// the callback query to answer.
+func replyToken(chatID int64, messageID int64, callbackID string) string {
+	return strconv.FormatInt(chatID, 10) + ":" + strconv.FormatInt(messageID, 10) + ":" + callbackID
+}
+
+func parseReplyToken(token string) (string, int64, string) {
+	parts := strings.SplitN(token, ":", 3)
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+	messageID, _ := strconv.ParseInt(parts[1], 10, 64)
+	return parts[0], messageID, parts[2]
+}
+
+func (this *Adapter) send(chatID string, replyTo int64, messages []chat.Message) error {
+	for _, message := range messages {
+		texts := texts(message)
+		for i, text := range texts {
+			params := sendMessage{ChatID: chatID, Text: text, ParseMode: "HTML", ReplyToMessageID: replyTo}
+			// Buttons go under the last part of a long message
+			if i == len(texts)-1 {
+				params.ReplyMarkup = replyMarkup(message)
+			}
+			if err := this.call(context.Background(), "sendMessage", params, nil); err != nil {
+				return err
+			}
+			replyTo = 0
+		}
+	}
+	return nil
+}
+
+// texts renders a message as HTML, split into parts that Telegram takes. A
+// card shows its title and note, with its rows as buttons.
+func texts(message chat.Message) []string {
+	if message.Card != nil {
+		text := "<b>" + html.EscapeString(message.Card.Title) + "</b>"
+		if message.Card.Note != "" {
+			text += "\n<i>" + html.EscapeString(message.Card.Note) + "</i>"
+		}
+		return []string{text}
+	}
+	title := ""
+	if message.Title != "" {
+		title = "<b>" + html.EscapeString(message.Title) + "</b>\n"
+	}
+	// Telegram counts the length without the markup
+	parts := split(message.Text, maxTextLength-len(utf16.Encode([]rune(message.Title)))-1)
+	for i := range parts {
+		parts[i] = html.EscapeString(parts[i])
+	}
+	parts[0] = title + parts[0]
+	return parts
+}
+
+// split cuts text into parts of at most limit UTF-16 code units, at line
+// breaks where it can.
+func split(text string, limit int) []string {
+	parts := []string{}
+	units := utf16.Encode([]rune(text))
+	for len(units) > limit {
+		end := limit
+		if utf16.IsSurrogate(rune(units[end-1])) {
+			end--
+		}
+		for i := end - 1; i > 0; i-- {
+			if units[i] == '\n' {
+				end = i + 1
+				break
+			}
+		}
+		parts = append(parts, strings.TrimSuffix(string(utf16.Decode(units[:end])), "\n"))
+		units = units[end:]
+	}
+	return append(parts, string(utf16.Decode(units)))
+}
+
+// replyMarkup lays buttons out as an inline keyboard, or when they all send
+// messages, as a keyboard that sends them. Buttons that don't fit that
+// keyboard are left out, as are those whose data Telegram would refuse.
+func replyMarkup(message chat.Message) interface{} {
+	rows := [][]InlineKeyboardButton{}
+	if card := message.Card; card != nil {
+		for _, row := range card.Rows {
+			rows = appendButtons(rows, 1, chat.Button{Label: row.Label + ": " + row.Button.Label, Data: row.Button.Data})
+		}
+		for _, button := range card.Buttons {
+			rows = appendButtons(rows, 1, button)
+		}
+	}
+	rows = appendButtons(rows, keyboardWidth, message.QuickReplies...)
+	if len(rows) > 0 {
+		return InlineKeyboardMarkup{InlineKeyboard: rows}
+	}
+	keyboard := [][]KeyboardButton{}
+	for _, button := range message.QuickReplies {
+		if button.Data != "" || button.Text == "" {
+			continue
+		}
+		if len(keyboard) == 0 || len(keyboard[len(keyboard)-1]) == keyboardWidth {
+			keyboard = append(keyboard, []KeyboardButton{})
+		}
+		keyboard[len(keyboard)-1] = append(keyboard[len(keyboard)-1], KeyboardButton{Text: button.Text})
+	}
+	if len(keyboard) > 0 {
+		return ReplyKeyboardMarkup{Keyboard: keyboard, OneTimeKeyboard: true, ResizeKeyboard: true}
+	}
+	return nil
+}
+
+func appendButtons(rows [][]InlineKeyboardButton, width int, buttons ...chat.Button) [][]InlineKeyboardButton {
+	row := []InlineKeyboardButton{}
+	for _, button := range buttons {
+		if button.Data == "" || len(button.Data) > maxCallbackDataLength {
+			continue
+		}
+		row = append(row, InlineKeyboardButton{Text: button.Label, CallbackData: button.Data})
+		if len(row) == width {
+			rows, row = append(rows, row), []InlineKeyboardButton{}
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	return rows
+}
@@ -0,0 +1,284 @@
+package telegram
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+)
+
+type fakeTelegramServer struct {
+	*httptest.Server
+	requestsMux sync.Mutex
+	requests    []string
+	updates     chan []Update
+}
+
+func (this *fakeTelegramServer) Requests() []string {
+	this.requestsMux.Lock()
+	defer this.requestsMux.Unlock()
+	requests := this.requests
+	this.requests = nil
+	return requests
+}
+
+// newFakeTelegramServer records requests as "<method> <body>" and answers
+// getUpdates with the batches sent to its updates channel, or with none
+// once it is empty. Messages to the chat 403 fail as they do when a user
+// has blocked the bot.
+func newFakeTelegramServer(t *testing.T) (*fakeTelegramServer, *Adapter) {
+	server := &fakeTelegramServer{updates: make(chan []Update, 10)}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/bottoken/") {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"ok":false,"error_code":404,"description":"Not Found"}`))
+			return
+		}
+		method := strings.TrimPrefix(r.URL.Path, "/bottoken/")
+		body, _ := ioutil.ReadAll(r.Body)
+		server.requestsMux.Lock()
+		server.requests = append(server.requests, method+" "+unescapeHTML(body))
+		server.requestsMux.Unlock()
+		result := interface{}(true)
+		switch method {
+		case "sendMessage":
+			if strings.Contains(string(body), `"chat_id":"403"`) {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(`{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`))
+				return
+			}
+			result = Message{MessageID: 1}
+		case "getMe":
+			result = User{ID: 100, IsBot: true, FirstName: "Choo", Username: "ChooDictBot"}
+		case "getChat":
+			result = Chat{ID: 1, Type: "private", FirstName: "Choo", LastName: "Choo"}
+		case "getChatMember":
+			result = ChatMember{Status: "member"}
+			if strings.Contains(string(body), `"user_id":1}`) {
+				result = ChatMember{Status: "creator"}
+			}
+		case "getUpdates":
+			select {
+			case updates := <-server.updates:
+				result = updates
+			default:
+				result = []Update{}
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": result})
+	}))
+	adapter := NewAdapter("token")
+	adapter.Endpoint = server.URL
+	adapter.Bot = User{ID: 100, IsBot: true, Username: "ChooDictBot"}
+	return server, adapter
+}
+
+// unescapeHTML undoes the escaping of <, > and & in JSON, so that tests can
+// look for the HTML that was sent.
+func unescapeHTML(body []byte) string {
+	return strings.NewReplacer(`\u003c`, "<", `\u003e`, ">", `\u0026`, "&").Replace(string(body))
+}
+
+func TestAdapterEvent(t *testing.T) {
+	adapter := NewAdapter("token")
+	adapter.Bot = User{ID: 100, IsBot: true, Username: "ChooDictBot"}
+	private := Chat{ID: 1, Type: "private"}
+	group := Chat{ID: -5, Type: "supergroup"}
+	user := &User{ID: 1, FirstName: "Choo"}
+	cases := []struct {
+		in   Update
+		want *chat.Event
+	}{
+		{
+			Update{UpdateID: 7, Message: &Message{MessageID: 3, From: user, Chat: private, Date: 1, Text: "line"}},
+			&chat.Event{Type: chat.EventTypeMessage, ID: "tg:7", ReplyToken: "1:3:", Source: chat.Source{Type: chat.SourceTypeUser, UserID: "tg:1"}, MessageType: chat.MessageTypeText, Text: "line"},
+		},
+		{
+			Update{UpdateID: 8, Message: &Message{MessageID: 4, From: user, Chat: group, Text: "@ChooDictBot line", Entities: []MessageEntity{{Type: "mention", Offset: 0, Length: 12}}}},
+			&chat.Event{Type: chat.EventTypeMessage, ID: "tg:8", ReplyToken: "-5:4:", Source: chat.Source{Type: chat.SourceTypeGroup, ChatID: "tg:-5", UserID: "tg:1"}, MessageType: chat.MessageTypeText, Text: "@ChooDictBot line", Mentions: []chat.Mention{{UserID: "tg:100", Index: 0, Length: 12}}},
+		},
+		// The bot's name is taken off commands, and mentions after them move
+		{
+			Update{UpdateID: 9, Message: &Message{MessageID: 5, From: user, Chat: group, Text: "/def@ChooDictBot @ChooDictBot", Entities: []MessageEntity{{Type: "bot_command", Offset: 0, Length: 16}, {Type: "mention", Offset: 17, Length: 12}}}},
+			&chat.Event{Type: chat.EventTypeMessage, ID: "tg:9", ReplyToken: "-5:5:", Source: chat.Source{Type: chat.SourceTypeGroup, ChatID: "tg:-5", UserID: "tg:1"}, MessageType: chat.MessageTypeText, Text: "/def @ChooDictBot", Mentions: []chat.Mention{{UserID: "tg:100", Index: 5, Length: 12}}},
+		},
+		// Commands for other bots are not the bot's business
+		{
+			Update{UpdateID: 10, Message: &Message{From: user, Chat: group, Text: "/def@OtherBot line", Entities: []MessageEntity{{Type: "bot_command", Offset: 0, Length: 13}}}},
+			nil,
+		},
+		{
+			Update{UpdateID: 11, Message: &Message{MessageID: 6, From: user, Chat: private, Sticker: json.RawMessage(`{}`)}},
+			&chat.Event{Type: chat.EventTypeMessage, ID: "tg:11", ReplyToken: "1:6:", Source: chat.Source{Type: chat.SourceTypeUser, UserID: "tg:1"}, MessageType: chat.MessageTypeSticker},
+		},
+		{
+			Update{UpdateID: 12, CallbackQuery: &CallbackQuery{ID: "query1", From: *user, Message: &Message{Chat: group}, Data: "1|syn|line"}},
+			&chat.Event{Type: chat.EventTypePostback, ID: "tg:12", ReplyToken: "-5:0:query1", Source: chat.Source{Type: chat.SourceTypeGroup, ChatID: "tg:-5", UserID: "tg:1"}, Postback: "1|syn|line"},
+		},
+		{
+			Update{UpdateID: 13, MyChatMember: &ChatMemberUpdated{Chat: group, From: *user, OldChatMember: ChatMember{Status: "left"}, NewChatMember: ChatMember{Status: "member"}}},
+			&chat.Event{Type: chat.EventTypeJoin, ID: "tg:13", ReplyToken: "-5:0:", Source: chat.Source{Type: chat.SourceTypeGroup, ChatID: "tg:-5", UserID: "tg:1"}},
+		},
+		{
+			Update{UpdateID: 14, MyChatMember: &ChatMemberUpdated{Chat: private, From: *user, OldChatMember: ChatMember{Status: "member"}, NewChatMember: ChatMember{Status: "kicked"}}},
+			&chat.Event{Type: chat.EventTypeUnfollow, ID: "tg:14", ReplyToken: "1:0:", Source: chat.Source{Type: chat.SourceTypeUser, UserID: "tg:1"}},
+		},
+		// Promotions don't change whether the bot is in the chat
+		{
+			Update{UpdateID: 15, MyChatMember: &ChatMemberUpdated{Chat: group, From: *user, OldChatMember: ChatMember{Status: "member"}, NewChatMember: ChatMember{Status: "administrator"}}},
+			nil,
+		},
+		{
+			Update{UpdateID: 16},
+			nil,
+		},
+	}
+	for _, c := range cases {
+		got := adapter.Event(&c.in)
+		if got == nil || c.want == nil {
+			if got != c.want {
+				t.Errorf("Adapter.Event(%d) == %+v, want %+v", c.in.UpdateID, got, c.want)
+			}
+			continue
+		}
+		if got.Type != c.want.Type || got.ID != c.want.ID || got.ReplyToken != c.want.ReplyToken || got.Source != c.want.Source ||
+			got.MessageType != c.want.MessageType || got.Text != c.want.Text || got.Postback != c.want.Postback || len(got.Mentions) != len(c.want.Mentions) {
+			t.Errorf("Adapter.Event(%d) == %+v, want %+v", c.in.UpdateID, got, c.want)
+			continue
+		}
+		for i := range c.want.Mentions {
+			if got.Mentions[i] != c.want.Mentions[i] {
+				t.Errorf("Adapter.Event(%d) mentions == %+v, want %+v", c.in.UpdateID, got.Mentions, c.want.Mentions)
+			}
+		}
+	}
+}
+
+func TestAdapterParseRequest(t *testing.T) {
+	adapter := NewAdapter("token")
+	adapter.Secret = "secret"
+	request := func(secret string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/telegram", strings.NewReader(`{"update_id":1,"message":{"message_id":2,"chat":{"id":1,"type":"private"},"from":{"id":1},"text":"line"}}`))
+		r.Header.Set("X-Telegram-Bot-Api-Secret-Token", secret)
+		return r
+	}
+
+	if _, err := adapter.ParseRequest(request("wrong")); err != ErrInvalidSecret {
+		t.Errorf("Adapter.ParseRequest() with a wrong secret == %v, want %v", err, ErrInvalidSecret)
+	}
+	events, err := adapter.ParseRequest(request("secret"))
+	if err != nil || len(events) != 1 || events[0].Text != "line" || events[0].Source.UserID != "tg:1" {
+		t.Errorf("Adapter.ParseRequest() == %+v, %v, want the message", events, err)
+	}
+}
+
+func TestAdapterSend(t *testing.T) {
+	server, adapter := newFakeTelegramServer(t)
+	defer server.Close()
+
+	me, err := adapter.GetMe()
+	if err != nil || me.Username != "ChooDictBot" {
+		t.Errorf("Adapter.GetMe() == %+v, %v", me, err)
+	}
+	server.Requests()
+
+	// Lookups are formatted, with the synonyms buttons under them
+	event := &chat.Event{ReplyToken: "-5:4:", Source: chat.Source{Type: chat.SourceTypeGroup, ChatID: "tg:-5", UserID: "tg:1"}}
+	message := chat.Message{Title: "line", Text: "a long <thin> mark"}.WithQuickReplies(
+		chat.NewPostbackButton("Synonyms", "1|syn|line"),
+		chat.NewPostbackButton("Too long", "1|syn|"+strings.Repeat("x", maxCallbackDataLength)),
+		chat.NewMessageButton("dot", "?dot"),
+	)
+	if err := adapter.Reply(event, message); err != nil {
+		t.Errorf("Adapter.Reply() == %v, want %v", err, nil)
+	}
+	requests := server.Requests()
+	for _, want := range []string{`"chat_id":"-5"`, `"text":"<b>line</b>\na long &lt;thin&gt; mark"`, `"parse_mode":"HTML"`, `"reply_to_message_id":4`, `"inline_keyboard":[[{"text":"Synonyms","callback_data":"1|syn|line"}]]`} {
+		if len(requests) != 1 || !strings.HasPrefix(requests[0], "sendMessage") || !strings.Contains(requests[0], want) {
+			t.Errorf("Adapter.Reply() sent %q, want %q", requests, want)
+		}
+	}
+
+	// Taps on buttons are answered
+	event = &chat.Event{ReplyToken: "1:0:query1", Source: chat.Source{Type: chat.SourceTypeUser, UserID: "tg:1"}}
+	adapter.Reply(event, chat.NewTextMessage("line").WithQuickReplies(chat.NewMessageButton("dot", "/def dot")))
+	requests = server.Requests()
+	if len(requests) != 2 || requests[0] != `answerCallbackQuery {"callback_query_id":"query1"}` ||
+		!strings.Contains(requests[1], `"keyboard":[[{"text":"/def dot"}]],"one_time_keyboard":true`) || strings.Contains(requests[1], "reply_to_message_id") {
+		t.Errorf("Adapter.Reply() to a button sent %q, want an answer and a message with a keyboard", requests)
+	}
+
+	card := chat.Message{Text: "Your settings:\nSenses: 1", Card: &chat.Card{
+		Title:   "Your settings",
+		Rows:    []chat.CardRow{{Label: "Senses", Button: chat.NewPostbackButton("1", "1|set|senses")}},
+		Note:    "Tap a value to change it.",
+		Buttons: []chat.Button{chat.NewPostbackButton("Reset to defaults", "1|set_reset|")},
+	}}
+	if err := adapter.Push("tg:1", card); err != nil {
+		t.Errorf("Adapter.Push() == %v, want %v", err, nil)
+	}
+	requests = server.Requests()
+	for _, want := range []string{`"chat_id":"1"`, `"text":"<b>Your settings</b>\n<i>Tap a value to change it.</i>"`, `[{"text":"Senses: 1","callback_data":"1|set|senses"}],[{"text":"Reset to defaults","callback_data":"1|set_reset|"}]`} {
+		if len(requests) != 1 || !strings.Contains(requests[0], want) {
+			t.Errorf("Adapter.Push() of a card sent %q, want %q", requests, want)
+		}
+	}
+
+	// Long messages are split, with the buttons under the last part
+	long := strings.Repeat("word\n", maxTextLength/5+1) + "end"
+	adapter.Push("tg:1", chat.NewTextMessage(long).WithQuickReplies(chat.NewPostbackButton("Synonyms", "1|syn|word")))
+	requests = server.Requests()
+	if len(requests) != 2 || strings.Contains(requests[0], "inline_keyboard") || !strings.Contains(requests[1], `"text":"word\nend"`) || !strings.Contains(requests[1], "inline_keyboard") {
+		t.Errorf("Adapter.Push() of a long message sent %q, want two parts", requests)
+	}
+
+	if err := adapter.Multicast([]string{"tg:403", "tg:1"}, chat.NewTextMessage("hello")); err == nil {
+		t.Errorf("Adapter.Multicast() to a user who blocked the bot == %v, want an error", err)
+	}
+	if requests = server.Requests(); len(requests) != 2 {
+		t.Errorf("Adapter.Multicast() sent %q, want a message to each user", requests)
+	}
+	if err := adapter.Broadcast(chat.NewTextMessage("hello")); err != chat.ErrNotSupported {
+		t.Errorf("Adapter.Broadcast() == %v, want %v", err, chat.ErrNotSupported)
+	}
+
+	profile, err := adapter.Profile("tg:1")
+	if err != nil || profile.DisplayName != "Choo Choo" {
+		t.Errorf("Adapter.Profile(%q) == %+v, %v", "tg:1", profile, err)
+	}
+	for _, c := range []struct {
+		userID string
+		want   bool
+	}{
+		{"tg:1", true},
+		{"tg:2", false},
+	} {
+		if got, err := adapter.IsGroupAdmin("tg:-5", c.userID); got != c.want || err != nil {
+			t.Errorf("Adapter.IsGroupAdmin(%q, %q) == %v, %v, want %v", "tg:-5", c.userID, got, err, c.want)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	cases := []struct {
+		in    string
+		limit int
+		want  []string
+	}{
+		{"line", 10, []string{"line"}},
+		{"a line\nand a dot", 10, []string{"a line", "and a dot"}},
+		{"linelineline", 5, []string{"linel", "ineli", "ne"}},
+		// Characters outside the BMP take two code units and aren't cut
+		{"a😀", 2, []string{"a", "😀"}},
+	}
+	for _, c := range cases {
+		if got := split(c.in, c.limit); strings.Join(got, "|") != strings.Join(c.want, "|") {
+			t.Errorf("split(%q, %d) == %q, want %q", c.in, c.limit, got, c.want)
+		}
+	}
+}
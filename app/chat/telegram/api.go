@@ -0,0 +1,184 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// The parts of the Telegram Bot API that the bot uses, see
+// https://core.telegram.org/bots/api
+
+type Update struct {
+	UpdateID      int64              `json:"update_id"`
+	Message       *Message           `json:"message,omitempty"`
+	CallbackQuery *CallbackQuery     `json:"callback_query,omitempty"`
+	MyChatMember  *ChatMemberUpdated `json:"my_chat_member,omitempty"`
+}
+
+type User struct {
+	ID           int64  `json:"id"`
+	IsBot        bool   `json:"is_bot,omitempty"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name,omitempty"`
+	Username     string `json:"username,omitempty"`
+	LanguageCode string `json:"language_code,omitempty"`
+}
+
+type Chat struct {
+	ID int64 `json:"id"`
+	// Type is "private", "group", "supergroup" or "channel"
+	Type      string `json:"type"`
+	FirstName string `json:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty"`
+	Username  string `json:"username,omitempty"`
+}
+
+type Message struct {
+	MessageID int64           `json:"message_id"`
+	From      *User           `json:"from,omitempty"`
+	Chat      Chat            `json:"chat"`
+	Date      int64           `json:"date"`
+	Text      string          `json:"text,omitempty"`
+	Entities  []MessageEntity `json:"entities,omitempty"`
+	// Only whether these are set matters to the bot
+	Photo     json.RawMessage `json:"photo,omitempty"`
+	Video     json.RawMessage `json:"video,omitempty"`
+	VideoNote json.RawMessage `json:"video_note,omitempty"`
+	Audio     json.RawMessage `json:"audio,omitempty"`
+	Voice     json.RawMessage `json:"voice,omitempty"`
+	Document  json.RawMessage `json:"document,omitempty"`
+	Location  json.RawMessage `json:"location,omitempty"`
+	Sticker   json.RawMessage `json:"sticker,omitempty"`
+}
+
+// MessageEntity marks a part of a message's text. Offsets and lengths are
+// counted in UTF-16 code units.
+type MessageEntity struct {
+	Type   string `json:"type"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+	User   *User  `json:"user,omitempty"`
+}
+
+type CallbackQuery struct {
+	ID      string   `json:"id"`
+	From    User     `json:"from"`
+	Message *Message `json:"message,omitempty"`
+	Data    string   `json:"data,omitempty"`
+}
+
+// ChatMemberUpdated tells that the bot was added to or removed from a chat,
+// or that a user blocked or unblocked it.
+type ChatMemberUpdated struct {
+	Chat          Chat       `json:"chat"`
+	From          User       `json:"from"`
+	Date          int64      `json:"date"`
+	OldChatMember ChatMember `json:"old_chat_member"`
+	NewChatMember ChatMember `json:"new_chat_member"`
+}
+
+type ChatMember struct {
+	// Status is "creator", "administrator", "member", "restricted", "left"
+	// or "kicked"
+	Status string `json:"status"`
+	User   User   `json:"user"`
+}
+
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+type ReplyKeyboardMarkup struct {
+	Keyboard        [][]KeyboardButton `json:"keyboard"`
+	OneTimeKeyboard bool               `json:"one_time_keyboard,omitempty"`
+	ResizeKeyboard  bool               `json:"resize_keyboard,omitempty"`
+}
+
+type KeyboardButton struct {
+	Text string `json:"text"`
+}
+
+type sendMessage struct {
+	ChatID           string      `json:"chat_id"`
+	Text             string      `json:"text"`
+	ParseMode        string      `json:"parse_mode,omitempty"`
+	ReplyToMessageID int64       `json:"reply_to_message_id,omitempty"`
+	ReplyMarkup      interface{} `json:"reply_markup,omitempty"`
+}
+
+type answerCallbackQuery struct {
+	CallbackQueryID string `json:"callback_query_id"`
+}
+
+type getChat struct {
+	ChatID string `json:"chat_id"`
+}
+
+type getChatMember struct {
+	ChatID string `json:"chat_id"`
+	UserID int64  `json:"user_id"`
+}
+
+type getUpdates struct {
+	Offset  int64 `json:"offset,omitempty"`
+	Timeout int   `json:"timeout,omitempty"`
+}
+
+type APIError struct {
+	Code        int
+	Description string
+}
+
+func (this *APIError) Error() string {
+	return fmt.Sprintf("telegram: %d %s", this.Code, this.Description)
+}
+
+type response struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+}
+
+// call calls a Bot API method and decodes its result into result, unless
+// result is nil.
+func (this *Adapter) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, this.Endpoint+"/bot"+this.Token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := this.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		// The URL holds the token, so it is left out of the error
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("telegram: %s: %w", method, err)
+	}
+	defer res.Body.Close()
+	var apiResponse response
+	if err := json.NewDecoder(res.Body).Decode(&apiResponse); err != nil {
+		return fmt.Errorf("telegram: %s: %s", method, res.Status)
+	}
+	if !apiResponse.OK {
+		return &APIError{Code: apiResponse.ErrorCode, Description: apiResponse.Description}
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(apiResponse.Result, result)
+}
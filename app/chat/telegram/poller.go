@@ -0,0 +1,85 @@
+package telegram
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+)
+
+const (
+	pollTimeout = 30 * time.Second
+	retryDelay  = 5 * time.Second
+)
+
+// Poller fetches updates by long polling, for servers that Telegram can't
+// send webhooks to. Telegram refuses to poll while a webhook is set.
+type Poller struct {
+	adapter *Adapter
+	handle  func(events []*chat.Event) error
+	offset  int64
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+}
+
+// NewPoller passes the events of each batch of updates to handle. Updates
+// are fetched again until handle takes them.
+func NewPoller(adapter *Adapter, handle func(events []*chat.Event) error) *Poller {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Poller{
+		adapter: adapter,
+		handle:  handle,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func (this *Poller) Start() {
+	this.running.Add(1)
+	go func() {
+		defer this.running.Done()
+		for this.ctx.Err() == nil {
+			if err := this.Poll(this.ctx); err != nil && this.ctx.Err() == nil {
+				log.Println(err)
+				select {
+				case <-this.ctx.Done():
+				case <-time.After(retryDelay):
+				}
+			}
+		}
+	}()
+}
+
+func (this *Poller) Stop() {
+	this.cancel()
+	this.running.Wait()
+}
+
+// Poll fetches one batch of updates and passes their events on.
+func (this *Poller) Poll(ctx context.Context) error {
+	updates := []Update{}
+	params := getUpdates{Offset: this.offset, Timeout: int(pollTimeout / time.Second)}
+	if err := this.adapter.call(ctx, "getUpdates", params, &updates); err != nil {
+		return err
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	events := []*chat.Event{}
+	for i := range updates {
+		if event := this.adapter.Event(&updates[i]); event != nil {
+			events = append(events, event)
+		}
+	}
+	if len(events) > 0 {
+		if err := this.handle(events); err != nil {
+			return err
+		}
+	}
+	// Telegram forgets the updates before the offset
+	this.offset = updates[len(updates)-1].UpdateID + 1
+	return nil
+}
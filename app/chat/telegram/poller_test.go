@@ -0,0 +1,67 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/choobot/choo-dict-bot/app/chat"
+)
+
+func TestPollerPoll(t *testing.T) {
+	server, adapter := newFakeTelegramServer(t)
+	defer server.Close()
+	var handled []*chat.Event
+	var handleErr error
+	poller := NewPoller(adapter, func(events []*chat.Event) error {
+		if handleErr != nil {
+			return handleErr
+		}
+		handled = append(handled, events...)
+		return nil
+	})
+	batch := []Update{
+		{UpdateID: 7, Message: &Message{MessageID: 1, Chat: Chat{ID: 1, Type: "private"}, Text: "line"}},
+		// Updates the bot ignores still move the offset
+		{UpdateID: 8},
+	}
+
+	// Batches that can't be handled are fetched again
+	handleErr = errors.New("queue is full")
+	server.updates <- batch
+	if err := poller.Poll(context.Background()); err != handleErr {
+		t.Errorf("Poller.Poll() while handle fails == %v, want %v", err, handleErr)
+	}
+	handleErr = nil
+	server.updates <- batch
+	if err := poller.Poll(context.Background()); err != nil || len(handled) != 1 || handled[0].Text != "line" {
+		t.Errorf("Poller.Poll() == %v, handled %+v, want the message", err, handled)
+	}
+	poller.Poll(context.Background())
+	requests := server.Requests()
+	for i, want := range []string{`"timeout":30`, `"timeout":30`, `"offset":9`} {
+		if len(requests) != 3 || !strings.HasPrefix(requests[i], "getUpdates") || !strings.Contains(requests[i], want) {
+			t.Errorf("Poller.Poll() sent %q, want %q in request %d", requests, want, i)
+		}
+	}
+	if strings.Contains(requests[1], "offset") {
+		t.Errorf("Poller.Poll() after a failed batch sent %q, want no offset", requests[1])
+	}
+}
+
+func TestPollerStop(t *testing.T) {
+	server, adapter := newFakeTelegramServer(t)
+	defer server.Close()
+	handled := make(chan []*chat.Event, 1)
+	poller := NewPoller(adapter, func(events []*chat.Event) error {
+		handled <- events
+		return nil
+	})
+	server.updates <- []Update{{UpdateID: 1, Message: &Message{Chat: Chat{ID: 1, Type: "private"}, Text: "line"}}}
+	poller.Start()
+	if events := <-handled; len(events) != 1 {
+		t.Errorf("Poller handled %+v, want the message", events)
+	}
+	poller.Stop()
+}
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"errors"
+	"sync"
+)
+
+var errCallFailed = errors.New("call failed")
+
+type flight struct {
+	done    chan struct{}
+	res     interface{}
+	err     error
+	waiters int
+}
+
+// flightGroup shares the result of a call among callers making the same call
+// at the same time.
+type flightGroup struct {
+	flights    map[string]*flight
+	flightsMux sync.Mutex
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{
+		flights: map[string]*flight{},
+	}
+}
+
+// do runs the call for a key unless one is already running, in which case it
+// waits for that call's result. shared reports whether the result came from
+// another caller's call.
+func (this *flightGroup) do(key string, run func() (interface{}, error)) (res interface{}, err error, shared bool) {
+	this.flightsMux.Lock()
+	if running, ok := this.flights[key]; ok {
+		running.waiters++
+		this.flightsMux.Unlock()
+		<-running.done
+		return running.res, running.err, true
+	}
+	call := &flight{done: make(chan struct{})}
+	this.flights[key] = call
+	this.flightsMux.Unlock()
+	defer func() {
+		this.flightsMux.Lock()
+		delete(this.flights, key)
+		this.flightsMux.Unlock()
+		close(call.done)
+	}()
+	// Waiters get an error if run panics
+	call.err = errCallFailed
+	call.res, call.err = run()
+	return call.res, call.err, false
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestFlightGroup(t *testing.T) {
+	group := newFlightGroup()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	calls := 0
+	var leaderRes interface{}
+	var leaderShared bool
+	done := make(chan struct{})
+	go func() {
+		leaderRes, _, leaderShared = group.do("line", func() (interface{}, error) {
+			calls++
+			close(started)
+			<-release
+			return "curve", nil
+		})
+		close(done)
+	}()
+	<-started
+	wg := sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err, shared := group.do("line", func() (interface{}, error) {
+				calls++
+				return "other", nil
+			})
+			if res != "curve" || err != nil || !shared {
+				t.Errorf("flightGroup.do() while running == %v, %v, %v, want %q, nil, true", res, err, shared, "curve")
+			}
+		}()
+	}
+	// Let the waiters join before the call finishes
+	for {
+		group.flightsMux.Lock()
+		waiters := group.flights["line"].waiters
+		group.flightsMux.Unlock()
+		if waiters == 3 {
+			break
+		}
+		runtime.Gosched()
+	}
+	close(release)
+	wg.Wait()
+	<-done
+	if leaderRes != "curve" || leaderShared || calls != 1 {
+		t.Errorf("flightGroup.do() == %v, shared %v after %d calls", leaderRes, leaderShared, calls)
+	}
+
+	wantErr := errors.New("DummyError")
+	if _, err, shared := group.do("line", func() (interface{}, error) { return nil, wantErr }); err != wantErr || shared {
+		t.Errorf("flightGroup.do() == %v, %v, want %v, false", err, shared, wantErr)
+	}
+	func() {
+		defer func() { recover() }()
+		group.do("panic", func() (interface{}, error) { panic("boom") })
+	}()
+	if len(group.flights) != 0 {
+		t.Errorf("flightGroup kept %d finished calls", len(group.flights))
+	}
+}
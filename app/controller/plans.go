@@ -0,0 +1,229 @@
+package controller
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/i18n"
+	"github.com/choobot/choo-dict-bot/app/scheduler"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+const (
+	TierFree    = "free"
+	TierPremium = "premium"
+	TierStaff   = "staff"
+)
+
+var ErrUnknownTier = errors.New("unknown tier")
+
+// Tier is a plan's allowance of requests. A limit of 0 means no limit.
+type Tier struct {
+	Name    string `json:"name"`
+	PerHour int    `json:"per_hour"`
+	PerDay  int    `json:"per_day"`
+}
+
+// DefaultTiers are ordered from the lowest tier to the highest. Users
+// without a tier are on the first one.
+var DefaultTiers = []Tier{
+	{Name: TierFree, PerHour: 30, PerDay: 100},
+	{Name: TierPremium, PerHour: 300, PerDay: 1000},
+	{Name: TierStaff},
+}
+
+// Allowance is what is left of a user's plan. A count left of -1 means no
+// limit.
+type Allowance struct {
+	Tier      Tier      `json:"tier"`
+	HourLeft  int       `json:"hour_left"`
+	DayLeft   int       `json:"day_left"`
+	HourReset time.Time `json:"hour_reset"`
+	DayReset  time.Time `json:"day_reset"`
+}
+
+type userUsage struct {
+	hour      time.Time
+	day       time.Time
+	hourCount int
+	dayCount  int
+}
+
+// Plans gives each user an hourly and daily allowance of requests by tier.
+// Tiers are assigned to users or to groups and rooms, and a request made in
+// a group or room gets the higher tier of the user's and the chat's. Periods
+// follow the clock in UTC.
+type Plans struct {
+	Store    store.TierStore
+	Tiers    []Tier
+	Clock    scheduler.Clock
+	exempt   map[string]bool
+	usage    map[string]*userUsage
+	today    time.Time
+	plansMux sync.Mutex
+}
+
+func NewPlans(tierStore store.TierStore, clock scheduler.Clock) *Plans {
+	return &Plans{
+		Store:  tierStore,
+		Tiers:  DefaultTiers,
+		Clock:  clock,
+		exempt: map[string]bool{},
+		usage:  map[string]*userUsage{},
+	}
+}
+
+// Exempt lets the requests of an ID through without counting them, such as
+// those the bot makes for itself.
+func (this *Plans) Exempt(id string) {
+	this.plansMux.Lock()
+	defer this.plansMux.Unlock()
+	this.exempt[id] = true
+}
+
+// Tier returns the tier of a user's requests in a chat, or in a 1:1 chat
+// when chatID is "".
+func (this *Plans) Tier(userID string, chatID string) Tier {
+	ids := []string{userID}
+	if chatID != "" {
+		ids = append(ids, chatID)
+	}
+	best := 0
+	for _, id := range ids {
+		name, err := this.Store.Get(id)
+		if err != nil {
+			continue
+		}
+		if rank := this.rank(name); rank > best {
+			best = rank
+		}
+	}
+	return this.Tiers[best]
+}
+
+// Assign puts a user, group or room on a tier.
+func (this *Plans) Assign(id string, tier string) error {
+	if this.rank(tier) < 0 {
+		return ErrUnknownTier
+	}
+	return this.Store.Set(id, tier)
+}
+
+func (this *Plans) Unassign(id string) error {
+	return this.Store.Remove(id)
+}
+
+func (this *Plans) Assignments() (map[string]string, error) {
+	return this.Store.List()
+}
+
+// Take counts a request made in a chat against a user's allowance, or fails
+// when the allowance is used up.
+func (this *Plans) Take(userID string, chatID string) error {
+	this.plansMux.Lock()
+	exempt := this.exempt[userID]
+	this.plansMux.Unlock()
+	if exempt {
+		return nil
+	}
+	tier := this.Tier(userID, chatID)
+	this.plansMux.Lock()
+	defer this.plansMux.Unlock()
+	usage := this.current(userID)
+	if used(usage.hourCount, tier.PerHour) || used(usage.dayCount, tier.PerDay) {
+		return &i18n.Error{Code: i18n.UserQuotaReached, Err: ErrThrottled}
+	}
+	usage.hourCount++
+	usage.dayCount++
+	return nil
+}
+
+// Refund gives back a request that failed for reasons of our own.
+func (this *Plans) Refund(userID string) {
+	this.plansMux.Lock()
+	defer this.plansMux.Unlock()
+	if usage, ok := this.usage[userID]; ok {
+		if usage.hourCount > 0 {
+			usage.hourCount--
+		}
+		if usage.dayCount > 0 {
+			usage.dayCount--
+		}
+	}
+}
+
+// Allowance returns what is left of a user's plan for requests in a chat.
+func (this *Plans) Allowance(userID string, chatID string) Allowance {
+	tier := this.Tier(userID, chatID)
+	this.plansMux.Lock()
+	defer this.plansMux.Unlock()
+	usage := this.current(userID)
+	return Allowance{
+		Tier:      tier,
+		HourLeft:  left(usage.hourCount, tier.PerHour),
+		DayLeft:   left(usage.dayCount, tier.PerDay),
+		HourReset: usage.hour.Add(time.Hour),
+		DayReset:  usage.day.AddDate(0, 0, 1),
+	}
+}
+
+// current returns a user's usage in the current hour and day, forgetting
+// users who haven't made a request today once a day. The caller must hold
+// plansMux.
+func (this *Plans) current(userID string) *userUsage {
+	now := this.Clock.Now().UTC()
+	hour, day := now.Truncate(time.Hour), time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if !this.today.Equal(day) {
+		for id, usage := range this.usage {
+			if !usage.day.Equal(day) {
+				delete(this.usage, id)
+			}
+		}
+		this.today = day
+	}
+	usage, ok := this.usage[userID]
+	if !ok {
+		usage = &userUsage{hour: hour, day: day}
+		this.usage[userID] = usage
+	}
+	if !usage.hour.Equal(hour) {
+		usage.hour, usage.hourCount = hour, 0
+	}
+	if !usage.day.Equal(day) {
+		usage.day, usage.dayCount = day, 0
+	}
+	return usage
+}
+
+func (this *Plans) rank(name string) int {
+	for i, tier := range this.Tiers {
+		if tier.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Names returns the names of the tiers from the lowest to the highest.
+func (this *Plans) Names() []string {
+	names := []string{}
+	for _, tier := range this.Tiers {
+		names = append(names, tier.Name)
+	}
+	return names
+}
+
+func used(count int, limit int) bool {
+	return limit > 0 && count >= limit
+}
+
+func left(count int, limit int) int {
+	if limit <= 0 {
+		return -1
+	}
+	if count >= limit {
+		return 0
+	}
+	return limit - count
+}
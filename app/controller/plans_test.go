@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/i18n"
+	"github.com/choobot/choo-dict-bot/app/scheduler"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+func TestPlans(t *testing.T) {
+	clock := scheduler.NewFakeClock(time.Date(2018, 11, 20, 23, 30, 0, 0, time.UTC))
+	plans := NewPlans(store.NewMemoryTierStore(), clock)
+	plans.Tiers = []Tier{{Name: TierFree, PerHour: 2, PerDay: 3}, {Name: TierPremium, PerHour: 5}, {Name: TierStaff}}
+
+	if err := plans.Assign("user2", "gold"); err != ErrUnknownTier {
+		t.Errorf("Plans.Assign(%q) == %v, want %v", "gold", err, ErrUnknownTier)
+	}
+	plans.Assign("user2", TierPremium)
+	plans.Assign("group1", TierStaff)
+	cases := []struct {
+		userID string
+		chatID string
+		want   string
+	}{
+		{"user1", "", TierFree},
+		{"user2", "", TierPremium},
+		{"user3", "group1", TierStaff},
+		{"user3", "group2", TierFree},
+		// A group's tier doesn't follow its members to other chats
+		{"user3", "", TierFree},
+		{"user2", "group2", TierPremium},
+	}
+	for _, c := range cases {
+		if got := plans.Tier(c.userID, c.chatID); got.Name != c.want {
+			t.Errorf("Plans.Tier(%q, %q) == %q, want %q", c.userID, c.chatID, got.Name, c.want)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := plans.Take("user1", ""); err != nil {
+			t.Errorf("Plans.Take() %d == %v, want %v", i+1, err, nil)
+		}
+	}
+	err := plans.Take("user1", "")
+	var coded *i18n.Error
+	if !errors.As(err, &coded) || coded.Code != i18n.UserQuotaReached || !errors.Is(err, ErrThrottled) {
+		t.Errorf("Plans.Take() over the hourly limit == %v, want a %q error", err, i18n.UserQuotaReached)
+	}
+	plans.Refund("user1")
+	if err := plans.Take("user1", ""); err != nil {
+		t.Errorf("Plans.Take() after a refund == %v, want %v", err, nil)
+	}
+	clock.Advance(15 * time.Minute)
+	want := Allowance{Tier: plans.Tiers[0], HourLeft: 0, DayLeft: 1, HourReset: time.Date(2018, 11, 21, 0, 0, 0, 0, time.UTC), DayReset: time.Date(2018, 11, 21, 0, 0, 0, 0, time.UTC)}
+	if got := plans.Allowance("user1", ""); got != want {
+		t.Errorf("Plans.Allowance() == %+v, want %+v", got, want)
+	}
+	clock.Advance(15 * time.Minute)
+	if got := plans.Allowance("user1", ""); got.HourLeft != 2 || got.DayLeft != 3 {
+		t.Errorf("Plans.Allowance() on the next day == %+v", got)
+	}
+	if got := plans.Allowance("user3", "group1"); got.HourLeft != -1 || got.DayLeft != -1 {
+		t.Errorf("Plans.Allowance() in a staff group == %+v, want no limits", got)
+	}
+	if got := plans.Allowance("user3", ""); got.HourLeft != 2 || got.DayLeft != 3 {
+		t.Errorf("Plans.Allowance() of a staff group member in a 1:1 chat == %+v", got)
+	}
+
+	plans.Exempt("bot")
+	for i := 0; i < 5; i++ {
+		if err := plans.Take("bot", ""); err != nil {
+			t.Errorf("Plans.Take() of an exempt ID == %v, want %v", err, nil)
+		}
+	}
+}
+
+func TestServiceControllerPlans(t *testing.T) {
+	clock := scheduler.NewFakeClock(time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC))
+	serviceController := NewServiceController(&mockDictService{}, 600)
+	defer serviceController.Close()
+	serviceController.Plans = NewPlans(store.NewMemoryTierStore(), clock)
+	serviceController.Plans.Tiers = []Tier{{Name: TierFree, PerDay: 2}, {Name: TierStaff}}
+	serviceController.Plans.Assign("group1", TierStaff)
+	serviceController.FindAntonyms("dummy_user", "", "missing_word")
+	// Failures of our own don't use up the allowance
+	serviceController.FindAntonyms("dummy_user", "", "error_word")
+	if _, _, err := serviceController.FindDefinitionsAndSynonyms("dummy_user", "", "line"); err != nil {
+		t.Errorf("ServiceController.FindDefinitionsAndSynonyms() within the allowance == %v, want %v", err, nil)
+	}
+	_, err := serviceController.FindExamples("dummy_user", "", "line")
+	var coded *i18n.Error
+	if !errors.As(err, &coded) || coded.Code != i18n.UserQuotaReached {
+		t.Errorf("ServiceController.FindExamples() over the allowance == %v, want a %q error", err, i18n.UserQuotaReached)
+	}
+	if _, err := serviceController.FindExamples("dummy_user2", "", "line"); err != nil {
+		t.Errorf("ServiceController.FindExamples() of another user == %v, want %v", err, nil)
+	}
+	if _, err := serviceController.FindExamples("dummy_user", "group1", "line"); err != nil {
+		t.Errorf("ServiceController.FindExamples() in a staff group == %v, want %v", err, nil)
+	}
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"log"
+	"sync"
+)
+
+type result struct {
+	res interface{}
+	err error
+}
+
+type job struct {
+	run  func() (interface{}, error)
+	done chan result
+}
+
+// workerPool runs calls on a fixed number of workers, so that no more than
+// that many calls are made to the dictionary service at once.
+type workerPool struct {
+	jobs      chan job
+	closeOnce sync.Once
+}
+
+func newWorkerPool(workers int, queue int) *workerPool {
+	pool := &workerPool{
+		jobs: make(chan job, queue),
+	}
+	for i := 0; i < workers; i++ {
+		go pool.work()
+	}
+	return pool
+}
+
+// submit queues a call, waiting while the queue is full. The returned
+// channel is buffered so that a worker never waits for a caller that has
+// stopped listening.
+func (this *workerPool) submit(run func() (interface{}, error)) <-chan result {
+	done := make(chan result, 1)
+	this.jobs <- job{run: run, done: done}
+	return done
+}
+
+// close stops the workers once the queued calls are done.
+func (this *workerPool) close() {
+	this.closeOnce.Do(func() {
+		close(this.jobs)
+	})
+}
+
+func (this *workerPool) work() {
+	for job := range this.jobs {
+		job.done <- this.run(job.run)
+	}
+}
+
+func (this *workerPool) run(run func() (interface{}, error)) (res result) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("dictionary service call panicked:", r)
+			res = result{err: errCallFailed}
+		}
+	}()
+	res.res, res.err = run()
+	return res
+}
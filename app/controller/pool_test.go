@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPool(t *testing.T) {
+	pool := newWorkerPool(2, 0)
+	defer pool.close()
+	var running, maxRunning int32
+	results := []<-chan result{}
+	submitted := make(chan struct{})
+	go func() {
+		for i := 0; i < 6; i++ {
+			i := i
+			results = append(results, pool.submit(func() (interface{}, error) {
+				now := atomic.AddInt32(&running, 1)
+				for {
+					max := atomic.LoadInt32(&maxRunning)
+					if now <= max || atomic.CompareAndSwapInt32(&maxRunning, max, now) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				return i, nil
+			}))
+		}
+		close(submitted)
+	}()
+	<-submitted
+	for i, done := range results {
+		if res := <-done; res.res != i || res.err != nil {
+			t.Errorf("workerPool call %d == %v, %v, want %d", i, res.res, res.err, i)
+		}
+	}
+	if max := atomic.LoadInt32(&maxRunning); max > 2 {
+		t.Errorf("workerPool with 2 workers ran %d calls at once", max)
+	}
+
+	res := <-pool.submit(func() (interface{}, error) {
+		panic("boom")
+	})
+	if res.err != errCallFailed {
+		t.Errorf("workerPool call that panicked == %v, want %v", res.err, errCallFailed)
+	}
+
+	// Results wait for callers that have stopped listening
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	pool.submit(func() (interface{}, error) {
+		defer wg.Done()
+		return nil, nil
+	})
+	wg.Wait()
+	if res := <-pool.submit(func() (interface{}, error) { return "next", nil }); res.res != "next" {
+		t.Errorf("workerPool call after an abandoned call == %v, want %q", res.res, "next")
+	}
+}
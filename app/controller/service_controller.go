@@ -2,113 +2,284 @@ package controller
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/choobot/choo-dict-bot/app/i18n"
+	"github.com/choobot/choo-dict-bot/app/scheduler"
 	"github.com/choobot/choo-dict-bot/app/service"
 )
 
+// ServiceController makes dictionary requests for users. chatID is the group
+// or room a request is made in, or "" for a 1:1 chat, and the chat's tier
+// applies to the request.
 type ServiceController interface {
-	FindDefinitionsAndSynonyms(userID string, word string) (string, string, error)
+	FindDefinitionsAndSynonyms(userID string, chatID string, word string) (string, string, error)
+	FindSynonyms(userID string, chatID string, word string) (string, error)
+	FindAntonyms(userID string, chatID string, word string) (string, error)
+	FindExamples(userID string, chatID string, word string) (string, error)
+	FindPronunciations(userID string, chatID string, word string) (string, error)
+	Lookup(userID string, chatID string, word string, options service.LookupOptions) (service.Entry, error)
 }
 
+// ErrThrottled is wrapped by the errors of requests refused by the
+// controller's own limits. A refusal by the dictionary service wraps
+// service.ErrRateLimited instead.
+var ErrThrottled = errors.New("throttled")
+
+const (
+	// maxConcurrentCalls bounds the calls made to the dictionary service at
+	// once, and maxQueuedCalls the calls waiting for a worker before callers
+	// have to wait to queue theirs.
+	maxConcurrentCalls = 8
+	maxQueuedCalls     = 64
+)
+
 type DictServiceController struct {
-	dictService     service.DictService
-	userProgress    map[string]int
-	concurrent      int
-	maxPerMinute    int
-	concurrentMux   sync.Mutex
-	userProgressMux sync.Mutex
-}
-
-func (this *DictServiceController) FindDefinitionsAndSynonyms(userID string, word string) (string, string, error) {
-	if this.concurrent >= this.maxPerMinute {
-		return "", "", errors.New("Sorry, we've reached the number of requests limit, please wait for 1 minute and try again.")
-	}
-	this.userProgressMux.Lock()
-	progress := this.userProgress[userID]
-	this.userProgressMux.Unlock()
-	if progress > 0 {
-		return "", "", errors.New("You're too fast, please slow down.")
-	} else {
-		this.concurrentMux.Lock()
-		this.concurrent++
-		this.concurrentMux.Unlock()
-		this.userProgressMux.Lock()
-		this.userProgress[userID] = 2
-		this.userProgressMux.Unlock()
-		word = strings.Split(word, " ")[0]
-		definistionsCh := make(chan string)
-		synonymsCh := make(chan string)
-		errorCh := make(chan error)
-		go func() {
-			time.Sleep(time.Duration(60000/this.maxPerMinute) * time.Millisecond)
-			res, err := this.dictService.FindDefinitions(word)
-			if err != nil {
-				errorCh <- err
-			} else {
-				definistionsCh <- res
-			}
+	// Plans limits each user's requests by tier, when set
+	Plans          *Plans
+	dictService    service.DictService
+	clock          scheduler.Clock
+	maxPerMinute   int
+	requests       int
+	windowStart    time.Time
+	inFlight       map[string]bool
+	errorCounts    map[string]int
+	flights        *flightGroup
+	pool           *workerPool
+	requestsMux    sync.Mutex
+	inFlightMux    sync.Mutex
+	errorCountsMux sync.Mutex
+}
 
-		}()
-		go func() {
-			time.Sleep(time.Duration(60000/this.maxPerMinute) * time.Millisecond)
-			res, err := this.dictService.FindSynonyms(word)
-			if err != nil {
-				errorCh <- err
-			} else {
-				synonymsCh <- res
-			}
+func (this *DictServiceController) FindDefinitionsAndSynonyms(userID string, chatID string, word string) (string, string, error) {
+	word = strings.Split(word, " ")[0]
+	res, err := this.limit(userID, chatID, func() (interface{}, error) {
+		definitionsCh := this.call("definitions "+word, 1, func() (interface{}, error) {
+			return this.dictService.FindDefinitions(word)
+		})
+		synonymsCh := this.call("synonyms "+word, 1, func() (interface{}, error) {
+			return this.dictService.FindSynonyms(word)
+		})
+		definitions, synonyms := <-definitionsCh, <-synonymsCh
+		if definitions.err != nil {
+			return nil, definitions.err
+		}
+		if errors.Is(synonyms.err, service.ErrNotFound) {
+			synonyms.res = ""
+		} else if synonyms.err != nil {
+			return nil, synonyms.err
+		}
+		return [2]string{definitions.res.(string), synonyms.res.(string)}, nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+	pair := res.([2]string)
+	return pair[0], pair[1], nil
+}
 
-		}()
-		definistions, synonyms := "", ""
-	Loop:
-		for {
-			select {
-			case definistions = <-definistionsCh:
-				this.userProgressMux.Lock()
-				this.userProgress[userID]--
-				userProgress := this.userProgress[userID]
-				this.userProgressMux.Unlock()
-				if userProgress == 0 {
-					break Loop
-				}
-			case synonyms = <-synonymsCh:
-				this.userProgressMux.Lock()
-				this.userProgress[userID]--
-				userProgress := this.userProgress[userID]
-				this.userProgressMux.Unlock()
-				if userProgress == 0 {
-					break Loop
-				}
-
-			case error := <-errorCh:
-				this.userProgressMux.Lock()
-				this.userProgress[userID] = 0
-				this.userProgressMux.Unlock()
-				return "", "", errors.New("There was error on DictService: " + error.Error())
-			}
+func (this *DictServiceController) FindSynonyms(userID string, chatID string, word string) (string, error) {
+	return this.find(userID, chatID, "synonyms", word, this.dictService.FindSynonyms)
+}
+
+func (this *DictServiceController) FindAntonyms(userID string, chatID string, word string) (string, error) {
+	return this.find(userID, chatID, "antonyms", word, this.dictService.FindAntonyms)
+}
+
+func (this *DictServiceController) FindExamples(userID string, chatID string, word string) (string, error) {
+	return this.find(userID, chatID, "examples", word, this.dictService.FindExamples)
+}
+
+func (this *DictServiceController) FindPronunciations(userID string, chatID string, word string) (string, error) {
+	return this.find(userID, chatID, "pronunciations", word, this.dictService.FindPronunciations)
+}
+
+func (this *DictServiceController) find(userID string, chatID string, endpoint string, word string, find func(word string) (string, error)) (string, error) {
+	word = strings.Split(word, " ")[0]
+	res, err := this.limit(userID, chatID, func() (interface{}, error) {
+		result := <-this.call(endpoint+" "+word, 1, func() (interface{}, error) {
+			return find(word)
+		})
+		return result.res, result.err
+	})
+	if err != nil {
+		return "", err
+	}
+	return res.(string), nil
+}
+
+func (this *DictServiceController) Lookup(userID string, chatID string, word string, options service.LookupOptions) (service.Entry, error) {
+	word = strings.Split(word, " ")[0]
+	res, err := this.limit(userID, chatID, func() (interface{}, error) {
+		result := <-this.call(fmt.Sprintf("lookup %s %+v", word, options), options.Calls(), func() (interface{}, error) {
+			return this.dictService.Lookup(word, options)
+		})
+		return result.res, result.err
+	})
+	if err != nil {
+		return service.Entry{}, err
+	}
+	return res.(service.Entry), nil
+}
+
+// limit runs one user request within the global and per-user limits. A user
+// has at most one request in flight, and failed requests other than for
+// unknown words don't count against their plan.
+func (this *DictServiceController) limit(userID string, chatID string, run func() (interface{}, error)) (interface{}, error) {
+	if err := this.unavailable(); err != nil {
+		return nil, err
+	}
+	if this.limited() {
+		return nil, limitError(i18n.RequestLimit)
+	}
+	if !this.acquire(userID) {
+		return nil, limitError(i18n.TooFast)
+	}
+	defer this.release(userID)
+	if this.Plans != nil {
+		if err := this.Plans.Take(userID, chatID); err != nil {
+			return nil, err
 		}
-		return definistions, synonyms, nil
 	}
+	res, err := run()
+	if this.Plans != nil && err != nil && !errors.Is(err, service.ErrNotFound) {
+		this.Plans.Refund(userID)
+	}
+	var coded *i18n.Error
+	if errors.As(err, &coded) {
+		return nil, err
+	} else if err != nil {
+		return nil, this.serviceError(err)
+	}
+	return res, nil
+}
+
+// call makes a call to the dictionary service on the worker pool, or waits
+// for the same call made by another user. Only calls that reach the service
+// count against the global limit, so a shared result is counted once. cost
+// is the number of API calls it makes.
+func (this *DictServiceController) call(key string, cost int, run func() (interface{}, error)) <-chan result {
+	done := make(chan result, 1)
+	go func() {
+		res, err, _ := this.flights.do(key, func() (interface{}, error) {
+			if !this.take(cost) {
+				return nil, limitError(i18n.RequestLimit)
+			}
+			result := <-this.pool.submit(run)
+			return result.res, result.err
+		})
+		done <- result{res: res, err: err}
+	}()
+	return done
+}
+
+// limited reports whether the global limit for this minute has been reached.
+func (this *DictServiceController) limited() bool {
+	this.requestsMux.Lock()
+	defer this.requestsMux.Unlock()
+	this.roll()
+	return this.requests >= this.maxPerMinute
+}
+
+// take counts n requests against the global limit, or reports false if
+// they would go over it.
+func (this *DictServiceController) take(n int) bool {
+	this.requestsMux.Lock()
+	defer this.requestsMux.Unlock()
+	this.roll()
+	if this.requests+n > this.maxPerMinute {
+		return false
+	}
+	this.requests += n
+	return true
+}
+
+// roll starts a new minute once the current one is over. The caller must
+// hold requestsMux.
+func (this *DictServiceController) roll() {
+	if now := this.clock.Now(); now.Sub(this.windowStart) >= time.Minute {
+		this.windowStart, this.requests = now, 0
+	}
+}
+
+func (this *DictServiceController) acquire(userID string) bool {
+	this.inFlightMux.Lock()
+	defer this.inFlightMux.Unlock()
+	if this.inFlight[userID] {
+		return false
+	}
+	this.inFlight[userID] = true
+	return true
+}
+
+func (this *DictServiceController) release(userID string) {
+	this.inFlightMux.Lock()
+	defer this.inFlightMux.Unlock()
+	delete(this.inFlight, userID)
+}
+
+// serviceError counts a dictionary service error and turns it into one users
+// can read. The service error can still be matched with errors.Is.
+func (this *DictServiceController) serviceError(err error) error {
+	this.errorCountsMux.Lock()
+	this.errorCounts[service.Category(err)]++
+	this.errorCountsMux.Unlock()
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		return err
+	case errors.Is(err, service.ErrRateLimited):
+		return &i18n.Error{Code: i18n.ServiceBusy, Err: err}
+	case errors.Is(err, service.ErrQuotaExceeded):
+		return &i18n.Error{Code: i18n.QuotaExhausted, Err: err}
+	case errors.Is(err, service.ErrUnavailable):
+		return &i18n.Error{Code: i18n.ServiceUnavailable, Err: err}
+	}
+	return &i18n.Error{Code: i18n.ServiceError, Args: []interface{}{err.Error()}, Err: err}
+}
+
+// unavailable fails fast, without waiting for a turn, when the dictionary
+// service knows it is down.
+func (this *DictServiceController) unavailable() error {
+	if breaker, ok := this.dictService.(interface{ Available() bool }); ok && !breaker.Available() {
+		return this.serviceError(&service.Error{Kind: service.ErrUnavailable})
+	}
+	return nil
+}
+
+// ErrorCounts returns the number of dictionary service errors by category.
+func (this *DictServiceController) ErrorCounts() map[string]int {
+	this.errorCountsMux.Lock()
+	defer this.errorCountsMux.Unlock()
+	counts := map[string]int{}
+	for category, count := range this.errorCounts {
+		counts[category] = count
+	}
+	return counts
+}
+
+// limitError is returned when a request is refused by the controller's own
+// limits rather than by the dictionary service.
+func limitError(code i18n.Code) error {
+	return &i18n.Error{Code: code, Err: ErrThrottled}
 }
 
 func NewServiceController(dictService service.DictService, maxPerMinute int) *DictServiceController {
-	serviceController := DictServiceController{
-		userProgress: map[string]int{},
-		concurrent:   0,
-		maxPerMinute: maxPerMinute,
+	return &DictServiceController{
 		dictService:  dictService,
+		clock:        scheduler.RealClock{},
+		maxPerMinute: maxPerMinute,
+		inFlight:     map[string]bool{},
+		errorCounts:  map[string]int{},
+		flights:      newFlightGroup(),
+		pool:         newWorkerPool(maxConcurrentCalls, maxQueuedCalls),
 	}
-	limiter := time.Tick(time.Minute)
-	go func() {
-		for {
-			<-limiter
-			serviceController.concurrentMux.Lock()
-			serviceController.concurrent = 0
-			serviceController.concurrentMux.Unlock()
-		}
-	}()
-	return &serviceController
+}
+
+// Close stops the workers once the calls already made are done. The
+// controller can't be used after it is closed.
+func (this *DictServiceController) Close() {
+	this.pool.close()
 }
@@ -2,9 +2,17 @@ package controller
 
 import (
 	"errors"
+	"reflect"
+	"runtime"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/choobot/choo-dict-bot/app/i18n"
+	"github.com/choobot/choo-dict-bot/app/scheduler"
+	"github.com/choobot/choo-dict-bot/app/service"
 )
 
 type mockDictService struct {
@@ -26,6 +34,41 @@ func (this *mockDictService) FindSynonyms(word string) (string, error) {
 	}
 	return "bar, dash, rule, score and underline", nil
 }
+func (this *mockDictService) FindAntonyms(word string) (string, error) {
+	if word == "error_word" {
+		return "", errors.New("DummyError")
+	} else if word == "missing_word" {
+		return "", &service.Error{Kind: service.ErrNotFound, StatusCode: 404}
+	} else if word == "busy_word" {
+		return "", &service.Error{Kind: service.ErrRateLimited, StatusCode: 429}
+	} else if word == "exhausted_word" {
+		return "", &service.Error{Kind: service.ErrQuotaExceeded}
+	}
+	return "curve", nil
+}
+func (this *mockDictService) FindExamples(word string) (string, error) {
+	if word == "error_word" {
+		return "", errors.New("DummyError")
+	}
+	return "- draw a line", nil
+}
+func (this *mockDictService) FindPronunciations(word string) (string, error) {
+	if word == "error_word" {
+		return "", errors.New("DummyError")
+	}
+	return "/lʌɪn/", nil
+}
+
+func (this *mockDictService) Lookup(word string, options service.LookupOptions) (service.Entry, error) {
+	if word == "error_word" {
+		return service.Entry{}, errors.New("DummyError")
+	}
+	entry := service.Entry{Word: word, Senses: []service.Sense{{Definition: "a long, narrow mark or band"}}}
+	if options.TranslateTo != "" {
+		entry.Translations = []string{"línea"}
+	}
+	return entry, nil
+}
 
 func TestServiceControllerFindDefinitionsAndSynonyms(t *testing.T) {
 	dictService := &mockDictService{}
@@ -34,7 +77,7 @@ func TestServiceControllerFindDefinitionsAndSynonyms(t *testing.T) {
 	wantDefinistions := "a long, narrow mark or band"
 	wantSynonyms := "bar, dash, rule, score and underline"
 	go func() {
-		definistions, synonyms, err := serviceController.FindDefinitionsAndSynonyms("dummy_user", word)
+		definistions, synonyms, err := serviceController.FindDefinitionsAndSynonyms("dummy_user", "", word)
 		if definistions != wantDefinistions || synonyms != wantSynonyms {
 			t.Errorf("ServiceController.FindDefinitionsAndSynonyms(%q, %q) == %q, %q %q, want %q, %q", "dummy_user", word, definistions, synonyms, err, wantDefinistions, wantSynonyms)
 		}
@@ -42,7 +85,7 @@ func TestServiceControllerFindDefinitionsAndSynonyms(t *testing.T) {
 
 	// Multiple user at the time
 	go func() {
-		definistions, synonyms, err := serviceController.FindDefinitionsAndSynonyms("dummy_user2", word)
+		definistions, synonyms, err := serviceController.FindDefinitionsAndSynonyms("dummy_user2", "", word)
 		if definistions != wantDefinistions || synonyms != wantSynonyms {
 			t.Errorf("ServiceController.FindDefinitionsAndSynonyms(%q, %q) == %q, %q %q, want %q, %q", "dummy_user", word, definistions, synonyms, err, wantDefinistions, wantSynonyms)
 		}
@@ -51,14 +94,14 @@ func TestServiceControllerFindDefinitionsAndSynonyms(t *testing.T) {
 	// Same user at the time
 	time.Sleep(5 * time.Millisecond)
 	wantErr := "You're too fast, please slow down."
-	definistions, synonyms, err := serviceController.FindDefinitionsAndSynonyms("dummy_user", word)
+	definistions, synonyms, err := serviceController.FindDefinitionsAndSynonyms("dummy_user", "", word)
 	if err == nil || err.Error() != wantErr {
 		t.Errorf("ServiceController.FindDefinitionsAndSynonyms(%q, %q) == %q, %q %q, want %q", "dummy_user", word, definistions, synonyms, err, wantErr)
 	}
 
 	//Same user after previous result
 	time.Sleep(2 * time.Second)
-	definistions, synonyms, err = serviceController.FindDefinitionsAndSynonyms("dummy_user", word)
+	definistions, synonyms, err = serviceController.FindDefinitionsAndSynonyms("dummy_user", "", word)
 	if definistions != wantDefinistions || synonyms != wantSynonyms {
 		t.Errorf("ServiceController.FindDefinitionsAndSynonyms(%q, %q) == %q, %q %q, want %q, %q", "dummy_user", word, definistions, synonyms, err, wantDefinistions, wantSynonyms)
 	}
@@ -66,7 +109,7 @@ func TestServiceControllerFindDefinitionsAndSynonyms(t *testing.T) {
 	// Some error on Dict API
 	word = "error_word"
 	wantErr = "There was error on DictService: DummyError"
-	definistions, synonyms, err = serviceController.FindDefinitionsAndSynonyms("dummy_user3", word)
+	definistions, synonyms, err = serviceController.FindDefinitionsAndSynonyms("dummy_user3", "", word)
 	if err == nil || err.Error() != wantErr {
 		t.Errorf("ServiceController.FindDefinitionsAndSynonyms(%q, %q) == %q, %q %q, want %q", "dummy_user", word, definistions, synonyms, err, wantErr)
 	}
@@ -83,7 +126,7 @@ func TestConcurrentLoadServiceControllerFindDefinitionsAndSynonyms(t *testing.T)
 			userID := "user" + strconv.Itoa(i)
 			wantDefinistions := "a long, narrow mark or band"
 			wantSynonyms := "bar, dash, rule, score and underline"
-			definistions, synonyms, err := serviceController.FindDefinitionsAndSynonyms(userID, word)
+			definistions, synonyms, err := serviceController.FindDefinitionsAndSynonyms(userID, "", word)
 			if definistions != wantDefinistions || synonyms != wantSynonyms || err != nil {
 				t.Errorf("ServiceController.FindDefinitionsAndSynonyms(%q, %q) == %q, %q %q, want %q, %q", userID, word, definistions, synonyms, err, wantDefinistions, wantSynonyms)
 			}
@@ -96,12 +139,18 @@ func TestConcurrentLoadServiceControllerFindDefinitionsAndSynonyms(t *testing.T)
 		<-resultCh
 	}
 
+	// Lookups of the same word share their calls and are counted once, so use
+	// up the rest of the limit with other words
+	for i := 0; i < concurrent; i++ {
+		serviceController.FindDefinitionsAndSynonyms("other_user"+strconv.Itoa(i), "", "word"+strconv.Itoa(i))
+	}
+
 	// More than limit
 	userID := "dummy_user1"
 	wantDefinistions := ""
 	wantSynonyms := ""
 	wantErr := "Sorry, we've reached the number of requests limit, please wait for 1 minute and try again."
-	definistions, synonyms, err := serviceController.FindDefinitionsAndSynonyms(userID, word)
+	definistions, synonyms, err := serviceController.FindDefinitionsAndSynonyms(userID, "", word)
 	if definistions != wantDefinistions || synonyms != wantSynonyms || err == nil || err.Error() != wantErr {
 		t.Errorf("ServiceController.FindDefinitionsAndSynonyms(%q, %q) == %q, %q %q, want %q, %q %q", userID, word, definistions, synonyms, err, wantDefinistions, wantSynonyms, wantErr)
 	}
@@ -112,8 +161,247 @@ func TestConcurrentLoadServiceControllerFindDefinitionsAndSynonyms(t *testing.T)
 	word = "line"
 	wantDefinistions = "a long, narrow mark or band"
 	wantSynonyms = "bar, dash, rule, score and underline"
-	definistions, synonyms, err = serviceController.FindDefinitionsAndSynonyms(userID, word)
+	definistions, synonyms, err = serviceController.FindDefinitionsAndSynonyms(userID, "", word)
 	if definistions != wantDefinistions || synonyms != wantSynonyms || err != nil {
 		t.Errorf("ServiceController.FindDefinitionsAndSynonyms(%q, %q) == %q, %q %q, want %q, %q", userID, word, definistions, synonyms, err, wantDefinistions, wantSynonyms)
 	}
 }
+
+func TestServiceControllerFindOne(t *testing.T) {
+	dictService := &mockDictService{}
+	serviceController := NewServiceController(dictService, 600)
+	cases := []struct {
+		find func(userID string, chatID string, word string) (string, error)
+		name string
+		word string
+		want string
+		err  string
+	}{
+		{serviceController.FindSynonyms, "FindSynonyms", "line", "bar, dash, rule, score and underline", ""},
+		{serviceController.FindAntonyms, "FindAntonyms", "line", "curve", ""},
+		{serviceController.FindExamples, "FindExamples", "line", "- draw a line", ""},
+		{serviceController.FindPronunciations, "FindPronunciations", "line", "/lʌɪn/", ""},
+		{serviceController.FindAntonyms, "FindAntonyms", "error_word", "", "There was error on DictService: DummyError"},
+	}
+	for _, c := range cases {
+		got, err := c.find("dummy_user", "", c.word)
+		if got != c.want || (c.err == "" && err != nil) || (c.err != "" && (err == nil || err.Error() != c.err)) {
+			t.Errorf("ServiceController.%s(%q, %q) == %q, %v, want %q, %q", c.name, "dummy_user", c.word, got, err, c.want, c.err)
+		}
+	}
+}
+
+func TestServiceControllerLookup(t *testing.T) {
+	dictService := &mockDictService{}
+	serviceController := NewServiceController(dictService, 600)
+	entry, err := serviceController.Lookup("dummy_user", "", "line of text", service.LookupOptions{Senses: 1, TranslateTo: "es"})
+	if err != nil || entry.Word != "line" || len(entry.Translations) != 1 {
+		t.Errorf("ServiceController.Lookup(%q, %q) == %+v, %v", "dummy_user", "line of text", entry, err)
+	}
+	wantErr := "There was error on DictService: DummyError"
+	if _, err := serviceController.Lookup("dummy_user", "", "error_word", service.DefaultLookupOptions); err == nil || err.Error() != wantErr {
+		t.Errorf("ServiceController.Lookup(%q, %q) == %v, want %q", "dummy_user", "error_word", err, wantErr)
+	}
+	// A lookup is charged for the entry, its synonyms and its translations
+	if requests := serviceController.requests; requests != 5 {
+		t.Errorf("ServiceController.Lookup() counted %d requests, want %d", requests, 5)
+	}
+	serviceController = NewServiceController(dictService, 2)
+	if _, err := serviceController.Lookup("dummy_user", "", "line", service.LookupOptions{Senses: 1, TranslateTo: "es"}); !errors.Is(err, ErrThrottled) {
+		t.Errorf("ServiceController.Lookup() of 3 calls with a limit of 2 == %v, want %v", err, ErrThrottled)
+	}
+	if _, err := serviceController.Lookup("dummy_user", "", "line", service.DefaultLookupOptions); err != nil {
+		t.Errorf("ServiceController.Lookup() of 2 calls with a limit of 2 == %v", err)
+	}
+}
+
+func TestServiceControllerErrors(t *testing.T) {
+	dictService := &mockDictService{}
+	serviceController := NewServiceController(dictService, 600)
+	if _, err := serviceController.FindAntonyms("dummy_user", "", "missing_word"); !errors.Is(err, service.ErrNotFound) {
+		t.Errorf("ServiceController.FindAntonyms(%q) == %v, want %v", "missing_word", err, service.ErrNotFound)
+	}
+	_, err := serviceController.FindAntonyms("dummy_user", "", "busy_word")
+	var coded *i18n.Error
+	if !errors.Is(err, service.ErrRateLimited) || errors.Is(err, ErrThrottled) || !errors.As(err, &coded) || coded.Code != i18n.ServiceBusy {
+		t.Errorf("ServiceController.FindAntonyms(%q) == %v, want a %q error", "busy_word", err, i18n.ServiceBusy)
+	}
+	_, err = serviceController.FindAntonyms("dummy_user", "", "exhausted_word")
+	if !errors.Is(err, service.ErrQuotaExceeded) || !errors.As(err, &coded) || coded.Code != i18n.QuotaExhausted {
+		t.Errorf("ServiceController.FindAntonyms(%q) == %v, want a %q error", "exhausted_word", err, i18n.QuotaExhausted)
+	}
+	serviceController.FindAntonyms("dummy_user", "", "error_word")
+	want := map[string]int{"not_found": 1, "rate_limited": 1, "quota_exceeded": 1, "unknown": 1}
+	if got := serviceController.ErrorCounts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ServiceController.ErrorCounts() == %v, want %v", got, want)
+	}
+	serviceController.acquire("dummy_user")
+	_, err = serviceController.FindAntonyms("dummy_user", "", "line")
+	if !errors.Is(err, ErrThrottled) || errors.Is(err, service.ErrRateLimited) {
+		t.Errorf("ServiceController.FindAntonyms() while busy == %v, want %v", err, ErrThrottled)
+	}
+}
+
+type unavailableDictService struct {
+	mockDictService
+}
+
+func (this *unavailableDictService) Available() bool {
+	return false
+}
+
+func TestServiceControllerUnavailable(t *testing.T) {
+	// One request per minute would wait a minute for its turn
+	serviceController := NewServiceController(&unavailableDictService{}, 1)
+	start := time.Now()
+	_, err := serviceController.FindSynonyms("dummy_user", "", "line")
+	var coded *i18n.Error
+	if !errors.As(err, &coded) || coded.Code != i18n.ServiceUnavailable || !errors.Is(err, service.ErrUnavailable) || time.Since(start) > time.Second {
+		t.Errorf("ServiceController.FindSynonyms() while unavailable == %v after %s", err, time.Since(start))
+	}
+	if _, _, err := serviceController.FindDefinitionsAndSynonyms("dummy_user", "", "line"); !errors.Is(err, service.ErrUnavailable) {
+		t.Errorf("ServiceController.FindDefinitionsAndSynonyms() while unavailable == %v, want %v", err, service.ErrUnavailable)
+	}
+}
+
+type slowDictService struct {
+	mockDictService
+	calls int32
+}
+
+func (this *slowDictService) FindAntonyms(word string) (string, error) {
+	atomic.AddInt32(&this.calls, 1)
+	time.Sleep(50 * time.Millisecond)
+	return this.mockDictService.FindAntonyms(word)
+}
+
+func TestServiceControllerCoalescing(t *testing.T) {
+	dictService := &slowDictService{}
+	serviceController := NewServiceController(dictService, 6000)
+	results := make(chan string, 5)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(userID string) {
+			defer wg.Done()
+			res, err := serviceController.FindAntonyms(userID, "", "line")
+			if err != nil {
+				res = err.Error()
+			}
+			results <- res
+		}("user" + strconv.Itoa(i))
+	}
+	wg.Wait()
+	close(results)
+	for res := range results {
+		if res != "curve" {
+			t.Errorf("ServiceController.FindAntonyms(%q) == %q, want %q", "line", res, "curve")
+		}
+	}
+	if calls := atomic.LoadInt32(&dictService.calls); calls != 1 {
+		t.Errorf("5 concurrent ServiceController.FindAntonyms(%q) called the service %d times, want %d", "line", calls, 1)
+	}
+	serviceController.requestsMux.Lock()
+	requests := serviceController.requests
+	serviceController.requestsMux.Unlock()
+	if requests != 1 {
+		t.Errorf("5 concurrent ServiceController.FindAntonyms(%q) counted %d requests, want %d", "line", requests, 1)
+	}
+	serviceController.FindAntonyms("user1", "", "line")
+	if calls := atomic.LoadInt32(&dictService.calls); calls != 2 {
+		t.Errorf("ServiceController.FindAntonyms(%q) after the first call finished called the service %d times, want %d", "line", calls, 2)
+	}
+}
+
+type pairDictService struct {
+	mockDictService
+	definitionsErr error
+	synonymsErr    error
+}
+
+func (this *pairDictService) FindDefinitions(word string) (string, error) {
+	time.Sleep(5 * time.Millisecond)
+	if this.definitionsErr != nil {
+		return "", this.definitionsErr
+	}
+	return this.mockDictService.FindDefinitions(word)
+}
+
+func (this *pairDictService) FindSynonyms(word string) (string, error) {
+	if this.synonymsErr != nil {
+		return "", this.synonymsErr
+	}
+	return this.mockDictService.FindSynonyms(word)
+}
+
+func TestServiceControllerFindDefinitionsAndSynonymsErrors(t *testing.T) {
+	dummyErr := errors.New("DummyError")
+	notFound := &service.Error{Kind: service.ErrNotFound}
+	cases := []struct {
+		definitionsErr error
+		synonymsErr    error
+		definitions    string
+		synonyms       string
+		err            string
+	}{
+		{nil, nil, "a long, narrow mark or band", "bar, dash, rule, score and underline", ""},
+		{nil, notFound, "a long, narrow mark or band", "", ""},
+		{dummyErr, nil, "", "", "There was error on DictService: DummyError"},
+		{nil, dummyErr, "", "", "There was error on DictService: DummyError"},
+		{dummyErr, errors.New("OtherError"), "", "", "There was error on DictService: DummyError"},
+		{notFound, dummyErr, "", "", "not found"},
+	}
+	goroutines := runtime.NumGoroutine()
+	for _, c := range cases {
+		dictService := &pairDictService{definitionsErr: c.definitionsErr, synonymsErr: c.synonymsErr}
+		serviceController := NewServiceController(dictService, 600)
+		definitions, synonyms, err := serviceController.FindDefinitionsAndSynonyms("dummy_user", "", "line")
+		if definitions != c.definitions || synonyms != c.synonyms || (c.err == "" && err != nil) || (c.err != "" && (err == nil || err.Error() != c.err)) {
+			t.Errorf("ServiceController.FindDefinitionsAndSynonyms() with errors %v, %v == %q, %q, %v, want %q, %q, %q", c.definitionsErr, c.synonymsErr, definitions, synonyms, err, c.definitions, c.synonyms, c.err)
+		}
+		serviceController.inFlightMux.Lock()
+		inFlight := len(serviceController.inFlight)
+		serviceController.inFlightMux.Unlock()
+		if inFlight != 0 {
+			t.Errorf("ServiceController.FindDefinitionsAndSynonyms() with errors %v, %v left %d users in flight", c.definitionsErr, c.synonymsErr, inFlight)
+		}
+		serviceController.Close()
+	}
+	// Nothing is left waiting once the workers have stopped
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := runtime.NumGoroutine(); got > goroutines {
+		t.Errorf("ServiceController.FindDefinitionsAndSynonyms() left %d goroutines running", got-goroutines)
+	}
+}
+
+func TestServiceControllerRequestLimit(t *testing.T) {
+	clock := scheduler.NewFakeClock(time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC))
+	serviceController := NewServiceController(&mockDictService{}, 2)
+	defer serviceController.Close()
+	serviceController.clock = clock
+	start := time.Now()
+	for _, word := range []string{"line", "curve"} {
+		if _, err := serviceController.FindExamples("dummy_user", "", word); err != nil {
+			t.Errorf("ServiceController.FindExamples(%q) == %v, want %v", word, err, nil)
+		}
+	}
+	// An idle controller doesn't wait to make a call
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("2 ServiceController.FindExamples() took %s", elapsed)
+	}
+	_, err := serviceController.FindExamples("dummy_user2", "", "bar")
+	var coded *i18n.Error
+	if !errors.As(err, &coded) || coded.Code != i18n.RequestLimit {
+		t.Errorf("ServiceController.FindExamples() over the limit == %v, want a %q error", err, i18n.RequestLimit)
+	}
+	clock.Advance(time.Minute)
+	if _, err := serviceController.FindExamples("dummy_user2", "", "bar"); err != nil {
+		t.Errorf("ServiceController.FindExamples() in the next minute == %v, want %v", err, nil)
+	}
+	if got := serviceController.ErrorCounts(); len(got) != 0 {
+		t.Errorf("ServiceController.ErrorCounts() after refused requests == %v, want none", got)
+	}
+}
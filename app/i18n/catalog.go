@@ -0,0 +1,707 @@
+package i18n
+
+const (
+	TooFast                 Code = "too_fast"
+	RequestLimit            Code = "request_limit"
+	ServiceError            Code = "service_error"
+	ServiceBusy             Code = "service_busy"
+	ServiceUnavailable      Code = "service_unavailable"
+	QuotaExhausted          Code = "quota_exhausted"
+	UserQuotaReached        Code = "user_quota_reached"
+	QuotaStatus             Code = "quota_status"
+	NoQuota                 Code = "no_quota"
+	Unlimited               Code = "unlimited"
+	NoDefinition            Code = "no_definition"
+	NoSynonyms              Code = "no_synonyms"
+	NoAntonyms              Code = "no_antonyms"
+	NoExamples              Code = "no_examples"
+	NoPronunciations        Code = "no_pronunciations"
+	NoTranslation           Code = "no_translation"
+	Translation             Code = "translation"
+	JoinGreeting            Code = "join_greeting"
+	FollowGreeting          Code = "follow_greeting"
+	WelcomeBack             Code = "welcome_back"
+	Friend                  Code = "friend"
+	GroupLookupHint         Code = "group_lookup_hint"
+	UnknownCommand          Code = "unknown_command"
+	DidYouMean              Code = "did_you_mean"
+	SeeHelp                 Code = "see_help"
+	Usage                   Code = "usage"
+	ButtonExpired           Code = "button_expired"
+	ModeStatus              Code = "mode_status"
+	UnknownMode             Code = "unknown_mode"
+	GroupAdminsOnly         Code = "group_admins_only"
+	GroupsUnavailable       Code = "groups_unavailable"
+	ModeMention             Code = "mode_mention"
+	ModeAll                 Code = "mode_all"
+	ModeOff                 Code = "mode_off"
+	PreviousPage            Code = "previous_page"
+	NextPage                Code = "next_page"
+	HistoryUnavailable      Code = "history_unavailable"
+	HistoryNeedsFriend      Code = "history_needs_friend"
+	HistoryAlreadyEmpty     Code = "history_already_empty"
+	ClearHistory            Code = "clear_history"
+	ConfirmClearHistory     Code = "confirm_clear_history"
+	HistoryEmpty            Code = "history_empty"
+	HistoryPages            Code = "history_pages"
+	HistoryPage             Code = "history_page"
+	HistoryCleared          Code = "history_cleared"
+	RecentWords             Code = "recent_words"
+	SummaryOff              Code = "summary_off"
+	SummaryOn               Code = "summary_on"
+	NoLookupsThisWeek       Code = "no_lookups_this_week"
+	WeeklyLookup            Code = "weekly_lookup"
+	WeeklyLookups           Code = "weekly_lookups"
+	WeeklyDifferent         Code = "weekly_different"
+	MostLookedUp            Code = "most_looked_up"
+	NotebookUnavailable     Code = "notebook_unavailable"
+	NotebookNeedsFriend     Code = "notebook_needs_friend"
+	SavedWord               Code = "saved_word"
+	NotebookEmptyHint       Code = "notebook_empty_hint"
+	NotebookPages           Code = "notebook_pages"
+	NotebookPage            Code = "notebook_page"
+	NotInNotebook           Code = "not_in_notebook"
+	RemovedWord             Code = "removed_word"
+	NotebookEmpty           Code = "notebook_empty"
+	NotebookTooLong         Code = "notebook_too_long"
+	SaveToNotebook          Code = "save_to_notebook"
+	SynonymsLabel           Code = "synonyms_label"
+	AntonymsLabel           Code = "antonyms_label"
+	ExamplesLabel           Code = "examples_label"
+	PronounceLabel          Code = "pronounce_label"
+	WordOfTheDay            Code = "word_of_the_day"
+	WordOfTheDayExamples    Code = "word_of_the_day_examples"
+	WordOfTheDayUnavailable Code = "word_of_the_day_unavailable"
+	WordOfTheDayAdminsOnly  Code = "word_of_the_day_admins_only"
+	WordOfTheDayOff         Code = "word_of_the_day_off"
+	WordOfTheDayOn          Code = "word_of_the_day_on"
+	BroadcastAdminsOnly     Code = "broadcast_admins_only"
+	Broadcasted             Code = "broadcasted"
+	QuizUnavailable         Code = "quiz_unavailable"
+	QuizNeedsFriend         Code = "quiz_needs_friend"
+	QuizNotPlayed           Code = "quiz_not_played"
+	QuizStats               Code = "quiz_stats"
+	QuizStopped             Code = "quiz_stopped"
+	QuizNoQuestion          Code = "quiz_no_question"
+	QuizQuestion            Code = "quiz_question"
+	QuizOver                Code = "quiz_over"
+	QuizCorrect             Code = "quiz_correct"
+	QuizWrong               Code = "quiz_wrong"
+	NextQuestion            Code = "next_question"
+	ReviewsUnavailable      Code = "reviews_unavailable"
+	ReviewsNeedFriend       Code = "reviews_need_friend"
+	ShowAnswer              Code = "show_answer"
+	ReviewQuestion          Code = "review_question"
+	ReviewsOff              Code = "reviews_off"
+	ReviewsOn               Code = "reviews_on"
+	NothingToReview         Code = "nothing_to_review"
+	WordsToReview           Code = "words_to_review"
+	NoLongerInNotebook      Code = "no_longer_in_notebook"
+	ReviewAnswer            Code = "review_answer"
+	ReviewSynonyms          Code = "review_synonyms"
+	HowWell                 Code = "how_well"
+	GradeAgainLabel         Code = "grade_again_label"
+	GradeHardLabel          Code = "grade_hard_label"
+	GradeGoodLabel          Code = "grade_good_label"
+	GradeEasyLabel          Code = "grade_easy_label"
+	ReviewScheduled         Code = "review_scheduled"
+	ReviewsDone             Code = "reviews_done"
+	MoreToGo                Code = "more_to_go"
+	TimezoneStatus          Code = "timezone_status"
+	UnknownTimezone         Code = "unknown_timezone"
+	TimezoneSet             Code = "timezone_set"
+	QuietHoursOff           Code = "quiet_hours_off"
+	QuietHoursStatus        Code = "quiet_hours_status"
+	QuietHoursSet           Code = "quiet_hours_set"
+	TimeToReview            Code = "time_to_review"
+	SettingsUnavailable     Code = "settings_unavailable"
+	SettingsNeedFriend      Code = "settings_need_friend"
+	SettingsUsage           Code = "settings_usage"
+	SettingChanged          Code = "setting_changed"
+	SettingsReset           Code = "settings_reset"
+	YourSettings            Code = "your_settings"
+	TapToChange             Code = "tap_to_change"
+	ResetToDefaults         Code = "reset_to_defaults"
+	SettingsLine            Code = "settings_line"
+	SensesLabel             Code = "senses_label"
+	SynonymsPerSenseLabel   Code = "synonyms_per_sense_label"
+	ExamplesSettingLabel    Code = "examples_setting_label"
+	PronunciationLabel      Code = "pronunciation_label"
+	DialectLabel            Code = "dialect_label"
+	ReplyLanguageLabel      Code = "reply_language_label"
+	TranslateToLabel        Code = "translate_to_label"
+	Spanish                 Code = "spanish"
+	German                  Code = "german"
+	Portuguese              Code = "portuguese"
+	Indonesian              Code = "indonesian"
+	Malay                   Code = "malay"
+	ExampleLine             Code = "example_line"
+	QuotaWarning            Code = "quota_warning"
+	ProviderQuotaExhausted  Code = "provider_quota_exhausted"
+	GroupAdminsList         Code = "group_admins_list"
+	NoGroupAdmins           Code = "no_group_admins"
+	ManageAdminsOnly        Code = "manage_admins_only"
+)
+
+var catalog = map[Code]map[string]string{
+	TooFast: {
+		English: "You're too fast, please slow down.",
+		Thai:    "ส่งเร็วเกินไป กรุณาช้าลงอีกนิด",
+	},
+	RequestLimit: {
+		English: "Sorry, we've reached the number of requests limit, please wait for 1 minute and try again.",
+		Thai:    "ขออภัย จำนวนคำขอเต็มแล้ว กรุณารอ 1 นาทีแล้วลองใหม่อีกครั้ง",
+	},
+	ServiceError: {
+		English: "There was error on DictService: %s",
+		Thai:    "เกิดข้อผิดพลาดกับบริการพจนานุกรม: %s",
+	},
+	ServiceBusy: {
+		English: "The dictionary is busy right now, please try again in a minute.",
+		Thai:    "พจนานุกรมไม่ว่างในขณะนี้ กรุณาลองใหม่ในอีกสักครู่",
+	},
+	ServiceUnavailable: {
+		English: "The dictionary is temporarily unavailable, please try again in a few minutes.",
+		Thai:    "พจนานุกรมใช้งานไม่ได้ชั่วคราว กรุณาลองใหม่ในอีกไม่กี่นาที",
+	},
+	QuotaExhausted: {
+		English: "The dictionary has reached its usage limit, so I can only look up words I've seen recently. Please try again later.",
+		Thai:    "พจนานุกรมถูกใช้งานครบโควตาแล้ว ตอนนี้ค้นหาได้เฉพาะคำที่เคยค้นหาเมื่อเร็วๆ นี้ กรุณาลองใหม่ภายหลัง",
+	},
+	UserQuotaReached: {
+		English: "You've used up your lookups for now. Send /quota to see when you get more.",
+		Thai:    "คุณใช้สิทธิ์ค้นหาครบแล้วในตอนนี้ ส่ง /quota เพื่อดูว่าจะค้นหาได้อีกเมื่อไร",
+	},
+	QuotaStatus: {
+		English: "Your plan: %s\nLookups left this hour: %s (resets in %s)\nLookups left today: %s (resets in %s)",
+		Thai:    "แพ็กเกจของคุณ: %s\nค้นหาได้อีกในชั่วโมงนี้: %s (รีเซ็ตในอีก %s)\nค้นหาได้อีกในวันนี้: %s (รีเซ็ตในอีก %s)",
+	},
+	NoQuota: {
+		English: "There's no limit on your lookups.",
+		Thai:    "คุณค้นหาได้ไม่จำกัด",
+	},
+	Unlimited: {
+		English: "unlimited",
+		Thai:    "ไม่จำกัด",
+	},
+	NoDefinition: {
+		English: "No definition for '%s'.",
+		Thai:    "ไม่พบความหมายของ '%s'",
+	},
+	NoSynonyms: {
+		English: "No synonyms for '%s'.",
+		Thai:    "ไม่พบคำพ้องความหมายของ '%s'",
+	},
+	NoAntonyms: {
+		English: "No antonyms for '%s'.",
+		Thai:    "ไม่พบคำตรงข้ามของ '%s'",
+	},
+	NoExamples: {
+		English: "No examples for '%s'.",
+		Thai:    "ไม่พบตัวอย่างประโยคของ '%s'",
+	},
+	NoPronunciations: {
+		English: "No pronunciations for '%s'.",
+		Thai:    "ไม่พบวิธีออกเสียงของ '%s'",
+	},
+	NoTranslation: {
+		English: "No %s translation for '%s'.",
+		Thai:    "ไม่พบคำแปลภาษา %s ของ '%s'",
+	},
+	Translation: {
+		English: "%s: %s",
+		Thai:    "ภาษา %s: %s",
+	},
+	JoinGreeting: {
+		English: "Thanks for adding me. I'm Choo Dict Bot, I'm here to help you to find English word definitions and synonyms. Mention me or start a message with '%[1]s' like '%[1]sserendipity' to look a word up.",
+		Thai:    "ขอบคุณที่เพิ่มฉันเข้ากลุ่ม ฉันคือ Choo Dict Bot ช่วยค้นหาความหมายและคำพ้องความหมายของคำภาษาอังกฤษ แท็กฉันหรือขึ้นต้นข้อความด้วย '%[1]s' เช่น '%[1]sserendipity' เพื่อค้นหาคำ",
+	},
+	FollowGreeting: {
+		English: "Hi %s, thanks for adding me. I'm Choo Dict Bot, I'm here to help you to find English word definitions and synonyms. Try to send me some words.",
+		Thai:    "สวัสดี %s ขอบคุณที่เพิ่มฉันเป็นเพื่อน ฉันคือ Choo Dict Bot ช่วยค้นหาความหมายและคำพ้องความหมายของคำภาษาอังกฤษ ลองส่งคำศัพท์มาได้เลย",
+	},
+	WelcomeBack: {
+		English: "Welcome back, %s! Send me a word and I'll find its definitions and synonyms.",
+		Thai:    "ยินดีต้อนรับกลับมา %s! ส่งคำศัพท์มาแล้วฉันจะหาความหมายและคำพ้องความหมายให้",
+	},
+	Friend: {
+		English: "there",
+		Thai:    "คุณ",
+	},
+	GroupLookupHint: {
+		English: "Send me a word like '%[1]sserendipity' and I'll find its definitions and synonyms.",
+		Thai:    "ส่งคำศัพท์ เช่น '%[1]sserendipity' แล้วฉันจะหาความหมายและคำพ้องความหมายให้",
+	},
+	UnknownCommand: {
+		English: "Unknown command '/%s'.",
+		Thai:    "ไม่รู้จักคำสั่ง '/%s'",
+	},
+	DidYouMean: {
+		English: " Did you mean '/%s'?",
+		Thai:    " หมายถึง '/%s' หรือเปล่า?",
+	},
+	SeeHelp: {
+		English: " Send /help to see all commands.",
+		Thai:    " ส่ง /help เพื่อดูคำสั่งทั้งหมด",
+	},
+	Usage: {
+		English: "Usage: %s",
+		Thai:    "วิธีใช้: %s",
+	},
+	ButtonExpired: {
+		English: "This button is no longer available, please send the word again.",
+		Thai:    "ปุ่มนี้ใช้ไม่ได้แล้ว กรุณาส่งคำศัพท์อีกครั้ง",
+	},
+	ModeStatus: {
+		English: "The current mode is '%s'. Admins can change it with '/mode mention', '/mode all' or '/mode off'.",
+		Thai:    "โหมดปัจจุบันคือ '%s' แอดมินเปลี่ยนได้ด้วย '/mode mention', '/mode all' หรือ '/mode off'",
+	},
+	UnknownMode: {
+		English: "Unknown mode '%s', please use 'mention', 'all' or 'off'.",
+		Thai:    "ไม่รู้จักโหมด '%s' กรุณาใช้ 'mention', 'all' หรือ 'off'",
+	},
+	GroupAdminsOnly: {
+		English: "Sorry, only group admins can change the mode.",
+		Thai:    "ขออภัย เฉพาะแอดมินของกลุ่มเท่านั้นที่เปลี่ยนโหมดได้",
+	},
+	GroupsUnavailable: {
+		English: "Sorry, group settings are not available right now.",
+		Thai:    "ขออภัย ตั้งค่ากลุ่มไม่ได้ในขณะนี้",
+	},
+	ModeMention: {
+		English: "OK, I'll only answer when mentioned or when a message starts with '%s'.",
+		Thai:    "ได้เลย ฉันจะตอบเฉพาะเมื่อถูกแท็กหรือเมื่อข้อความขึ้นต้นด้วย '%s'",
+	},
+	ModeAll: {
+		English: "OK, I'll look up every message in this chat.",
+		Thai:    "ได้เลย ฉันจะค้นหาทุกข้อความในแชทนี้",
+	},
+	ModeOff: {
+		English: "OK, I'll stay quiet. Admins can turn me back on with '/mode mention'.",
+		Thai:    "ได้เลย ฉันจะเงียบไว้ แอดมินเปิดฉันอีกครั้งได้ด้วย '/mode mention'",
+	},
+	PreviousPage: {
+		English: "Previous page",
+		Thai:    "หน้าก่อน",
+	},
+	NextPage: {
+		English: "Next page",
+		Thai:    "หน้าถัดไป",
+	},
+	HistoryUnavailable: {
+		English: "Sorry, the history is not available right now.",
+		Thai:    "ขออภัย ประวัติการค้นหาใช้งานไม่ได้ในขณะนี้",
+	},
+	HistoryNeedsFriend: {
+		English: "Please add me as a friend to keep your history.",
+		Thai:    "กรุณาเพิ่มฉันเป็นเพื่อนเพื่อเก็บประวัติการค้นหา",
+	},
+	HistoryAlreadyEmpty: {
+		English: "Your history is already empty.",
+		Thai:    "ประวัติการค้นหาของคุณว่างอยู่แล้ว",
+	},
+	ClearHistory: {
+		English: "Clear history",
+		Thai:    "ล้างประวัติ",
+	},
+	ConfirmClearHistory: {
+		English: "Clear all %d lookups from your history? This can't be undone.",
+		Thai:    "ล้างการค้นหาทั้งหมด %d รายการออกจากประวัติหรือไม่? ย้อนกลับไม่ได้",
+	},
+	HistoryEmpty: {
+		English: "You haven't looked anything up yet.",
+		Thai:    "คุณยังไม่ได้ค้นหาคำใดเลย",
+	},
+	HistoryPages: {
+		English: "There are only %d pages in your history.",
+		Thai:    "ประวัติการค้นหามีเพียง %d หน้า",
+	},
+	HistoryPage: {
+		English: "Your lookups (page %d/%d):",
+		Thai:    "คำที่คุณค้นหา (หน้า %d/%d):",
+	},
+	HistoryCleared: {
+		English: "Cleared %d lookups from your history.",
+		Thai:    "ล้างการค้นหา %d รายการออกจากประวัติแล้ว",
+	},
+	RecentWords: {
+		English: "Your recent words:\n%s\n\nTap one to look it up again.",
+		Thai:    "คำที่คุณค้นหาล่าสุด:\n%s\n\nแตะคำเพื่อค้นหาอีกครั้ง",
+	},
+	SummaryOff: {
+		English: "Weekly summaries are off.",
+		Thai:    "ปิดสรุปรายสัปดาห์แล้ว",
+	},
+	SummaryOn: {
+		English: "Weekly summaries are on. I'll send you one every Monday morning.",
+		Thai:    "เปิดสรุปรายสัปดาห์แล้ว ฉันจะส่งให้ทุกเช้าวันจันทร์",
+	},
+	NoLookupsThisWeek: {
+		English: "You haven't looked anything up this week.",
+		Thai:    "สัปดาห์นี้คุณยังไม่ได้ค้นหาคำใดเลย",
+	},
+	WeeklyLookup: {
+		English: "You looked up 1 word this week.",
+		Thai:    "สัปดาห์นี้คุณค้นหาไป 1 คำ",
+	},
+	WeeklyLookups: {
+		English: "You looked up %d words this week%s.",
+		Thai:    "สัปดาห์นี้คุณค้นหาไป %d คำ%s",
+	},
+	WeeklyDifferent: {
+		English: ", %d of them different",
+		Thai:    " ไม่ซ้ำกัน %d คำ",
+	},
+	MostLookedUp: {
+		English: "\nMost looked up: %s.",
+		Thai:    "\nคำที่ค้นหาบ่อยที่สุด: %s",
+	},
+	NotebookUnavailable: {
+		English: "Sorry, the notebook is not available right now.",
+		Thai:    "ขออภัย สมุดคำศัพท์ใช้งานไม่ได้ในขณะนี้",
+	},
+	NotebookNeedsFriend: {
+		English: "Please add me as a friend to use your notebook.",
+		Thai:    "กรุณาเพิ่มฉันเป็นเพื่อนเพื่อใช้สมุดคำศัพท์",
+	},
+	SavedWord: {
+		English: "Saved '%s' to your notebook. Send /notebook to see your words.",
+		Thai:    "บันทึก '%s' ลงสมุดคำศัพท์แล้ว ส่ง /notebook เพื่อดูคำศัพท์ของคุณ",
+	},
+	NotebookEmptyHint: {
+		English: "Your notebook is empty. Look a word up and tap '%s' to keep it.",
+		Thai:    "สมุดคำศัพท์ของคุณยังว่างอยู่ ค้นหาคำแล้วแตะ '%s' เพื่อเก็บไว้",
+	},
+	NotebookPages: {
+		English: "There are only %d pages in your notebook.",
+		Thai:    "สมุดคำศัพท์มีเพียง %d หน้า",
+	},
+	NotebookPage: {
+		English: "Your notebook (page %d/%d):",
+		Thai:    "สมุดคำศัพท์ของคุณ (หน้า %d/%d):",
+	},
+	NotInNotebook: {
+		English: "'%s' is not in your notebook.",
+		Thai:    "ไม่มี '%s' ในสมุดคำศัพท์ของคุณ",
+	},
+	RemovedWord: {
+		English: "Removed '%s' from your notebook.",
+		Thai:    "ลบ '%s' ออกจากสมุดคำศัพท์แล้ว",
+	},
+	NotebookEmpty: {
+		English: "Your notebook is empty.",
+		Thai:    "สมุดคำศัพท์ของคุณยังว่างอยู่",
+	},
+	NotebookTooLong: {
+		English: "Your notebook is too long to export at once, only the most recent words were exported.",
+		Thai:    "สมุดคำศัพท์ยาวเกินกว่าจะส่งออกได้ในครั้งเดียว จึงส่งออกเฉพาะคำล่าสุด",
+	},
+	SaveToNotebook: {
+		English: "Save to notebook",
+		Thai:    "บันทึกลงสมุด",
+	},
+	SynonymsLabel: {
+		English: "Synonyms",
+		Thai:    "คำพ้อง",
+	},
+	AntonymsLabel: {
+		English: "Antonyms",
+		Thai:    "คำตรงข้าม",
+	},
+	ExamplesLabel: {
+		English: "Examples",
+		Thai:    "ตัวอย่าง",
+	},
+	PronounceLabel: {
+		English: "Pronounce",
+		Thai:    "ออกเสียง",
+	},
+	WordOfTheDay: {
+		English: "Word of the day: %s",
+		Thai:    "คำศัพท์ประจำวัน: %s",
+	},
+	WordOfTheDayExamples: {
+		English: "Examples:\n%s",
+		Thai:    "ตัวอย่างประโยค:\n%s",
+	},
+	WordOfTheDayUnavailable: {
+		English: "Sorry, the word of the day is not available right now.",
+		Thai:    "ขออภัย คำศัพท์ประจำวันใช้งานไม่ได้ในขณะนี้",
+	},
+	WordOfTheDayAdminsOnly: {
+		English: "Sorry, only group admins can change the word of the day.",
+		Thai:    "ขออภัย เฉพาะแอดมินของกลุ่มเท่านั้นที่เปลี่ยนคำศัพท์ประจำวันได้",
+	},
+	WordOfTheDayOff: {
+		English: "OK, no more words of the day. Send /wotd on to get them again.",
+		Thai:    "ได้เลย จะไม่ส่งคำศัพท์ประจำวันอีก ส่ง /wotd on เพื่อรับอีกครั้ง",
+	},
+	WordOfTheDayOn: {
+		English: "OK, I'll send the %s word of the day every morning. Send /wotd off to stop.",
+		Thai:    "ได้เลย ฉันจะส่งคำศัพท์ประจำวันระดับ %s ทุกเช้า ส่ง /wotd off เพื่อหยุด",
+	},
+	BroadcastAdminsOnly: {
+		English: "Sorry, only bot admins can broadcast.",
+		Thai:    "ขออภัย เฉพาะแอดมินของบอทเท่านั้นที่บรอดแคสต์ได้",
+	},
+	Broadcasted: {
+		English: "Broadcast the word of the day to all friends.",
+		Thai:    "บรอดแคสต์คำศัพท์ประจำวันถึงเพื่อนทุกคนแล้ว",
+	},
+	QuizUnavailable: {
+		English: "Sorry, the quiz is not available right now.",
+		Thai:    "ขออภัย ควิซใช้งานไม่ได้ในขณะนี้",
+	},
+	QuizNeedsFriend: {
+		English: "Please add me as a friend to play the quiz.",
+		Thai:    "กรุณาเพิ่มฉันเป็นเพื่อนเพื่อเล่นควิซ",
+	},
+	QuizNotPlayed: {
+		English: "You haven't played yet, send /quiz to start.",
+		Thai:    "คุณยังไม่เคยเล่น ส่ง /quiz เพื่อเริ่ม",
+	},
+	QuizStats: {
+		English: "You've answered %d of %d questions correctly (%d%%).\nCurrent streak: %d, best streak: %d.",
+		Thai:    "คุณตอบถูก %d จาก %d ข้อ (%d%%)\nตอบถูกติดกันตอนนี้: %d ข้อ สูงสุด: %d ข้อ",
+	},
+	QuizStopped: {
+		English: "Quiz stopped. Send /quiz whenever you want to play again.",
+		Thai:    "หยุดควิซแล้ว ส่ง /quiz เมื่อไรก็ได้ที่อยากเล่นอีก",
+	},
+	QuizNoQuestion: {
+		English: "Sorry, I couldn't think of a question, please send /quiz again.",
+		Thai:    "ขออภัย ฉันคิดคำถามไม่ออก กรุณาส่ง /quiz อีกครั้ง",
+	},
+	QuizQuestion: {
+		English: "Which word means:\n\n\"%s\"",
+		Thai:    "คำไหนมีความหมายว่า:\n\n\"%s\"",
+	},
+	QuizOver: {
+		English: "This question is over. Send /quiz for a new one.",
+		Thai:    "คำถามนี้จบไปแล้ว ส่ง /quiz เพื่อรับคำถามใหม่",
+	},
+	QuizCorrect: {
+		English: "Correct! Your streak is %d.",
+		Thai:    "ถูกต้อง! คุณตอบถูกติดกัน %d ข้อ",
+	},
+	QuizWrong: {
+		English: "Not quite, the answer was '%s'.",
+		Thai:    "ยังไม่ใช่ คำตอบคือ '%s'",
+	},
+	NextQuestion: {
+		English: "Next question",
+		Thai:    "คำถามถัดไป",
+	},
+	ReviewsUnavailable: {
+		English: "Sorry, reviews are not available right now.",
+		Thai:    "ขออภัย การทบทวนใช้งานไม่ได้ในขณะนี้",
+	},
+	ReviewsNeedFriend: {
+		English: "Please add me as a friend to review your words.",
+		Thai:    "กรุณาเพิ่มฉันเป็นเพื่อนเพื่อทบทวนคำศัพท์",
+	},
+	ShowAnswer: {
+		English: "Show answer",
+		Thai:    "ดูคำตอบ",
+	},
+	ReviewQuestion: {
+		English: "Do you remember what '%s' means?",
+		Thai:    "จำได้ไหมว่า '%s' แปลว่าอะไร?",
+	},
+	ReviewsOff: {
+		English: "Daily reviews are off. You can still review with /review.",
+		Thai:    "ปิดการทบทวนรายวันแล้ว ยังทบทวนเองได้ด้วย /review",
+	},
+	ReviewsOn: {
+		English: "Daily reviews are on. I'll send you the words due each day.",
+		Thai:    "เปิดการทบทวนรายวันแล้ว ฉันจะส่งคำที่ถึงเวลาทบทวนให้ทุกวัน",
+	},
+	NothingToReview: {
+		English: "No words to review right now. Save words to your notebook and I'll remind you when they are due.",
+		Thai:    "ตอนนี้ยังไม่มีคำให้ทบทวน บันทึกคำลงสมุดคำศัพท์แล้วฉันจะเตือนเมื่อถึงเวลา",
+	},
+	WordsToReview: {
+		English: "%d word(s) to review.\n\n",
+		Thai:    "มี %d คำให้ทบทวน\n\n",
+	},
+	NoLongerInNotebook: {
+		English: "'%s' is no longer in your notebook.",
+		Thai:    "'%s' ไม่อยู่ในสมุดคำศัพท์ของคุณแล้ว",
+	},
+	ReviewAnswer: {
+		English: "%s: %s",
+		Thai:    "%s: %s",
+	},
+	ReviewSynonyms: {
+		English: "\nSynonyms: %s",
+		Thai:    "\nคำพ้องความหมาย: %s",
+	},
+	HowWell: {
+		English: "\n\nHow well did you remember it?",
+		Thai:    "\n\nคุณจำคำนี้ได้ดีแค่ไหน?",
+	},
+	GradeAgainLabel: {
+		English: "Again",
+		Thai:    "จำไม่ได้",
+	},
+	GradeHardLabel: {
+		English: "Hard",
+		Thai:    "ยาก",
+	},
+	GradeGoodLabel: {
+		English: "Good",
+		Thai:    "ดี",
+	},
+	GradeEasyLabel: {
+		English: "Easy",
+		Thai:    "ง่าย",
+	},
+	ReviewScheduled: {
+		English: "Got it, I'll ask you about '%s' again in %d day(s).",
+		Thai:    "รับทราบ ฉันจะถามเรื่อง '%s' อีกครั้งในอีก %d วัน",
+	},
+	ReviewsDone: {
+		English: " That's all for now, well done!",
+		Thai:    " ตอนนี้หมดแล้ว เก่งมาก!",
+	},
+	MoreToGo: {
+		English: "%d more to go.\n\n",
+		Thai:    "เหลืออีก %d คำ\n\n",
+	},
+	TimezoneStatus: {
+		English: "Your time zone is %s.",
+		Thai:    "เขตเวลาของคุณคือ %s",
+	},
+	UnknownTimezone: {
+		English: "Unknown time zone '%s', please use a name like Asia/Bangkok.",
+		Thai:    "ไม่รู้จักเขตเวลา '%s' กรุณาใช้ชื่อเช่น Asia/Bangkok",
+	},
+	TimezoneSet: {
+		English: "Your time zone is now %s.",
+		Thai:    "ตั้งเขตเวลาของคุณเป็น %s แล้ว",
+	},
+	QuietHoursOff: {
+		English: "Quiet hours are off.",
+		Thai:    "ปิดช่วงเวลางดแจ้งเตือนแล้ว",
+	},
+	QuietHoursStatus: {
+		English: "Your quiet hours are %s.",
+		Thai:    "ช่วงเวลางดแจ้งเตือนของคุณคือ %s",
+	},
+	QuietHoursSet: {
+		English: "Your quiet hours are now %s.",
+		Thai:    "ตั้งช่วงเวลางดแจ้งเตือนเป็น %s แล้ว",
+	},
+	TimeToReview: {
+		English: "Time to review! You have %d word(s) due.\n\n",
+		Thai:    "ได้เวลาทบทวนแล้ว! มี %d คำที่ถึงเวลาทบทวน\n\n",
+	},
+	SettingsUnavailable: {
+		English: "Sorry, settings are not available right now.",
+		Thai:    "ขออภัย การตั้งค่าใช้งานไม่ได้ในขณะนี้",
+	},
+	SettingsNeedFriend: {
+		English: "Please add me as a friend to change your settings.",
+		Thai:    "กรุณาเพิ่มฉันเป็นเพื่อนเพื่อเปลี่ยนการตั้งค่า",
+	},
+	SettingsUsage: {
+		English: "Usage: /settings <name> <value>, where the settings are:\n%s",
+		Thai:    "วิธีใช้: /settings <ชื่อ> <ค่า> โดยการตั้งค่าที่มีคือ:\n%s",
+	},
+	SettingChanged: {
+		English: "%s is now %s.",
+		Thai:    "ตั้ง%sเป็น %s แล้ว",
+	},
+	SettingsReset: {
+		English: "Your settings are back to the defaults.",
+		Thai:    "คืนค่าการตั้งค่าเป็นค่าเริ่มต้นแล้ว",
+	},
+	YourSettings: {
+		English: "Your settings",
+		Thai:    "การตั้งค่าของคุณ",
+	},
+	TapToChange: {
+		English: "Tap a value to change it.",
+		Thai:    "แตะที่ค่าเพื่อเปลี่ยน",
+	},
+	ResetToDefaults: {
+		English: "Reset to defaults",
+		Thai:    "คืนค่าเริ่มต้น",
+	},
+	SettingsLine: {
+		English: "%s: %s",
+		Thai:    "%s: %s",
+	},
+	SensesLabel: {
+		English: "Senses",
+		Thai:    "จำนวนความหมาย",
+	},
+	SynonymsPerSenseLabel: {
+		English: "Synonyms per sense",
+		Thai:    "คำพ้องต่อความหมาย",
+	},
+	ExamplesSettingLabel: {
+		English: "Examples",
+		Thai:    "ตัวอย่างประโยค",
+	},
+	PronunciationLabel: {
+		English: "Pronunciation",
+		Thai:    "การออกเสียง",
+	},
+	DialectLabel: {
+		English: "Dialect",
+		Thai:    "สำเนียง",
+	},
+	ReplyLanguageLabel: {
+		English: "Reply language",
+		Thai:    "ภาษาที่ใช้ตอบ",
+	},
+	TranslateToLabel: {
+		English: "Translate to",
+		Thai:    "แปลเป็นภาษา",
+	},
+	Spanish: {
+		English: "Spanish",
+		Thai:    "สเปน",
+	},
+	German: {
+		English: "German",
+		Thai:    "เยอรมัน",
+	},
+	Portuguese: {
+		English: "Portuguese",
+		Thai:    "โปรตุเกส",
+	},
+	Indonesian: {
+		English: "Indonesian",
+		Thai:    "อินโดนีเซีย",
+	},
+	Malay: {
+		English: "Malay",
+		Thai:    "มาเลย์",
+	},
+	ExampleLine: {
+		English: "   e.g. %s",
+		Thai:    "   เช่น %s",
+	},
+	QuotaWarning: {
+		English: "%s has used %d of its %d calls this %s.",
+		Thai:    "%s ใช้ไปแล้ว %d จาก %d ครั้งใน%sนี้",
+	},
+	ProviderQuotaExhausted: {
+		English: " Until the quota resets, lookups are answered from the cache or the fallback provider.",
+		Thai:    " จนกว่าโควตาจะรีเซ็ต การค้นหาจะตอบจากแคชหรือผู้ให้บริการสำรอง",
+	},
+	GroupAdminsList: {
+		English: "Group admins: %s.",
+		Thai:    "แอดมินของกลุ่ม: %s",
+	},
+	NoGroupAdmins: {
+		English: "This group has no admins yet. Bot admins and the chat's own admins can add some with '/admins add @member'.",
+		Thai:    "กลุ่มนี้ยังไม่มีแอดมิน แอดมินของบอทหรือแอดมินของแชทเพิ่มได้ด้วย '/admins add @สมาชิก'",
+	},
+	ManageAdminsOnly: {
+		English: "Sorry, only group admins can add or remove admins.",
+		Thai:    "ขออภัย เฉพาะแอดมินของกลุ่มเท่านั้นที่เพิ่มหรือลบแอดมินได้",
+	},
+}
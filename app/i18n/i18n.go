@@ -0,0 +1,66 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	English = "en"
+	Thai    = "th"
+)
+
+// Code identifies a user-facing message in the catalog.
+type Code string
+
+// Text returns the message in the given language, falling back to English
+// when the language or the message is not translated.
+func Text(language string, code Code, args ...interface{}) string {
+	translations, ok := catalog[code]
+	if !ok {
+		return string(code)
+	}
+	format, ok := translations[language]
+	if !ok {
+		format = translations[English]
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Supported reports whether the catalog is translated into the language.
+func Supported(language string) bool {
+	return language == English || language == Thai
+}
+
+// Error is an error that users should see. It carries a message code so that
+// it can be shown in the user's language; Error returns the English text.
+type Error struct {
+	Code Code
+	Args []interface{}
+	Err  error
+}
+
+func NewError(code Code, args ...interface{}) *Error {
+	return &Error{Code: code, Args: args}
+}
+
+func (this *Error) Error() string {
+	return Text(English, this.Code, this.Args...)
+}
+
+func (this *Error) Unwrap() error {
+	return this.Err
+}
+
+// Message returns the text of an error in the given language. Errors without
+// a code are shown as they are.
+func Message(language string, err error) string {
+	var coded *Error
+	if errors.As(err, &coded) {
+		return Text(language, coded.Code, coded.Args...)
+	}
+	return err.Error()
+}
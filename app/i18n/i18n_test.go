@@ -0,0 +1,58 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCatalog(t *testing.T) {
+	for code, translations := range catalog {
+		for _, language := range []string{English, Thai} {
+			if translations[language] == "" {
+				t.Errorf("catalog[%q] has no %q translation", code, language)
+			}
+		}
+		if strings.Count(translations[English], "%") != strings.Count(translations[Thai], "%") {
+			t.Errorf("catalog[%q] translations take different arguments", code)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	cases := []struct {
+		language string
+		code     Code
+		args     []interface{}
+		want     string
+	}{
+		{English, NoDefinition, []interface{}{"line"}, "No definition for 'line'."},
+		{Thai, NoDefinition, []interface{}{"line"}, "ไม่พบความหมายของ 'line'"},
+		{"ja", TooFast, nil, "You're too fast, please slow down."},
+		{English, GroupLookupHint, []interface{}{"?"}, "Send me a word like '?serendipity' and I'll find its definitions and synonyms."},
+		{English, Code("missing"), nil, "missing"},
+	}
+	for _, c := range cases {
+		if got := Text(c.language, c.code, c.args...); got != c.want {
+			t.Errorf("Text(%q, %q, %v) == %q, want %q", c.language, c.code, c.args, got, c.want)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	cause := errors.New("DummyError")
+	err := fmt.Errorf("lookup: %w", &Error{Code: ServiceError, Args: []interface{}{cause.Error()}, Err: cause})
+	if got, want := err.Error(), "lookup: There was error on DictService: DummyError"; got != want {
+		t.Errorf("Error.Error() == %q, want %q", got, want)
+	}
+	if !errors.Is(err, cause) {
+		t.Errorf("errors.Is(%v, %v) == false, want true", err, cause)
+	}
+	if got, want := Message(Thai, NewError(TooFast)), "ส่งเร็วเกินไป กรุณาช้าลงอีกนิด"; got != want {
+		t.Errorf("Message(%q, TooFast) == %q, want %q", Thai, got, want)
+	}
+	if got, want := Message(Thai, cause), "DummyError"; got != want {
+		t.Errorf("Message(%q, %v) == %q, want %q", Thai, cause, got, want)
+	}
+}
@@ -1,55 +1,257 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"log"
+	"math/rand"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/choobot/choo-dict-bot/app/admin"
 	"github.com/choobot/choo-dict-bot/app/bot"
+	"github.com/choobot/choo-dict-bot/app/chat"
+	"github.com/choobot/choo-dict-bot/app/chat/line"
+	"github.com/choobot/choo-dict-bot/app/chat/slack"
+	"github.com/choobot/choo-dict-bot/app/chat/telegram"
+	"github.com/choobot/choo-dict-bot/app/scheduler"
 	"github.com/choobot/choo-dict-bot/app/service"
+	"github.com/choobot/choo-dict-bot/app/store"
 
 	"github.com/choobot/choo-dict-bot/app/controller"
 	"github.com/line/line-bot-sdk-go/linebot"
+	_ "github.com/mattn/go-sqlite3"
 )
 
+// shutdownTimeout is how long requests in flight get to finish on shutdown.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
+	rand.Seed(time.Now().UnixNano())
 	client, err := linebot.New(os.Getenv("LINE_BOT_SECRET"), os.Getenv("LINE_BOT_TOKEN"))
 	if err != nil {
 		log.Fatal(err)
 	}
+	quotas := service.NewQuotaManager(store.NewMemoryQuotaStore(), scheduler.RealClock{})
+	quotas.SetLimits("oxford", service.QuotaLimits{
+		PerMinute: envInt("OXFORD_QUOTA_PER_MINUTE"),
+		PerDay:    envInt("OXFORD_QUOTA_PER_DAY"),
+		PerMonth:  envInt("OXFORD_QUOTA_PER_MONTH"),
+	})
 	dictService := &service.OxfordService{
 		AppId:          os.Getenv("OXFORD_API_ID"),
 		AppKey:         os.Getenv("OXFORD_API_KEY"),
 		EndpointPrefix: "https://od-api.oxforddictionaries.com",
+		Client: &http.Client{
+			// Retries count against the quota too
+			Transport: service.NewRetryTransport(quotas.Transport("oxford", nil)),
+			Timeout:   10 * time.Second,
+		},
+	}
+	breaker := service.NewCircuitBreaker(dictService, scheduler.RealClock{})
+	quotaService := service.NewQuotaService(breaker, "oxford", quotas)
+	serviceController := controller.NewServiceController(quotaService, 30)
+	plans := controller.NewPlans(store.NewMemoryTierStore(), scheduler.RealClock{})
+	serviceController.Plans = plans
+	lineAdapter := line.NewAdapter(client)
+	lineAdapter.PushOnRedelivery = os.Getenv("PUSH_ON_REDELIVERY") == "true"
+	adapters := chat.NewMux(lineAdapter)
+	dictBot := bot.NewDictBot(serviceController, adapters)
+	dictBot.Plans = plans
+	quotas.OnWarning = dictBot.WarnQuota
+	if databasePath := os.Getenv("DATABASE_PATH"); databasePath != "" {
+		db, err := sql.Open("sqlite3", databasePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		registry, err := store.NewSQLiteUserRegistry(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		groups, err := store.NewSQLiteGroupStore(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		notebook, err := store.NewSQLiteNotebook(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		reviews, err := store.NewSQLiteReviewStore(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		quizzes, err := store.NewSQLiteQuizStore(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		subscriptions, err := store.NewSQLiteSubscriptionStore(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		history, err := store.NewSQLiteHistory(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		quotaStore, err := store.NewSQLiteQuotaStore(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		tiers, err := store.NewSQLiteTierStore(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		events, err := store.NewSQLiteEventStore(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		dictBot.Registry = registry
+		dictBot.Groups = groups
+		dictBot.Notebook = notebook
+		dictBot.Reviews = reviews
+		dictBot.Quizzes = quizzes
+		dictBot.Subscriptions = subscriptions
+		dictBot.History = history
+		dictBot.Events = events
+		quotas.Store = quotaStore
+		plans.Store = tiers
+	} else {
+		dictBot.Registry = store.NewMemoryUserRegistry()
+		dictBot.Groups = store.NewMemoryGroupStore()
+		dictBot.Notebook = store.NewMemoryNotebook()
+		dictBot.Reviews = store.NewMemoryReviewStore()
+		dictBot.Quizzes = store.NewMemoryQuizStore()
+		dictBot.Subscriptions = store.NewMemorySubscriptionStore()
+		dictBot.History = store.NewMemoryHistory()
+		dictBot.Events = store.NewMemoryEventStore()
+	}
+	if botInfo, err := client.GetBotInfo().Do(); err != nil {
+		log.Println(err)
+	} else {
+		dictBot.BotUserIDs = append(dictBot.BotUserIDs, botInfo.UserID)
+	}
+	if admins := os.Getenv("BOT_ADMINS"); admins != "" {
+		dictBot.Admins = strings.Split(admins, ",")
 	}
-	serviceController := controller.NewServiceController(dictService, 30)
-	bot := &bot.DictBot{
-		ServiceController: serviceController,
-		Client:            client,
+	if words := os.Getenv("WORD_OF_THE_DAY_WORDS"); words != "" {
+		dictBot.CuratedWords = strings.Split(words, ",")
 	}
-	http.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
-		events, err := client.ParseRequest(r)
+	// Every platform is registered before anything sends through the Mux
+	var telegramAdapter *telegram.Adapter
+	if token := os.Getenv("TELEGRAM_BOT_TOKEN"); token != "" {
+		telegramAdapter = telegram.NewAdapter(token)
+		telegramAdapter.Secret = os.Getenv("TELEGRAM_WEBHOOK_SECRET")
+		me, err := telegramAdapter.GetMe()
 		if err != nil {
-			if err == linebot.ErrInvalidSignature {
+			log.Fatal(err)
+		}
+		telegramAdapter.Bot = me
+		dictBot.BotUserIDs = append(dictBot.BotUserIDs, telegram.ID(me.ID))
+		adapters.Handle(telegram.IDPrefix, telegramAdapter)
+	}
+	var slackAdapter *slack.Adapter
+	if token := os.Getenv("SLACK_BOT_TOKEN"); token != "" {
+		slackAdapter = slack.NewAdapter(token, os.Getenv("SLACK_SIGNING_SECRET"))
+		botUserID, err := slackAdapter.AuthTest()
+		if err != nil {
+			log.Fatal(err)
+		}
+		dictBot.BotUserIDs = append(dictBot.BotUserIDs, slack.ID(botUserID))
+		adapters.Handle(slack.IDPrefix, slackAdapter)
+	}
+	jobs := scheduler.NewScheduler(scheduler.RealClock{})
+	jobs.Every("reviews", time.Minute, dictBot.PushDueReviews)
+	jobs.Every("word_of_the_day", time.Minute, dictBot.SendWordOfTheDay)
+	jobs.Every("weekly_summaries", time.Minute, dictBot.PushWeeklySummaries)
+	jobs.Start()
+	dispatcher := bot.NewDispatcher(dictBot.HandleEvent, 8, 128)
+	http.Handle("/callback", webhook(lineAdapter.ParseRequest, linebot.ErrInvalidSignature, dispatcher))
+	var poller *telegram.Poller
+	if telegramAdapter != nil {
+		if os.Getenv("TELEGRAM_MODE") == "polling" {
+			poller = telegram.NewPoller(telegramAdapter, dispatcher.Enqueue)
+			poller.Start()
+		} else {
+			http.Handle("/telegram", webhook(telegramAdapter.ParseRequest, telegram.ErrInvalidSecret, dispatcher))
+		}
+	}
+	if slackAdapter != nil {
+		// Slash commands, taps on buttons and events share the request URL
+		http.Handle("/slack", slackAdapter.URLVerification(webhook(slackAdapter.ParseRequest, slack.ErrInvalidSignature, dispatcher)))
+	}
+	adminServer := admin.NewServer(os.Getenv("ADMIN_TOKEN"))
+	adminServer.Handle("/admin/breaker", admin.BreakerHandler(breaker))
+	adminServer.Handle("/admin/quota", admin.QuotaHandler(quotas))
+	adminServer.Handle("/admin/tiers", admin.TiersHandler(plans))
+	adminServer.Handle("/admin/webhook", admin.DispatcherHandler(dispatcher))
+	http.Handle("/admin/", adminServer)
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "80"
+	}
+	server := &http.Server{Addr: ":" + port}
+	go func() {
+		fmt.Println("Runnign at :" + port)
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	// Stop taking events, then finish the queued ones before the jobs stop
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+	log.Println("shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println(err)
+	}
+	if poller != nil {
+		poller.Stop()
+	}
+	dispatcher.Stop()
+	jobs.Stop()
+}
+
+// webhook queues the events of a platform's webhook requests. Platforms get
+// a non-2xx response only when the whole batch should be sent again: 400 for
+// a bad signature, 500 for a request we couldn't read and 503 when the queue
+// is full. Events are handled in the background so that platforms don't time
+// out waiting for the dictionary, and failures of single events are only
+// logged, as resending the batch would repeat the events that succeeded.
+func webhook(parse func(r *http.Request) ([]*chat.Event, error), invalidSignature error, dispatcher *bot.Dispatcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		events, err := parse(r)
+		if err != nil {
+			if err == invalidSignature {
 				w.WriteHeader(http.StatusBadRequest)
 			} else {
 				w.WriteHeader(http.StatusInternalServerError)
 			}
 			return
 		}
-		if err := bot.Response(events); err != nil {
+		if err := dispatcher.Enqueue(events); err != nil {
 			log.Println(err)
-			w.WriteHeader(http.StatusInternalServerError)
+			w.WriteHeader(http.StatusServiceUnavailable)
 		}
+	}
+}
 
-	})
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "80"
+// envInt reads a number from the environment, or 0 when it isn't set.
+func envInt(key string) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0
 	}
-	fmt.Println("Runnign at :" + port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatal(err)
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("%s: %v", key, err)
 	}
+	return n
 }
@@ -0,0 +1,58 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+)
+
+type waiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// FakeClock is a Clock for tests whose time only moves with Advance.
+type FakeClock struct {
+	now        time.Time
+	waiters    []waiter
+	waitersMux sync.Mutex
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (this *FakeClock) Now() time.Time {
+	this.waitersMux.Lock()
+	defer this.waitersMux.Unlock()
+	return this.now
+}
+
+func (this *FakeClock) After(d time.Duration) <-chan time.Time {
+	this.waitersMux.Lock()
+	defer this.waitersMux.Unlock()
+	ch := make(chan time.Time, 1)
+	this.waiters = append(this.waiters, waiter{at: this.now.Add(d), ch: ch})
+	return ch
+}
+
+// Waiters returns the number of pending After calls.
+func (this *FakeClock) Waiters() int {
+	this.waitersMux.Lock()
+	defer this.waitersMux.Unlock()
+	return len(this.waiters)
+}
+
+func (this *FakeClock) Advance(d time.Duration) {
+	this.waitersMux.Lock()
+	defer this.waitersMux.Unlock()
+	this.now = this.now.Add(d)
+	pending := []waiter{}
+	for _, w := range this.waiters {
+		if w.at.After(this.now) {
+			pending = append(pending, w)
+		} else {
+			w.ch <- this.now
+		}
+	}
+	this.waiters = pending
+}
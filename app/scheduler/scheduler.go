@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type RealClock struct {
+}
+
+func (this RealClock) Now() time.Time {
+	return time.Now()
+}
+
+func (this RealClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      func(now time.Time) error
+}
+
+// Scheduler runs jobs periodically inside the server process. Each job runs
+// in its own goroutine and never overlaps with itself.
+type Scheduler struct {
+	clock   Clock
+	jobs    []job
+	stop    chan struct{}
+	running sync.WaitGroup
+}
+
+func NewScheduler(clock Clock) *Scheduler {
+	return &Scheduler{
+		clock: clock,
+		stop:  make(chan struct{}),
+	}
+}
+
+func (this *Scheduler) Every(name string, interval time.Duration, run func(now time.Time) error) {
+	this.jobs = append(this.jobs, job{
+		name:     name,
+		interval: interval,
+		run:      run,
+	})
+}
+
+func (this *Scheduler) Start() {
+	for _, j := range this.jobs {
+		this.running.Add(1)
+		go func(j job) {
+			defer this.running.Done()
+			for {
+				select {
+				case <-this.stop:
+					return
+				case <-this.clock.After(j.interval):
+					if err := j.run(this.clock.Now()); err != nil {
+						log.Printf("job=%s error=%v", j.name, err)
+					}
+				}
+			}
+		}(j)
+	}
+}
+
+func (this *Scheduler) Stop() {
+	close(this.stop)
+	this.running.Wait()
+}
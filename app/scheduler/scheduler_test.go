@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSchedulerEvery(t *testing.T) {
+	start := time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	scheduler := NewScheduler(clock)
+	runs := make(chan time.Time, 10)
+	scheduler.Every("test", time.Minute, func(now time.Time) error {
+		runs <- now
+		return errors.New("logged and ignored")
+	})
+	scheduler.Start()
+
+	waitFor(t, func() bool { return clock.Waiters() == 1 })
+	clock.Advance(30 * time.Second)
+	select {
+	case <-runs:
+		t.Errorf("Scheduler ran a job before its interval")
+	default:
+	}
+	clock.Advance(30 * time.Second)
+	if now := <-runs; !now.Equal(start.Add(time.Minute)) {
+		t.Errorf("Scheduler ran a job at %v, want %v", now, start.Add(time.Minute))
+	}
+
+	// The job keeps running after an error
+	waitFor(t, func() bool { return clock.Waiters() == 1 })
+	clock.Advance(time.Minute)
+	if now := <-runs; !now.Equal(start.Add(2 * time.Minute)) {
+		t.Errorf("Scheduler ran a job at %v, want %v", now, start.Add(2*time.Minute))
+	}
+
+	waitFor(t, func() bool { return clock.Waiters() == 1 })
+	scheduler.Stop()
+	clock.Advance(time.Minute)
+	select {
+	case <-runs:
+		t.Errorf("Scheduler ran a job after Stop")
+	default:
+	}
+}
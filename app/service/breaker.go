@@ -0,0 +1,193 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/scheduler"
+)
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+type BreakerStatus struct {
+	State    BreakerState `json:"state"`
+	Requests int          `json:"requests"`
+	Failures int          `json:"failures"`
+	OpenedAt time.Time    `json:"opened_at"`
+}
+
+// CircuitBreaker is a DictService that stops calling a failing provider. It
+// opens when the failure rate over a window reaches a threshold, fails fast
+// with ErrUnavailable while open, and then lets probe requests through one
+// at a time until enough of them succeed.
+type CircuitBreaker struct {
+	DictService DictService
+	Clock       scheduler.Clock
+	// Window is the period over which the failure rate is measured
+	Window      time.Duration
+	MinRequests int
+	FailureRate float64
+	OpenFor     time.Duration
+	// Probes is the number of successful probes that close the breaker
+	Probes      int
+	state       BreakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+	successes   int
+	stateMux    sync.Mutex
+}
+
+func NewCircuitBreaker(dictService DictService, clock scheduler.Clock) *CircuitBreaker {
+	return &CircuitBreaker{
+		DictService: dictService,
+		Clock:       clock,
+		Window:      time.Minute,
+		MinRequests: 10,
+		FailureRate: 0.5,
+		OpenFor:     30 * time.Second,
+		Probes:      2,
+		state:       BreakerClosed,
+	}
+}
+
+func (this *CircuitBreaker) FindDefinitions(word string) (string, error) {
+	return this.find(word, this.DictService.FindDefinitions)
+}
+
+func (this *CircuitBreaker) FindSynonyms(word string) (string, error) {
+	return this.find(word, this.DictService.FindSynonyms)
+}
+
+func (this *CircuitBreaker) FindAntonyms(word string) (string, error) {
+	return this.find(word, this.DictService.FindAntonyms)
+}
+
+func (this *CircuitBreaker) FindExamples(word string) (string, error) {
+	return this.find(word, this.DictService.FindExamples)
+}
+
+func (this *CircuitBreaker) FindPronunciations(word string) (string, error) {
+	return this.find(word, this.DictService.FindPronunciations)
+}
+
+func (this *CircuitBreaker) Lookup(word string, options LookupOptions) (Entry, error) {
+	entry := Entry{}
+	err := this.call(func() error {
+		var err error
+		entry, err = this.DictService.Lookup(word, options)
+		return err
+	})
+	return entry, err
+}
+
+func (this *CircuitBreaker) find(word string, find func(word string) (string, error)) (string, error) {
+	res := ""
+	err := this.call(func() error {
+		var err error
+		res, err = find(word)
+		return err
+	})
+	return res, err
+}
+
+func (this *CircuitBreaker) call(run func() error) error {
+	if !this.allow() {
+		return &Error{Kind: ErrUnavailable}
+	}
+	err := run()
+	this.record(err)
+	return err
+}
+
+func (this *CircuitBreaker) allow() bool {
+	this.stateMux.Lock()
+	defer this.stateMux.Unlock()
+	switch this.state {
+	case BreakerOpen:
+		if this.Clock.Now().Sub(this.openedAt) < this.OpenFor {
+			return false
+		}
+		this.state, this.successes, this.probing = BreakerHalfOpen, 0, false
+		fallthrough
+	case BreakerHalfOpen:
+		if this.probing {
+			return false
+		}
+		this.probing = true
+	}
+	return true
+}
+
+func (this *CircuitBreaker) record(err error) {
+	// A word the provider doesn't know is a healthy answer
+	failed := err != nil && !errors.Is(err, ErrNotFound)
+	this.stateMux.Lock()
+	defer this.stateMux.Unlock()
+	now := this.Clock.Now()
+	switch this.state {
+	case BreakerOpen:
+		return
+	case BreakerHalfOpen:
+		this.probing = false
+		if failed {
+			this.state, this.openedAt = BreakerOpen, now
+			return
+		}
+		this.successes++
+		if this.successes >= this.Probes {
+			this.state, this.windowStart, this.requests, this.failures = BreakerClosed, now, 0, 0
+		}
+		return
+	}
+	if now.Sub(this.windowStart) >= this.Window {
+		this.windowStart, this.requests, this.failures = now, 0, 0
+	}
+	this.requests++
+	if failed {
+		this.failures++
+	}
+	if this.requests >= this.MinRequests && float64(this.failures) >= this.FailureRate*float64(this.requests) {
+		this.state, this.openedAt = BreakerOpen, now
+	}
+}
+
+// Available reports whether a request would be let through now.
+func (this *CircuitBreaker) Available() bool {
+	this.stateMux.Lock()
+	defer this.stateMux.Unlock()
+	switch this.state {
+	case BreakerOpen:
+		return this.Clock.Now().Sub(this.openedAt) >= this.OpenFor
+	case BreakerHalfOpen:
+		return !this.probing
+	}
+	return true
+}
+
+func (this *CircuitBreaker) Status() BreakerStatus {
+	this.stateMux.Lock()
+	defer this.stateMux.Unlock()
+	return BreakerStatus{
+		State:    this.state,
+		Requests: this.requests,
+		Failures: this.failures,
+		OpenedAt: this.openedAt,
+	}
+}
+
+// Reset closes the breaker, forgetting past failures.
+func (this *CircuitBreaker) Reset() {
+	this.stateMux.Lock()
+	defer this.stateMux.Unlock()
+	this.state, this.windowStart, this.requests, this.failures, this.probing = BreakerClosed, this.Clock.Now(), 0, 0, false
+}
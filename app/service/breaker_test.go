@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/scheduler"
+)
+
+type fakeDictService struct {
+	err   error
+	calls int
+}
+
+func (this *fakeDictService) find(word string) (string, error) {
+	this.calls++
+	if this.err != nil {
+		return "", this.err
+	}
+	return word, nil
+}
+
+func (this *fakeDictService) FindDefinitions(word string) (string, error)    { return this.find(word) }
+func (this *fakeDictService) FindSynonyms(word string) (string, error)       { return this.find(word) }
+func (this *fakeDictService) FindAntonyms(word string) (string, error)       { return this.find(word) }
+func (this *fakeDictService) FindExamples(word string) (string, error)       { return this.find(word) }
+func (this *fakeDictService) FindPronunciations(word string) (string, error) { return this.find(word) }
+
+func (this *fakeDictService) Lookup(word string, options LookupOptions) (Entry, error) {
+	_, err := this.find(word)
+	return Entry{Word: word}, err
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := scheduler.NewFakeClock(time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC))
+	provider := &fakeDictService{}
+	breaker := NewCircuitBreaker(provider, clock)
+	breaker.MinRequests, breaker.FailureRate, breaker.Probes = 4, 0.5, 2
+
+	provider.err = &Error{Kind: ErrNotFound}
+	for i := 0; i < 4; i++ {
+		breaker.FindDefinitions("missing")
+	}
+	provider.err = &Error{Kind: ErrUpstream}
+	breaker.FindSynonyms("line")
+	if got := breaker.Status(); got.State != BreakerClosed || got.Requests != 5 || got.Failures != 1 {
+		t.Errorf("CircuitBreaker.Status() after not found answers == %+v, want closed", got)
+	}
+	clock.Advance(time.Minute)
+	for i := 0; i < 4; i++ {
+		breaker.Lookup("line", DefaultLookupOptions)
+	}
+	if got := breaker.Status(); got.State != BreakerOpen || !got.OpenedAt.Equal(clock.Now()) || breaker.Available() {
+		t.Errorf("CircuitBreaker.Status() after 4 failures == %+v, want open", got)
+	}
+	calls := provider.calls
+	if _, err := breaker.FindDefinitions("line"); !errors.Is(err, ErrUnavailable) || provider.calls != calls {
+		t.Errorf("CircuitBreaker.FindDefinitions() while open == %v and called the provider", err)
+	}
+
+	clock.Advance(breaker.OpenFor)
+	if !breaker.Available() {
+		t.Errorf("CircuitBreaker.Available() after %s == false, want true", breaker.OpenFor)
+	}
+	if _, err := breaker.FindDefinitions("line"); !errors.Is(err, ErrUpstream) || breaker.Status().State != BreakerOpen {
+		t.Errorf("CircuitBreaker failed probe == %v, %s, want %v, open", err, breaker.Status().State, ErrUpstream)
+	}
+	clock.Advance(breaker.OpenFor)
+	provider.err = nil
+	if got, err := breaker.FindDefinitions("line"); got != "line" || err != nil || breaker.Status().State != BreakerHalfOpen {
+		t.Errorf("CircuitBreaker first probe == %q, %v, %s, want half-open", got, err, breaker.Status().State)
+	}
+	breaker.FindDefinitions("line")
+	if got := breaker.Status(); got.State != BreakerClosed || got.Requests != 0 {
+		t.Errorf("CircuitBreaker.Status() after %d probes == %+v, want closed", breaker.Probes, got)
+	}
+
+	breaker.state, breaker.openedAt = BreakerOpen, clock.Now()
+	breaker.Reset()
+	if got := breaker.Status(); got.State != BreakerClosed || !breaker.Available() {
+		t.Errorf("CircuitBreaker.Status() after reset == %+v, want closed", got)
+	}
+}
+
+func TestCircuitBreakerOneProbeAtATime(t *testing.T) {
+	clock := scheduler.NewFakeClock(time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC))
+	breaker := NewCircuitBreaker(&fakeDictService{}, clock)
+	breaker.state, breaker.openedAt = BreakerOpen, clock.Now()
+	clock.Advance(breaker.OpenFor)
+	if !breaker.allow() || breaker.allow() || breaker.Available() {
+		t.Errorf("CircuitBreaker let two probes through at once")
+	}
+	breaker.record(nil)
+	if !breaker.allow() {
+		t.Errorf("CircuitBreaker.allow() after a probe == false, want true")
+	}
+}
@@ -5,19 +5,73 @@ import (
 	"io/ioutil"
 	"net/http"
 	"sort"
+	"strings"
 
 	"github.com/buger/jsonparser"
 )
 
+const (
+	maxExamplesPerSense = 2
+	maxTranslations     = 5
+)
+
 type DictService interface {
 	FindDefinitions(word string) (string, error)
 	FindSynonyms(word string) (string, error)
+	FindAntonyms(word string) (string, error)
+	FindExamples(word string) (string, error)
+	FindPronunciations(word string) (string, error)
+	Lookup(word string, options LookupOptions) (Entry, error)
+}
+
+// LookupOptions tailor a lookup to a user's settings.
+type LookupOptions struct {
+	Senses           int
+	SynonymsPerSense int
+	Examples         bool
+	Pronunciation    bool
+	// Dialect is "gb" or "us", or "" for any
+	Dialect string
+	// TranslateTo is a language code, or "" for no translations
+	TranslateTo string
+}
+
+// Calls returns how many calls to the dictionary API a lookup makes: the
+// entry, its synonyms and, when asked for, its translations.
+func (this LookupOptions) Calls() int {
+	if this.TranslateTo != "" {
+		return 3
+	}
+	return 2
+}
+
+var DefaultLookupOptions = LookupOptions{
+	Senses:           1,
+	SynonymsPerSense: 5,
+}
+
+type Sense struct {
+	Definition string
+	Examples   []string
+}
+
+// Entry is the result of a lookup. An entry without senses means the word
+// wasn't found.
+type Entry struct {
+	Word           string
+	Senses         []Sense
+	Synonyms       []string
+	Pronunciations []string
+	AudioURL       string
+	Translations   []string
 }
 
 type OxfordService struct {
 	AppId          string
 	AppKey         string
 	EndpointPrefix string
+	// Client is used for API requests, or a default client when nil
+	Client *http.Client
 }
 
 func (this *OxfordService) UnmarshallDefinitions(data []byte) string {
@@ -50,96 +104,300 @@ func (this *OxfordService) UnmarshallDefinitions(data []byte) string {
 	return values
 }
 
-func (this *OxfordService) FindDefinitions(word string) (string, error) {
-	req, err := http.NewRequest("GET", this.EndpointPrefix+"/api/v1/entries/en/"+word, nil)
+func (this *OxfordService) get(path string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", this.EndpointPrefix+"/api/v1/entries/en/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("app_id", this.AppId)
 	req.Header.Add("app_key", this.AppKey)
-	client := &http.Client{}
-	res, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer res.Body.Close()
-	if res.StatusCode == http.StatusNotFound {
-		return "No definition for '" + word + "'.", nil
-	} else if res.StatusCode == http.StatusOK {
-		body, _ := ioutil.ReadAll(res.Body)
-		return this.UnmarshallDefinitions(body), nil
-	} else {
-		body, _ := ioutil.ReadAll(res.Body)
-		return "", errors.New(string(body))
+	client := this.Client
+	if client == nil {
+		client = &http.Client{}
 	}
+	return client.Do(req)
 }
 
-func (this *OxfordService) UnmarshallSynonyms(data []byte) string {
-	count := 0
-	values := map[string]int{}
+func (this *OxfordService) FindDefinitions(word string) (string, error) {
+	return this.find(word, this.UnmarshallDefinitions)
+}
+
+func (this *OxfordService) eachSense(data []byte, callback func(sense []byte)) {
 	jsonparser.ArrayEach(data, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
 		jsonparser.ArrayEach(value, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
 			jsonparser.ArrayEach(value, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
 				jsonparser.ArrayEach(value, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+					callback(value)
 					jsonparser.ArrayEach(value, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
-						if count >= 5 {
-							return
-						}
-						val, err := jsonparser.GetString(value, "text")
-						if err == nil {
-							values[val] = 0
-							count++
-						}
-					}, "synonyms")
-					jsonparser.ArrayEach(value, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
-						jsonparser.ArrayEach(value, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
-							if count >= 5 {
-								return
-							}
-							val, err := jsonparser.GetString(value, "text")
-							if err == nil {
-								values[val] = 0
-								count++
-							}
-						}, "synonyms")
+						callback(value)
 					}, "subsenses")
 				}, "senses")
 			}, "entries")
 		}, "lexicalEntries")
 	}, "results")
+}
+
+func (this *OxfordService) unmarshallSenseTexts(data []byte, key string, max int) []string {
+	values := []string{}
+	this.eachSense(data, func(sense []byte) {
+		jsonparser.ArrayEach(sense, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+			if len(values) >= max {
+				return
+			}
+			val, err := jsonparser.GetString(value, "text")
+			if err == nil {
+				values = append(values, val)
+			}
+		}, key)
+	})
+	return values
+}
+
+func (this *OxfordService) UnmarshallSynonyms(data []byte) string {
+	values := map[string]int{}
+	for _, val := range this.unmarshallSenseTexts(data, "synonyms", 5) {
+		values[val] = 0
+	}
 	return this.MapToString(values)
 }
 
-func (this *OxfordService) FindSynonyms(word string) (string, error) {
-	req, err := http.NewRequest("GET", this.EndpointPrefix+"/api/v1/entries/en/"+word+"/synonyms", nil)
-	req.Header.Add("Accept", "application/json")
-	req.Header.Add("app_id", this.AppId)
-	req.Header.Add("app_key", this.AppKey)
-	client := &http.Client{}
-	res, err := client.Do(req)
+func (this *OxfordService) UnmarshallAntonyms(data []byte) string {
+	values := map[string]int{}
+	for _, val := range this.unmarshallSenseTexts(data, "antonyms", 5) {
+		values[val] = 0
+	}
+	return this.MapToString(values)
+}
+
+func (this *OxfordService) UnmarshallExamples(data []byte) string {
+	values := this.unmarshallSenseTexts(data, "examples", 3)
+	for i := range values {
+		values[i] = "- " + values[i]
+	}
+	return strings.Join(values, "\n")
+}
+
+func (this *OxfordService) UnmarshallPronunciations(data []byte) string {
+	spellings, audioFile := this.unmarshallPronunciationList(data)
+	text := strings.Join(spellings, ", ")
+	if audioFile != "" {
+		text += "\n" + audioFile
+	}
+	return text
+}
+
+func (this *OxfordService) unmarshallPronunciationList(data []byte) ([]string, string) {
+	spellings := []string{}
+	audioFile := ""
+	collect := func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		spelling, err := jsonparser.GetString(value, "phoneticSpelling")
+		if err == nil {
+			found := false
+			for _, s := range spellings {
+				if s == "/"+spelling+"/" {
+					found = true
+				}
+			}
+			if !found {
+				spellings = append(spellings, "/"+spelling+"/")
+			}
+		}
+		if audioFile == "" {
+			audioFile, _ = jsonparser.GetString(value, "audioFile")
+		}
+	}
+	jsonparser.ArrayEach(data, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		jsonparser.ArrayEach(value, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+			jsonparser.ArrayEach(value, collect, "pronunciations")
+			jsonparser.ArrayEach(value, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+				jsonparser.ArrayEach(value, collect, "pronunciations")
+			}, "entries")
+		}, "lexicalEntries")
+	}, "results")
+	return spellings, audioFile
+}
+
+// eachTopSense calls back with the top level senses only, without their
+// subsenses.
+func (this *OxfordService) eachTopSense(data []byte, callback func(sense []byte)) {
+	jsonparser.ArrayEach(data, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		jsonparser.ArrayEach(value, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+			jsonparser.ArrayEach(value, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+				jsonparser.ArrayEach(value, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+					callback(value)
+				}, "senses")
+			}, "entries")
+		}, "lexicalEntries")
+	}, "results")
+}
+
+func (this *OxfordService) texts(data []byte, key string, max int) []string {
+	values := []string{}
+	jsonparser.ArrayEach(data, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+		if len(values) >= max {
+			return
+		}
+		if val, err := jsonparser.GetString(value, "text"); err == nil {
+			values = append(values, val)
+		}
+	}, key)
+	return values
+}
+
+// UnmarshallSenses returns up to max senses. A sense defined only through
+// its subsenses takes the definition of the first one.
+func (this *OxfordService) UnmarshallSenses(data []byte, max int, examples int) []Sense {
+	senses := []Sense{}
+	this.eachTopSense(data, func(value []byte) {
+		if len(senses) >= max {
+			return
+		}
+		definition, err := jsonparser.GetString(value, "definitions", "[0]")
+		if err != nil {
+			definition, err = jsonparser.GetString(value, "subsenses", "[0]", "definitions", "[0]")
+		}
+		if err != nil {
+			return
+		}
+		senses = append(senses, Sense{Definition: definition, Examples: this.texts(value, "examples", examples)})
+	})
+	return senses
+}
+
+// UnmarshallSenseSynonyms returns up to perSense synonyms from each of the
+// first senses, subsenses included, without repeats.
+func (this *OxfordService) UnmarshallSenseSynonyms(data []byte, senses int, perSense int) []string {
+	synonyms := []string{}
+	seen := map[string]bool{}
+	count := 0
+	this.eachTopSense(data, func(value []byte) {
+		if count >= senses {
+			return
+		}
+		count++
+		values := this.texts(value, "synonyms", perSense)
+		jsonparser.ArrayEach(value, func(value []byte, dataType jsonparser.ValueType, offset int, err error) {
+			values = append(values, this.texts(value, "synonyms", perSense)...)
+		}, "subsenses")
+		added := 0
+		for _, val := range values {
+			if added < perSense && !seen[val] {
+				seen[val] = true
+				synonyms = append(synonyms, val)
+				added++
+			}
+		}
+	})
+	return synonyms
+}
+
+func (this *OxfordService) UnmarshallTranslations(data []byte, max int) []string {
+	translations := []string{}
+	seen := map[string]bool{}
+	this.eachSense(data, func(sense []byte) {
+		for _, val := range this.texts(sense, "translations", max) {
+			if len(translations) < max && !seen[val] {
+				seen[val] = true
+				translations = append(translations, val)
+			}
+		}
+	})
+	return translations
+}
+
+// fetch returns the body of a successful API response.
+func (this *OxfordService) fetch(path string) ([]byte, error) {
+	res, err := this.get(path)
 	if err != nil {
-		return "", err
+		return nil, &Error{Kind: ErrNetwork, Err: err}
 	}
 	defer res.Body.Close()
-	if res.StatusCode == http.StatusNotFound {
-		return "No synonyms for '" + word + "'.", nil
-	} else if res.StatusCode == http.StatusOK {
-		body, _ := ioutil.ReadAll(res.Body)
-		return this.UnmarshallSynonyms(body), nil
-	} else {
-		body, _ := ioutil.ReadAll(res.Body)
-		return "", errors.New(string(body))
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, &Error{Kind: ErrNetwork, StatusCode: res.StatusCode, Err: err}
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, statusError(res.StatusCode, body)
+	}
+	return body, nil
+}
+
+func (this *OxfordService) Lookup(word string, options LookupOptions) (Entry, error) {
+	entry := Entry{Word: word}
+	path := word
+	if options.Dialect != "" {
+		path += "/regions=" + options.Dialect
+	}
+	body, err := this.fetch(path)
+	if errors.Is(err, ErrNotFound) {
+		return entry, nil
+	} else if err != nil {
+		return entry, err
+	}
+	examples := 0
+	if options.Examples {
+		examples = maxExamplesPerSense
+	}
+	entry.Senses = this.UnmarshallSenses(body, options.Senses, examples)
+	if options.Pronunciation {
+		entry.Pronunciations, entry.AudioURL = this.unmarshallPronunciationList(body)
+	}
+	if body, err = this.fetch(word + "/synonyms"); err == nil {
+		entry.Synonyms = this.UnmarshallSenseSynonyms(body, options.Senses, options.SynonymsPerSense)
+	} else if !errors.Is(err, ErrNotFound) {
+		return Entry{}, err
+	}
+	if options.TranslateTo != "" {
+		if body, err = this.fetch(word + "/translations=" + options.TranslateTo); err == nil {
+			entry.Translations = this.UnmarshallTranslations(body, maxTranslations)
+		} else if !errors.Is(err, ErrNotFound) {
+			return Entry{}, err
+		}
+	}
+	return entry, nil
+}
+
+func (this *OxfordService) FindSynonyms(word string) (string, error) {
+	return this.find(word+"/synonyms", this.UnmarshallSynonyms)
+}
+
+func (this *OxfordService) FindAntonyms(word string) (string, error) {
+	return this.find(word+"/antonyms", this.UnmarshallAntonyms)
+}
+
+func (this *OxfordService) FindExamples(word string) (string, error) {
+	return this.find(word, this.UnmarshallExamples)
+}
+
+func (this *OxfordService) FindPronunciations(word string) (string, error) {
+	return this.find(word, this.UnmarshallPronunciations)
+}
+
+func (this *OxfordService) find(path string, unmarshall func(data []byte) string) (string, error) {
+	body, err := this.fetch(path)
+	if err != nil {
+		return "", err
 	}
+	return unmarshall(body), nil
 }
 
 func (this *OxfordService) MapToString(values map[string]int) string {
-	text := ""
-	i := 0
 	keys := []string{}
 	for k := range values {
 		keys = append(keys, k)
 	}
+	return JoinWords(keys)
+}
+
+// JoinWords sorts the words and joins them as "a, b and c".
+func JoinWords(words []string) string {
+	text := ""
+	i := 0
+	keys := append([]string{}, words...)
 	sort.Strings(keys)
 	for _, k := range keys {
-		if i == len(keys)-1 {
+		if i != 0 && i == len(keys)-1 {
 			text += " and "
 		} else if i != 0 {
 			text += ", "
@@ -1,7 +1,10 @@
 package service
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -30,6 +33,12 @@ func TestOxfordServiceMapToString(t *testing.T) {
 			},
 			"1, 2, 3, 4 and 5",
 		},
+		{
+			map[string]int{
+				"1": 0,
+			},
+			"1",
+		},
 		{
 			map[string]int{},
 			"",
@@ -136,8 +145,8 @@ func TestOxfordServiceFindDefinitions(t *testing.T) {
 		},
 		{
 			"choopong",
-			"No definition for 'choopong'.",
-			nil,
+			"",
+			ErrNotFound,
 		},
 	}
 	for _, c := range cases {
@@ -197,8 +206,8 @@ func TestOxfordServiceFindSynonyms(t *testing.T) {
 		},
 		{
 			"choopong",
-			"No synonyms for 'choopong'.",
-			nil,
+			"",
+			ErrNotFound,
 		},
 	}
 	for _, c := range cases {
@@ -238,3 +247,198 @@ func TestOxfordServiceFindSynonyms(t *testing.T) {
 		t.Errorf("OxfordService.FindSynonyms(%q) == %q , want %q", word, err, wantErr)
 	}
 }
+
+func TestOxfordServiceUnmarshallAntonyms(t *testing.T) {
+	cases := []struct {
+		in   []byte
+		want string
+	}{
+		{
+			[]byte(""),
+			"",
+		},
+		{
+			[]byte(`{"results": [{"lexicalEntries": [{"entries": [{"senses": [{"antonyms": [{"text": "dry"}, {"text": "arid"}], "subsenses": [{"antonyms": [{"text": "parched"}]}]}]}]}]}]}`),
+			"arid, dry and parched",
+		},
+	}
+	for _, c := range cases {
+		service := &OxfordService{}
+		got := service.UnmarshallAntonyms(c.in)
+		if got != c.want {
+			t.Errorf("OxfordService.UnmarshallAntonyms(%q) == %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestOxfordServiceUnmarshallExamples(t *testing.T) {
+	cases := []struct {
+		in   []byte
+		want string
+	}{
+		{
+			[]byte(""),
+			"",
+		},
+		{
+			[]byte(`{"results": [{"lexicalEntries": [{"entries": [{"senses": [{"examples": [{"text": "1"}, {"text": "2"}], "subsenses": [{"examples": [{"text": "3"}, {"text": "4"}]}]}]}]}]}]}`),
+			"- 1\n- 2\n- 3",
+		},
+	}
+	for _, c := range cases {
+		service := &OxfordService{}
+		got := service.UnmarshallExamples(c.in)
+		if got != c.want {
+			t.Errorf("OxfordService.UnmarshallExamples(%q) == %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestOxfordServiceUnmarshallPronunciations(t *testing.T) {
+	cases := []struct {
+		in   []byte
+		want string
+	}{
+		{
+			[]byte(""),
+			"",
+		},
+		{
+			[]byte(`{"results": [{"lexicalEntries": [{"pronunciations": [{"audioFile": "http://audio/line.mp3", "phoneticSpelling": "lʌɪn"}, {"phoneticSpelling": "lʌɪn"}]}]}]}`),
+			"/lʌɪn/\nhttp://audio/line.mp3",
+		},
+		{
+			[]byte(`{"results": [{"lexicalEntries": [{"entries": [{"pronunciations": [{"phoneticSpelling": "skwɛː"}, {"phoneticSpelling": "skwɛr"}]}]}]}]}`),
+			"/skwɛː/, /skwɛr/",
+		},
+	}
+	for _, c := range cases {
+		service := &OxfordService{}
+		got := service.UnmarshallPronunciations(c.in)
+		if got != c.want {
+			t.Errorf("OxfordService.UnmarshallPronunciations(%q) == %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+const lineEntryJSON = `{"results": [{"lexicalEntries": [
+	{"pronunciations": [{"audioFile": "http://audio/line.mp3", "phoneticSpelling": "lʌɪn"}],
+	 "entries": [{"senses": [
+		{"definitions": ["a long, narrow mark or band"], "examples": [{"text": "a line of white paint"}, {"text": "draw a line"}, {"text": "lines on her face"}]},
+		{"subsenses": [{"definitions": ["a row of people or things"]}]},
+		{"crossReferenceMarkers": ["see queue"]},
+		{"definitions": ["a length of cord"]}
+	]}]}
+]}]}`
+
+func TestOxfordServiceUnmarshallSenses(t *testing.T) {
+	service := &OxfordService{}
+	cases := []struct {
+		max      int
+		examples int
+		want     []Sense
+	}{
+		{1, 0, []Sense{{Definition: "a long, narrow mark or band", Examples: []string{}}}},
+		{5, 2, []Sense{
+			{Definition: "a long, narrow mark or band", Examples: []string{"a line of white paint", "draw a line"}},
+			{Definition: "a row of people or things", Examples: []string{}},
+			{Definition: "a length of cord", Examples: []string{}},
+		}},
+	}
+	for _, c := range cases {
+		got := service.UnmarshallSenses([]byte(lineEntryJSON), c.max, c.examples)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("OxfordService.UnmarshallSenses(%d, %d) == %+v, want %+v", c.max, c.examples, got, c.want)
+		}
+	}
+	if got := service.UnmarshallSenses([]byte(""), 1, 0); len(got) != 0 {
+		t.Errorf("OxfordService.UnmarshallSenses(%q) == %+v, want none", "", got)
+	}
+}
+
+func TestOxfordServiceUnmarshallSenseSynonyms(t *testing.T) {
+	data := []byte(`{"results": [{"lexicalEntries": [{"entries": [{"senses": [
+		{"synonyms": [{"text": "bar"}, {"text": "dash"}, {"text": "rule"}], "subsenses": [{"synonyms": [{"text": "stripe"}, {"text": "bar"}]}]},
+		{"synonyms": [{"text": "queue"}, {"text": "row"}]},
+		{"synonyms": [{"text": "cord"}]}
+	]}]}]}]}`)
+	service := &OxfordService{}
+	cases := []struct {
+		senses   int
+		perSense int
+		want     []string
+	}{
+		{1, 5, []string{"bar", "dash", "rule", "stripe"}},
+		{1, 2, []string{"bar", "dash"}},
+		{2, 1, []string{"bar", "queue"}},
+		{5, 10, []string{"bar", "dash", "rule", "stripe", "queue", "row", "cord"}},
+	}
+	for _, c := range cases {
+		got := service.UnmarshallSenseSynonyms(data, c.senses, c.perSense)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("OxfordService.UnmarshallSenseSynonyms(%d, %d) == %q, want %q", c.senses, c.perSense, got, c.want)
+		}
+	}
+}
+
+func TestOxfordServiceUnmarshallTranslations(t *testing.T) {
+	data := []byte(`{"results": [{"lexicalEntries": [{"entries": [{"senses": [
+		{"translations": [{"language": "es", "text": "línea"}, {"language": "es", "text": "raya"}], "subsenses": [{"translations": [{"text": "fila"}, {"text": "línea"}]}]}
+	]}]}]}]}`)
+	service := &OxfordService{}
+	if got, want := service.UnmarshallTranslations(data, 5), []string{"línea", "raya", "fila"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("OxfordService.UnmarshallTranslations() == %q, want %q", got, want)
+	}
+	if got := service.UnmarshallTranslations(data, 1); len(got) != 1 {
+		t.Errorf("OxfordService.UnmarshallTranslations(1) returned %d translations", len(got))
+	}
+}
+
+func TestOxfordServiceLookup(t *testing.T) {
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		switch r.URL.Path {
+		case "/api/v1/entries/en/line", "/api/v1/entries/en/line/regions=us":
+			w.Write([]byte(lineEntryJSON))
+		case "/api/v1/entries/en/line/synonyms":
+			w.Write([]byte(`{"results": [{"lexicalEntries": [{"entries": [{"senses": [{"synonyms": [{"text": "bar"}]}, {"synonyms": [{"text": "queue"}]}]}]}]}]}`))
+		case "/api/v1/entries/en/line/translations=es":
+			w.Write([]byte(`{"results": [{"lexicalEntries": [{"entries": [{"senses": [{"translations": [{"text": "línea"}]}]}]}]}]}`))
+		case "/api/v1/entries/en/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("server error"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	service := &OxfordService{EndpointPrefix: server.URL}
+
+	entry, err := service.Lookup("line", DefaultLookupOptions)
+	want := Entry{Word: "line", Senses: []Sense{{Definition: "a long, narrow mark or band", Examples: []string{}}}, Synonyms: []string{"bar"}}
+	if err != nil || !reflect.DeepEqual(entry, want) {
+		t.Errorf("OxfordService.Lookup(%q) == %+v, %v, want %+v", "line", entry, err, want)
+	}
+	if strings.Join(requests, ",") != "/api/v1/entries/en/line,/api/v1/entries/en/line/synonyms" {
+		t.Errorf("OxfordService.Lookup(%q) requested %q", "line", requests)
+	}
+
+	requests = []string{}
+	options := LookupOptions{Senses: 2, SynonymsPerSense: 3, Examples: true, Pronunciation: true, Dialect: "us", TranslateTo: "es"}
+	entry, err = service.Lookup("line", options)
+	if err != nil || len(entry.Senses) != 2 || len(entry.Senses[0].Examples) != maxExamplesPerSense || !reflect.DeepEqual(entry.Synonyms, []string{"bar", "queue"}) ||
+		!reflect.DeepEqual(entry.Pronunciations, []string{"/lʌɪn/"}) || entry.AudioURL != "http://audio/line.mp3" || !reflect.DeepEqual(entry.Translations, []string{"línea"}) {
+		t.Errorf("OxfordService.Lookup(%q, %+v) == %+v, %v", "line", options, entry, err)
+	}
+	if requests[0] != "/api/v1/entries/en/line/regions=us" || len(requests) != 3 {
+		t.Errorf("OxfordService.Lookup(%q, %+v) requested %q", "line", options, requests)
+	}
+
+	if entry, err := service.Lookup("missing", DefaultLookupOptions); err != nil || entry.Word != "missing" || len(entry.Senses) != 0 {
+		t.Errorf("OxfordService.Lookup(%q) == %+v, %v, want no senses", "missing", entry, err)
+	}
+	if _, err := service.Lookup("broken", DefaultLookupOptions); err == nil || err.Error() != "server error" {
+		t.Errorf("OxfordService.Lookup(%q) == %v, want %q", "broken", err, "server error")
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Kinds of dictionary service errors. Errors returned by a DictService match
+// one of them with errors.Is.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrRateLimited   = errors.New("rate limited")
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	ErrAuth          = errors.New("authentication failed")
+	ErrNetwork       = errors.New("network error")
+	ErrUpstream      = errors.New("upstream error")
+	// ErrUnavailable is returned without calling the provider while it is
+	// considered down.
+	ErrUnavailable = errors.New("unavailable")
+)
+
+var kinds = []error{ErrNotFound, ErrRateLimited, ErrQuotaExceeded, ErrAuth, ErrNetwork, ErrUpstream, ErrUnavailable}
+
+type Error struct {
+	Kind       error
+	StatusCode int
+	// Message is the provider's description of the error, if any
+	Message string
+	Err     error
+}
+
+func (this *Error) Error() string {
+	if this.Message != "" {
+		return this.Message
+	}
+	if this.Err != nil {
+		return this.Err.Error()
+	}
+	return this.Kind.Error()
+}
+
+func (this *Error) Is(target error) bool {
+	return target == this.Kind
+}
+
+func (this *Error) Unwrap() error {
+	return this.Err
+}
+
+// statusError classifies an unsuccessful HTTP response.
+func statusError(statusCode int, body []byte) *Error {
+	err := &Error{Kind: ErrUpstream, StatusCode: statusCode, Message: strings.TrimSpace(string(body))}
+	switch {
+	case statusCode == http.StatusNotFound:
+		err.Kind, err.Message = ErrNotFound, ""
+	case statusCode == http.StatusTooManyRequests:
+		err.Kind = ErrRateLimited
+	case statusCode == http.StatusForbidden && strings.Contains(strings.ToLower(err.Message), "limit"):
+		err.Kind = ErrQuotaExceeded
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		err.Kind = ErrAuth
+	}
+	return err
+}
+
+// Category names the kind of an error for logs and metrics. Errors of no
+// known kind are "unknown".
+func Category(err error) string {
+	for _, kind := range kinds {
+		if errors.Is(err, kind) {
+			return strings.Replace(kind.Error(), " ", "_", -1)
+		}
+	}
+	return "unknown"
+}
+
+// Temporary reports whether a request that failed with err may succeed when
+// tried again.
+func Temporary(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrNetwork) || errors.Is(err, ErrUpstream)
+}
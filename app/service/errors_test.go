@@ -0,0 +1,57 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOxfordServiceErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/entries/en/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/api/v1/entries/en/busy":
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte("Too many requests"))
+		case "/api/v1/entries/en/monthly":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("Usage limits are exceeded"))
+		case "/api/v1/entries/en/denied":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("Authentication failed"))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("Bad gateway\n"))
+		}
+	}))
+	cases := []struct {
+		word      string
+		kind      error
+		message   string
+		category  string
+		temporary bool
+	}{
+		{"missing", ErrNotFound, "not found", "not_found", false},
+		{"busy", ErrRateLimited, "Too many requests", "rate_limited", true},
+		{"monthly", ErrQuotaExceeded, "Usage limits are exceeded", "quota_exceeded", false},
+		{"denied", ErrAuth, "Authentication failed", "authentication_failed", false},
+		{"broken", ErrUpstream, "Bad gateway", "upstream_error", true},
+	}
+	service := &OxfordService{EndpointPrefix: server.URL}
+	for _, c := range cases {
+		_, err := service.FindDefinitions(c.word)
+		if !errors.Is(err, c.kind) || err.Error() != c.message || Category(err) != c.category || Temporary(err) != c.temporary {
+			t.Errorf("OxfordService.FindDefinitions(%q) == %v (%s, temporary %v), want %v", c.word, err, Category(err), Temporary(err), c.kind)
+		}
+	}
+	server.Close()
+	_, err := service.FindSynonyms("line")
+	if !errors.Is(err, ErrNetwork) || !Temporary(err) {
+		t.Errorf("OxfordService.FindSynonyms() with the server down == %v, want %v", err, ErrNetwork)
+	}
+	if got := Category(errors.New("dummy")); got != "unknown" {
+		t.Errorf("Category(%q) == %q, want %q", "dummy", got, "unknown")
+	}
+}
@@ -0,0 +1,387 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/choobot/choo-dict-bot/app/scheduler"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+const (
+	maxCachedResults = 1000
+	// maxQueuedWarnings bounds the warnings waiting for OnWarning
+	maxQueuedWarnings = 16
+)
+
+// QuotaLimits are the calls a provider's plan allows. A limit of 0 means no
+// limit.
+type QuotaLimits struct {
+	PerMinute int `json:"per_minute"`
+	PerDay    int `json:"per_day"`
+	PerMonth  int `json:"per_month"`
+}
+
+type QuotaUsage struct {
+	Provider  string      `json:"provider"`
+	Minute    int         `json:"minute"`
+	Day       int         `json:"day"`
+	Month     int         `json:"month"`
+	Limits    QuotaLimits `json:"limits"`
+	Exhausted bool        `json:"exhausted"`
+}
+
+// QuotaWarning is raised once per period when a provider's usage reaches the
+// warning level, and again when it reaches the limit.
+type QuotaWarning struct {
+	Provider string
+	// Period is "day" or "month"
+	Period string
+	Used   int
+	Limit  int
+}
+
+type quotaCount struct {
+	period string
+	count  int
+	warned float64
+}
+
+type quotaUsage struct {
+	minute quotaCount
+	day    quotaCount
+	month  quotaCount
+}
+
+// QuotaManager counts the calls made to each provider per minute, day and
+// month, in UTC. Daily and monthly counts are kept in the store so that they
+// survive restarts.
+type QuotaManager struct {
+	Store store.QuotaStore
+	Clock scheduler.Clock
+	// WarnAt is the fraction of a limit at which OnWarning is called
+	WarnAt float64
+	// OnWarning is called in order on a goroutine of its own, so that calls
+	// being counted don't wait for the warnings to be sent
+	OnWarning func(warning QuotaWarning)
+	limits    map[string]QuotaLimits
+	usage     map[string]*quotaUsage
+	warnings  chan QuotaWarning
+	usageMux  sync.Mutex
+}
+
+func NewQuotaManager(quotaStore store.QuotaStore, clock scheduler.Clock) *QuotaManager {
+	quotas := &QuotaManager{
+		Store:    quotaStore,
+		Clock:    clock,
+		WarnAt:   0.8,
+		limits:   map[string]QuotaLimits{},
+		usage:    map[string]*quotaUsage{},
+		warnings: make(chan QuotaWarning, maxQueuedWarnings),
+	}
+	go quotas.warn()
+	return quotas
+}
+
+func (this *QuotaManager) SetLimits(provider string, limits QuotaLimits) {
+	this.usageMux.Lock()
+	defer this.usageMux.Unlock()
+	this.limits[provider] = limits
+}
+
+// Record counts one call to a provider. The store counts atomically, so it
+// is written without holding usageMux and calls don't queue behind its I/O.
+func (this *QuotaManager) Record(provider string) {
+	this.usageMux.Lock()
+	usage := this.current(provider)
+	usage.minute.count++
+	periods := []string{usage.day.period, usage.month.period}
+	this.usageMux.Unlock()
+
+	stored := make([]int, len(periods))
+	for i, period := range periods {
+		count, err := this.Store.Increment(provider, period)
+		if err != nil {
+			log.Println(err)
+			count = -1
+		}
+		stored[i] = count
+	}
+
+	this.usageMux.Lock()
+	usage = this.current(provider)
+	for i, count := range []*quotaCount{&usage.day, &usage.month} {
+		if count.period != periods[i] {
+			// The period ended while the store was written
+			continue
+		}
+		if stored[i] < 0 {
+			count.count++
+		} else if stored[i] > count.count {
+			// Concurrent calls can return from the store out of order
+			count.count = stored[i]
+		}
+	}
+	limits := this.limits[provider]
+	warnings := []QuotaWarning{}
+	if warning, ok := this.warning(provider, "day", &usage.day, limits.PerDay); ok {
+		warnings = append(warnings, warning)
+	}
+	if warning, ok := this.warning(provider, "month", &usage.month, limits.PerMonth); ok {
+		warnings = append(warnings, warning)
+	}
+	this.usageMux.Unlock()
+	for _, warning := range warnings {
+		select {
+		case this.warnings <- warning:
+		default:
+			log.Printf("dropped quota warning %+v, too many are waiting", warning)
+		}
+	}
+}
+
+func (this *QuotaManager) warn() {
+	for warning := range this.warnings {
+		if this.OnWarning != nil {
+			this.OnWarning(warning)
+		}
+	}
+}
+
+// Exhausted reports whether a provider has used up any of its limits.
+func (this *QuotaManager) Exhausted(provider string) bool {
+	this.usageMux.Lock()
+	defer this.usageMux.Unlock()
+	return this.exhausted(provider, this.current(provider))
+}
+
+// Usage returns the usage of every provider with limits.
+func (this *QuotaManager) Usage() []QuotaUsage {
+	this.usageMux.Lock()
+	defer this.usageMux.Unlock()
+	usages := []QuotaUsage{}
+	for provider, limits := range this.limits {
+		usage := this.current(provider)
+		usages = append(usages, QuotaUsage{
+			Provider:  provider,
+			Minute:    usage.minute.count,
+			Day:       usage.day.count,
+			Month:     usage.month.count,
+			Limits:    limits,
+			Exhausted: this.exhausted(provider, usage),
+		})
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		return usages[i].Provider < usages[j].Provider
+	})
+	return usages
+}
+
+// Transport counts every request to a provider that gets a response.
+func (this *QuotaManager) Transport(provider string, transport http.RoundTripper) http.RoundTripper {
+	return &quotaTransport{quotas: this, provider: provider, transport: transport}
+}
+
+// current returns a provider's usage in the current periods, starting new
+// periods as the clock passes them. The caller must hold usageMux.
+func (this *QuotaManager) current(provider string) *quotaUsage {
+	usage, ok := this.usage[provider]
+	if !ok {
+		usage = &quotaUsage{}
+		this.usage[provider] = usage
+	}
+	now := this.Clock.Now().UTC()
+	if period := now.Format("2006-01-02T15:04"); usage.minute.period != period {
+		usage.minute = quotaCount{period: period}
+	}
+	this.load(provider, &usage.day, "day "+now.Format("2006-01-02"))
+	this.load(provider, &usage.month, "month "+now.Format("2006-01"))
+	return usage
+}
+
+func (this *QuotaManager) load(provider string, count *quotaCount, period string) {
+	if count.period == period {
+		return
+	}
+	*count = quotaCount{period: period}
+	stored, err := this.Store.Get(provider, period)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	count.count = stored
+}
+
+func (this *QuotaManager) exhausted(provider string, usage *quotaUsage) bool {
+	limits := this.limits[provider]
+	return reached(usage.minute.count, limits.PerMinute) || reached(usage.day.count, limits.PerDay) || reached(usage.month.count, limits.PerMonth)
+}
+
+func (this *QuotaManager) warning(provider string, period string, count *quotaCount, limit int) (QuotaWarning, bool) {
+	if limit <= 0 {
+		return QuotaWarning{}, false
+	}
+	level := 0.0
+	if used := float64(count.count) / float64(limit); used >= 1 {
+		level = 1
+	} else if used >= this.WarnAt {
+		level = this.WarnAt
+	}
+	if level <= count.warned {
+		return QuotaWarning{}, false
+	}
+	count.warned = level
+	return QuotaWarning{Provider: provider, Period: period, Used: count.count, Limit: limit}, true
+}
+
+func reached(count int, limit int) bool {
+	return limit > 0 && count >= limit
+}
+
+type quotaTransport struct {
+	quotas    *QuotaManager
+	provider  string
+	transport http.RoundTripper
+}
+
+func (this *quotaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := this.transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	res, err := transport.RoundTrip(req)
+	if err == nil {
+		this.quotas.Record(this.provider)
+	}
+	return res, err
+}
+
+// QuotaService is a DictService that runs in degraded mode while its
+// provider's quota is exhausted. It then answers from the fallback provider
+// if there is one, or else from the results it has cached, and fails with
+// ErrQuotaExceeded for anything else.
+type QuotaService struct {
+	DictService DictService
+	Provider    string
+	Quotas      *QuotaManager
+	Fallback    DictService
+	cache       map[string]interface{}
+	cacheKeys   []string
+	cacheMux    sync.Mutex
+}
+
+func NewQuotaService(dictService DictService, provider string, quotas *QuotaManager) *QuotaService {
+	return &QuotaService{
+		DictService: dictService,
+		Provider:    provider,
+		Quotas:      quotas,
+		cache:       map[string]interface{}{},
+	}
+}
+
+func (this *QuotaService) FindDefinitions(word string) (string, error) {
+	return this.find("definitions "+word, func(dictService DictService) (string, error) {
+		return dictService.FindDefinitions(word)
+	})
+}
+
+func (this *QuotaService) FindSynonyms(word string) (string, error) {
+	return this.find("synonyms "+word, func(dictService DictService) (string, error) {
+		return dictService.FindSynonyms(word)
+	})
+}
+
+func (this *QuotaService) FindAntonyms(word string) (string, error) {
+	return this.find("antonyms "+word, func(dictService DictService) (string, error) {
+		return dictService.FindAntonyms(word)
+	})
+}
+
+func (this *QuotaService) FindExamples(word string) (string, error) {
+	return this.find("examples "+word, func(dictService DictService) (string, error) {
+		return dictService.FindExamples(word)
+	})
+}
+
+func (this *QuotaService) FindPronunciations(word string) (string, error) {
+	return this.find("pronunciations "+word, func(dictService DictService) (string, error) {
+		return dictService.FindPronunciations(word)
+	})
+}
+
+func (this *QuotaService) Lookup(word string, options LookupOptions) (Entry, error) {
+	res, err := this.call(fmt.Sprintf("lookup %s %+v", word, options), func(dictService DictService) (interface{}, error) {
+		return dictService.Lookup(word, options)
+	})
+	if err != nil {
+		return Entry{}, err
+	}
+	return res.(Entry), nil
+}
+
+// Available reports whether a request would be answered now. In degraded
+// mode the provider isn't called, so its own availability doesn't matter.
+func (this *QuotaService) Available() bool {
+	if breaker, ok := this.DictService.(interface{ Available() bool }); ok && !this.Degraded() {
+		return breaker.Available()
+	}
+	return true
+}
+
+// Degraded reports whether the provider's quota is exhausted.
+func (this *QuotaService) Degraded() bool {
+	return this.Quotas.Exhausted(this.Provider)
+}
+
+func (this *QuotaService) find(key string, find func(dictService DictService) (string, error)) (string, error) {
+	res, err := this.call(key, func(dictService DictService) (interface{}, error) {
+		return find(dictService)
+	})
+	if err != nil {
+		return "", err
+	}
+	return res.(string), nil
+}
+
+func (this *QuotaService) call(key string, run func(dictService DictService) (interface{}, error)) (interface{}, error) {
+	if !this.Degraded() {
+		res, err := run(this.DictService)
+		if err == nil {
+			this.store(key, res)
+			return res, nil
+		}
+		// The provider may count calls we don't, such as those made with
+		// the same key elsewhere
+		if !errors.Is(err, ErrQuotaExceeded) {
+			return nil, err
+		}
+	}
+	if this.Fallback != nil {
+		return run(this.Fallback)
+	}
+	this.cacheMux.Lock()
+	res, ok := this.cache[key]
+	this.cacheMux.Unlock()
+	if ok {
+		return res, nil
+	}
+	return nil, &Error{Kind: ErrQuotaExceeded, Message: this.Provider + " quota exhausted"}
+}
+
+// store caches a result, forgetting the oldest result when the cache is full.
+func (this *QuotaService) store(key string, res interface{}) {
+	this.cacheMux.Lock()
+	defer this.cacheMux.Unlock()
+	if _, ok := this.cache[key]; !ok {
+		if len(this.cacheKeys) >= maxCachedResults {
+			delete(this.cache, this.cacheKeys[0])
+			this.cacheKeys = this.cacheKeys[1:]
+		}
+		this.cacheKeys = append(this.cacheKeys, key)
+	}
+	this.cache[key] = res
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/choobot/choo-dict-bot/app/scheduler"
+	"github.com/choobot/choo-dict-bot/app/store"
+)
+
+func TestQuotaManager(t *testing.T) {
+	clock := scheduler.NewFakeClock(time.Date(2018, 11, 30, 23, 59, 0, 0, time.UTC))
+	quotaStore := store.NewMemoryQuotaStore()
+	quotas := NewQuotaManager(quotaStore, clock)
+	quotas.SetLimits("oxford", QuotaLimits{PerMinute: 2, PerDay: 5, PerMonth: 5})
+	warnings := make(chan QuotaWarning, 10)
+	quotas.OnWarning = func(warning QuotaWarning) {
+		warnings <- warning
+	}
+
+	quotas.Record("oxford")
+	if quotas.Exhausted("oxford") {
+		t.Errorf("QuotaManager.Exhausted() after 1 call == true, want false")
+	}
+	quotas.Record("oxford")
+	if !quotas.Exhausted("oxford") {
+		t.Errorf("QuotaManager.Exhausted() after 2 calls in a minute == false, want true")
+	}
+	clock.Advance(time.Minute)
+	if quotas.Exhausted("oxford") {
+		t.Errorf("QuotaManager.Exhausted() in the next minute == true, want false")
+	}
+	for i := 0; i < 2; i++ {
+		quotas.Record("oxford")
+	}
+	usage := quotas.Usage()
+	if len(usage) != 1 || usage[0].Minute != 2 || usage[0].Day != 2 || usage[0].Month != 2 {
+		t.Errorf("QuotaManager.Usage() on a new day and month == %+v", usage)
+	}
+
+	// Counts survive a restart
+	quotas = NewQuotaManager(quotaStore, clock)
+	quotas.SetLimits("oxford", QuotaLimits{PerDay: 5, PerMonth: 5})
+	quotas.OnWarning = func(warning QuotaWarning) {
+		warnings <- warning
+	}
+	for i := 0; i < 3; i++ {
+		quotas.Record("oxford")
+	}
+	if !quotas.Exhausted("oxford") {
+		t.Errorf("QuotaManager.Exhausted() after 5 calls in a month == false, want true")
+	}
+	want := []QuotaWarning{
+		{"oxford", "day", 4, 5},
+		{"oxford", "month", 4, 5},
+		{"oxford", "day", 5, 5},
+		{"oxford", "month", 5, 5},
+	}
+	for i := range want {
+		select {
+		case warning := <-warnings:
+			if warning != want[i] {
+				t.Errorf("QuotaManager warning %d == %+v, want %+v", i, warning, want[i])
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("QuotaManager sent %d warnings, want %+v", i, want)
+		}
+	}
+	if quotas.Exhausted("other") {
+		t.Errorf("QuotaManager.Exhausted() for a provider without limits == true, want false")
+	}
+}
+
+// blockingQuotaStore holds increments until release is closed.
+type blockingQuotaStore struct {
+	*store.MemoryQuotaStore
+	entered chan bool
+	release chan bool
+}
+
+func (this blockingQuotaStore) Increment(provider string, period string) (int, error) {
+	this.entered <- true
+	<-this.release
+	return this.MemoryQuotaStore.Increment(provider, period)
+}
+
+func TestQuotaManagerSlowStore(t *testing.T) {
+	quotaStore := blockingQuotaStore{store.NewMemoryQuotaStore(), make(chan bool, 100), make(chan bool)}
+	quotas := NewQuotaManager(quotaStore, scheduler.NewFakeClock(time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC)))
+	quotas.SetLimits("oxford", QuotaLimits{PerDay: 100})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			quotas.Record("oxford")
+		}()
+	}
+	// Other calls go on while the store is busy
+	<-quotaStore.entered
+	done := make(chan bool)
+	go func() {
+		quotas.Exhausted("oxford")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("QuotaManager.Exhausted() waited for the store")
+	}
+	close(quotaStore.release)
+	wg.Wait()
+	if usage := quotas.Usage(); len(usage) != 1 || usage[0].Day != 10 || usage[0].Month != 10 {
+		t.Errorf("QuotaManager.Usage() after 10 concurrent calls == %+v", usage)
+	}
+}
+
+func TestQuotaManagerSlowWarning(t *testing.T) {
+	quotas := NewQuotaManager(store.NewMemoryQuotaStore(), scheduler.NewFakeClock(time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC)))
+	quotas.SetLimits("oxford", QuotaLimits{PerDay: 1})
+	release := make(chan bool)
+	defer close(release)
+	quotas.OnWarning = func(warning QuotaWarning) {
+		<-release
+	}
+	done := make(chan bool)
+	go func() {
+		quotas.Record("oxford")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("QuotaManager.Record() waited for OnWarning")
+	}
+}
+
+func TestQuotaManagerTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	quotas := NewQuotaManager(store.NewMemoryQuotaStore(), scheduler.RealClock{})
+	quotas.SetLimits("oxford", QuotaLimits{})
+	client := &http.Client{Transport: quotas.Transport("oxford", nil)}
+	for i := 0; i < 3; i++ {
+		res, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+	if usage := quotas.Usage(); usage[0].Minute != 3 || usage[0].Month != 3 {
+		t.Errorf("QuotaManager.Usage() after 3 requests == %+v", usage)
+	}
+}
+
+func TestQuotaService(t *testing.T) {
+	clock := scheduler.NewFakeClock(time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC))
+	quotas := NewQuotaManager(store.NewMemoryQuotaStore(), clock)
+	quotas.SetLimits("oxford", QuotaLimits{PerDay: 1})
+	provider := &fakeDictService{}
+	quotaService := NewQuotaService(provider, "oxford", quotas)
+
+	if res, err := quotaService.FindDefinitions("line"); res != "line" || err != nil {
+		t.Errorf("QuotaService.FindDefinitions() == %q, %v", res, err)
+	}
+	quotas.Record("oxford")
+	if !quotaService.Degraded() {
+		t.Errorf("QuotaService.Degraded() with the quota used == false, want true")
+	}
+	if res, err := quotaService.FindDefinitions("line"); res != "line" || err != nil || provider.calls != 1 {
+		t.Errorf("QuotaService.FindDefinitions() of a cached word == %q, %v after %d calls", res, err, provider.calls)
+	}
+	if _, err := quotaService.FindSynonyms("line"); !errors.Is(err, ErrQuotaExceeded) || provider.calls != 1 {
+		t.Errorf("QuotaService.FindSynonyms() of an uncached word == %v after %d calls, want %v", err, provider.calls, ErrQuotaExceeded)
+	}
+
+	fallback := &fakeDictService{}
+	quotaService.Fallback = fallback
+	if entry, err := quotaService.Lookup("line", DefaultLookupOptions); entry.Word != "line" || err != nil || fallback.calls != 1 || provider.calls != 1 {
+		t.Errorf("QuotaService.Lookup() with a fallback == %+v, %v", entry, err)
+	}
+
+	// The provider can run out before we count it out
+	clock.Advance(24 * time.Hour)
+	provider.err = &Error{Kind: ErrQuotaExceeded}
+	if _, err := quotaService.FindAntonyms("line"); err != nil || fallback.calls != 2 {
+		t.Errorf("QuotaService.FindAntonyms() when the provider is out of quota == %v, want the fallback's answer", err)
+	}
+}
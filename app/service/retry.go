@@ -0,0 +1,139 @@
+package service
+
+import (
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryTransport retries requests that failed with a network error or a
+// 429, 502, 503 or 504 response, backing off exponentially with jitter.
+type RetryTransport struct {
+	// Transport makes the requests, or http.DefaultTransport when nil
+	Transport  http.RoundTripper
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	// Budget bounds the time spent on a request including retries. A request
+	// whose context has an earlier deadline stops retrying before it.
+	Budget time.Duration
+	// RetriesPerMinute caps retries across all requests so that they don't
+	// use up the provider's per-minute quota.
+	RetriesPerMinute int
+	// Random returns a number in [0, 1) to spread the delays.
+	Random      func() float64
+	retries     int
+	windowStart time.Time
+	retriesMux  sync.Mutex
+}
+
+func NewRetryTransport(transport http.RoundTripper) *RetryTransport {
+	return &RetryTransport{
+		Transport:        transport,
+		MaxRetries:       2,
+		BaseDelay:        200 * time.Millisecond,
+		MaxDelay:         2 * time.Second,
+		Budget:           5 * time.Second,
+		RetriesPerMinute: 10,
+		Random:           rand.Float64,
+	}
+}
+
+func (this *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := this.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	deadline := time.Now().Add(this.Budget)
+	if contextDeadline, ok := req.Context().Deadline(); ok && contextDeadline.Before(deadline) {
+		deadline = contextDeadline
+	}
+	for attempt := 0; ; attempt++ {
+		res, err := transport.RoundTrip(req)
+		if attempt >= this.MaxRetries || !retryable(req, res, err) {
+			return res, err
+		}
+		delay := this.backoff(attempt)
+		if res != nil {
+			if after, ok := retryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+				delay = after
+			}
+		}
+		if time.Now().Add(delay).After(deadline) || !this.takeRetry() {
+			return res, err
+		}
+		if res != nil {
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// backoff returns a random delay up to BaseDelay * 2^attempt, capped at
+// MaxDelay.
+func (this *RetryTransport) backoff(attempt int) time.Duration {
+	delay := this.BaseDelay << uint(attempt)
+	if delay > this.MaxDelay || delay <= 0 {
+		delay = this.MaxDelay
+	}
+	random := rand.Float64
+	if this.Random != nil {
+		random = this.Random
+	}
+	return time.Duration(random() * float64(delay))
+}
+
+func (this *RetryTransport) takeRetry() bool {
+	this.retriesMux.Lock()
+	defer this.retriesMux.Unlock()
+	if now := time.Now(); now.Sub(this.windowStart) >= time.Minute {
+		this.windowStart, this.retries = now, 0
+	}
+	if this.retries >= this.RetriesPerMinute {
+		return false
+	}
+	this.retries++
+	return true
+}
+
+func retryable(req *http.Request, res *http.Response, err error) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if err != nil {
+		return req.Context().Err() == nil
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header given in seconds or as a date.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := at.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport(t *testing.T) {
+	calls := map[string]int{}
+	callsMux := sync.Mutex{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		callsMux.Lock()
+		calls[r.URL.Path]++
+		call := calls[r.URL.Path]
+		callsMux.Unlock()
+		switch r.URL.Path {
+		case "/api/v1/entries/en/flaky":
+			if call < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(lineEntryJSON))
+		case "/api/v1/entries/en/later":
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/api/v1/entries/en/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+	newService := func() (*OxfordService, *RetryTransport) {
+		transport := NewRetryTransport(nil)
+		transport.BaseDelay, transport.MaxDelay = time.Millisecond, 5*time.Millisecond
+		return &OxfordService{EndpointPrefix: server.URL, Client: &http.Client{Transport: transport}}, transport
+	}
+	count := func(path string) int {
+		callsMux.Lock()
+		defer callsMux.Unlock()
+		return calls["/api/v1/entries/en/"+path]
+	}
+
+	service, transport := newService()
+	if got, err := service.FindDefinitions("flaky"); err != nil || got != "a long, narrow mark or band" || count("flaky") != 3 {
+		t.Errorf("OxfordService.FindDefinitions(%q) == %q, %v after %d calls", "flaky", got, err, count("flaky"))
+	}
+	if _, err := service.FindDefinitions("down"); !errors.Is(err, ErrUpstream) || count("down") != 1+transport.MaxRetries {
+		t.Errorf("OxfordService.FindDefinitions(%q) == %v after %d calls, want %d", "down", err, count("down"), 1+transport.MaxRetries)
+	}
+	if _, err := service.FindDefinitions("later"); !errors.Is(err, ErrRateLimited) || count("later") != 1 {
+		t.Errorf("OxfordService.FindDefinitions(%q) with Retry-After past the budget == %v after %d calls", "later", err, count("later"))
+	}
+	if _, err := service.FindDefinitions("broken"); !errors.Is(err, ErrUpstream) || count("broken") != 1 {
+		t.Errorf("OxfordService.FindDefinitions(%q) == %v after %d calls, want no retries", "broken", err, count("broken"))
+	}
+
+	service, transport = newService()
+	transport.RetriesPerMinute = 1
+	service.FindDefinitions("capped")
+	if got := count("capped"); got != 2 {
+		t.Errorf("OxfordService.FindDefinitions(%q) with 1 retry per minute made %d calls, want %d", "capped", got, 2)
+	}
+
+	_, transport = newService()
+	transport.BaseDelay, transport.MaxDelay = time.Second, time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequest("GET", server.URL+"/api/v1/entries/en/slow", nil)
+	start := time.Now()
+	res, err := transport.RoundTrip(req.WithContext(ctx))
+	if err != nil || res.StatusCode != http.StatusBadGateway || time.Since(start) > time.Second || count("slow") > 2 {
+		t.Errorf("RetryTransport.RoundTrip() with a 50ms deadline == %v, %v after %s and %d calls", res, err, time.Since(start), count("slow"))
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"Tue, 20 Nov 2018 10:00:30 GMT", 30 * time.Second, true},
+		{"Tue, 20 Nov 2018 09:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, c := range cases {
+		if got, ok := retryAfter(c.value, now); got != c.want || ok != c.ok {
+			t.Errorf("retryAfter(%q) == %s, %v, want %s, %v", c.value, got, ok, c.want, c.ok)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+)
+
+var ErrGroupNotFound = errors.New("group not found")
+
+const (
+	GroupModeMention = "mention"
+	GroupModeAll     = "all"
+	GroupModeOff     = "off"
+)
+
+// Group holds the settings of a group or room chat, keyed by its group or
+// room ID.
+type Group struct {
+	ID     string
+	Mode   string
+	Admins []string
+}
+
+func (this Group) IsAdmin(userID string) bool {
+	for _, admin := range this.Admins {
+		if admin == userID {
+			return true
+		}
+	}
+	return false
+}
+
+type GroupStore interface {
+	Get(groupID string) (Group, error)
+	Save(group Group) error
+}
+
+type MemoryGroupStore struct {
+	groups    map[string]Group
+	groupsMux sync.RWMutex
+}
+
+func NewMemoryGroupStore() *MemoryGroupStore {
+	return &MemoryGroupStore{
+		groups: map[string]Group{},
+	}
+}
+
+func (this *MemoryGroupStore) Get(groupID string) (Group, error) {
+	this.groupsMux.RLock()
+	defer this.groupsMux.RUnlock()
+	group, ok := this.groups[groupID]
+	if !ok {
+		return Group{}, ErrGroupNotFound
+	}
+	group.Admins = append([]string{}, group.Admins...)
+	return group, nil
+}
+
+func (this *MemoryGroupStore) Save(group Group) error {
+	this.groupsMux.Lock()
+	defer this.groupsMux.Unlock()
+	group.Admins = append([]string{}, group.Admins...)
+	this.groups[group.ID] = group
+	return nil
+}
+
+type SQLiteGroupStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteGroupStore(db *sql.DB) (*SQLiteGroupStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS chat_groups (
+			id TEXT PRIMARY KEY,
+			mode TEXT NOT NULL,
+			admins TEXT NOT NULL DEFAULT ''
+		)`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteGroupStore{db: db}, nil
+}
+
+func (this *SQLiteGroupStore) Get(groupID string) (Group, error) {
+	group := Group{}
+	admins := ""
+	err := this.db.QueryRow(`SELECT id, mode, admins FROM chat_groups WHERE id = ?`, groupID).Scan(&group.ID, &group.Mode, &admins)
+	if err == sql.ErrNoRows {
+		return Group{}, ErrGroupNotFound
+	} else if err != nil {
+		return Group{}, err
+	}
+	if admins != "" {
+		group.Admins = strings.Split(admins, ",")
+	}
+	return group, nil
+}
+
+func (this *SQLiteGroupStore) Save(group Group) error {
+	_, err := this.db.Exec(`INSERT OR REPLACE INTO chat_groups (id, mode, admins) VALUES (?, ?, ?)`, group.ID, group.Mode, strings.Join(group.Admins, ","))
+	return err
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testGroupStore(t *testing.T, name string, groups GroupStore) {
+	if _, err := groups.Get("group1"); err != ErrGroupNotFound {
+		t.Errorf("%s.Get(%q) == %v, want %v", name, "group1", err, ErrGroupNotFound)
+	}
+	cases := []Group{
+		{ID: "group1", Mode: GroupModeMention},
+		{ID: "group1", Mode: GroupModeAll, Admins: []string{"user1", "user2"}},
+		{ID: "room1", Mode: GroupModeOff, Admins: []string{"user3"}},
+	}
+	for _, want := range cases {
+		if err := groups.Save(want); err != nil {
+			t.Errorf("%s.Save(%+v) == %v, want %v", name, want, err, nil)
+		}
+		got, err := groups.Get(want.ID)
+		if err != nil || got.ID != want.ID || got.Mode != want.Mode || len(got.Admins) != len(want.Admins) || (len(want.Admins) > 0 && !reflect.DeepEqual(got.Admins, want.Admins)) {
+			t.Errorf("%s.Get(%q) == %+v, %v, want %+v", name, want.ID, got, err, want)
+		}
+	}
+}
+
+func TestMemoryGroupStore(t *testing.T) {
+	testGroupStore(t, "MemoryGroupStore", NewMemoryGroupStore())
+}
+
+func TestSQLiteGroupStore(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	groups, err := NewSQLiteGroupStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testGroupStore(t, "SQLiteGroupStore", groups)
+}
+
+func TestGroupIsAdmin(t *testing.T) {
+	group := Group{ID: "group1", Admins: []string{"user1"}}
+	if !group.IsAdmin("user1") || group.IsAdmin("user2") || group.IsAdmin("") {
+		t.Errorf("Group.IsAdmin() does not match admins %v", group.Admins)
+	}
+}
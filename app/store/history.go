@@ -0,0 +1,219 @@
+package store
+
+import (
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+)
+
+type HistoryEntry struct {
+	Word       string
+	LookedUpAt time.Time
+}
+
+type History interface {
+	Add(userID string, entry HistoryEntry) error
+	// List returns a page of entries, most recent first, and the total
+	// number of entries.
+	List(userID string, offset int, limit int) ([]HistoryEntry, int, error)
+	// RecentWords returns the most recently looked up words without repeats.
+	RecentWords(userID string, limit int) ([]string, error)
+	Since(userID string, since time.Time) ([]HistoryEntry, error)
+	// Users returns the users who looked something up since the given time.
+	Users(since time.Time) ([]string, error)
+	Clear(userID string) (int, error)
+}
+
+type MemoryHistory struct {
+	entries    map[string][]HistoryEntry
+	entriesMux sync.RWMutex
+}
+
+func NewMemoryHistory() *MemoryHistory {
+	return &MemoryHistory{
+		entries: map[string][]HistoryEntry{},
+	}
+}
+
+func (this *MemoryHistory) Add(userID string, entry HistoryEntry) error {
+	this.entriesMux.Lock()
+	defer this.entriesMux.Unlock()
+	this.entries[userID] = append(this.entries[userID], entry)
+	return nil
+}
+
+// newestFirst returns the user's entries from the most recent one. Entries
+// looked up at the same time keep the reverse of the order they were added.
+func (this *MemoryHistory) newestFirst(userID string) []HistoryEntry {
+	this.entriesMux.RLock()
+	defer this.entriesMux.RUnlock()
+	entries := []HistoryEntry{}
+	for i := len(this.entries[userID]) - 1; i >= 0; i-- {
+		entries = append(entries, this.entries[userID][i])
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].LookedUpAt.After(entries[j].LookedUpAt)
+	})
+	return entries
+}
+
+func (this *MemoryHistory) List(userID string, offset int, limit int) ([]HistoryEntry, int, error) {
+	entries := this.newestFirst(userID)
+	total := len(entries)
+	if offset >= total {
+		return []HistoryEntry{}, total, nil
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return entries[offset:end], total, nil
+}
+
+func (this *MemoryHistory) RecentWords(userID string, limit int) ([]string, error) {
+	words := []string{}
+	seen := map[string]bool{}
+	for _, entry := range this.newestFirst(userID) {
+		if len(words) == limit {
+			break
+		}
+		if !seen[entry.Word] {
+			seen[entry.Word] = true
+			words = append(words, entry.Word)
+		}
+	}
+	return words, nil
+}
+
+func (this *MemoryHistory) Since(userID string, since time.Time) ([]HistoryEntry, error) {
+	entries := []HistoryEntry{}
+	for _, entry := range this.newestFirst(userID) {
+		if !entry.LookedUpAt.Before(since) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (this *MemoryHistory) Users(since time.Time) ([]string, error) {
+	this.entriesMux.RLock()
+	defer this.entriesMux.RUnlock()
+	userIDs := []string{}
+	for userID, entries := range this.entries {
+		for _, entry := range entries {
+			if !entry.LookedUpAt.Before(since) {
+				userIDs = append(userIDs, userID)
+				break
+			}
+		}
+	}
+	sort.Strings(userIDs)
+	return userIDs, nil
+}
+
+func (this *MemoryHistory) Clear(userID string) (int, error) {
+	this.entriesMux.Lock()
+	defer this.entriesMux.Unlock()
+	cleared := len(this.entries[userID])
+	delete(this.entries, userID)
+	return cleared, nil
+}
+
+type SQLiteHistory struct {
+	db *sql.DB
+}
+
+func NewSQLiteHistory(db *sql.DB) (*SQLiteHistory, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS lookup_history (
+			user_id TEXT NOT NULL,
+			word TEXT NOT NULL,
+			looked_up_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS lookup_history_user ON lookup_history (user_id, looked_up_at);`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteHistory{db: db}, nil
+}
+
+func (this *SQLiteHistory) Add(userID string, entry HistoryEntry) error {
+	_, err := this.db.Exec(`INSERT INTO lookup_history (user_id, word, looked_up_at) VALUES (?, ?, ?)`, userID, entry.Word, unixTime(entry.LookedUpAt))
+	return err
+}
+
+func (this *SQLiteHistory) List(userID string, offset int, limit int) ([]HistoryEntry, int, error) {
+	total := 0
+	if err := this.db.QueryRow(`SELECT COUNT(*) FROM lookup_history WHERE user_id = ?`, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	entries, err := this.query(`SELECT word, looked_up_at FROM lookup_history WHERE user_id = ? ORDER BY looked_up_at DESC, rowid DESC LIMIT ? OFFSET ?`, userID, limit, offset)
+	return entries, total, err
+}
+
+func (this *SQLiteHistory) RecentWords(userID string, limit int) ([]string, error) {
+	rows, err := this.db.Query(`SELECT word FROM lookup_history WHERE user_id = ? GROUP BY word ORDER BY MAX(looked_up_at) DESC, MAX(rowid) DESC LIMIT ?`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	words := []string{}
+	for rows.Next() {
+		var word string
+		if err := rows.Scan(&word); err != nil {
+			return nil, err
+		}
+		words = append(words, word)
+	}
+	return words, rows.Err()
+}
+
+func (this *SQLiteHistory) Since(userID string, since time.Time) ([]HistoryEntry, error) {
+	return this.query(`SELECT word, looked_up_at FROM lookup_history WHERE user_id = ? AND looked_up_at >= ? ORDER BY looked_up_at DESC, rowid DESC`, userID, since.Unix())
+}
+
+func (this *SQLiteHistory) Users(since time.Time) ([]string, error) {
+	rows, err := this.db.Query(`SELECT DISTINCT user_id FROM lookup_history WHERE looked_up_at >= ? ORDER BY user_id`, since.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	userIDs := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+func (this *SQLiteHistory) Clear(userID string) (int, error) {
+	res, err := this.db.Exec(`DELETE FROM lookup_history WHERE user_id = ?`, userID)
+	if err != nil {
+		return 0, err
+	}
+	cleared, err := res.RowsAffected()
+	return int(cleared), err
+}
+
+func (this *SQLiteHistory) query(query string, args ...interface{}) ([]HistoryEntry, error) {
+	rows, err := this.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []HistoryEntry{}
+	for rows.Next() {
+		entry := HistoryEntry{}
+		var lookedUpAt int64
+		if err := rows.Scan(&entry.Word, &lookedUpAt); err != nil {
+			return nil, err
+		}
+		entry.LookedUpAt = fromUnixTime(lookedUpAt)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
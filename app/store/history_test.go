@@ -0,0 +1,81 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testHistory(t *testing.T, name string, history History) {
+	now := time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC)
+	lookups := []struct {
+		userID string
+		word   string
+		at     time.Time
+	}{
+		{"user1", "line", now.AddDate(0, 0, -8)},
+		{"user1", "square", now.Add(-2 * time.Hour)},
+		{"user1", "line", now.Add(-time.Hour)},
+		{"user1", "circle", now},
+		{"user1", "dot", now},
+		{"user2", "dot", now.AddDate(0, 0, -10)},
+	}
+	for _, lookup := range lookups {
+		if err := history.Add(lookup.userID, HistoryEntry{Word: lookup.word, LookedUpAt: lookup.at}); err != nil {
+			t.Errorf("%s.Add(%q, %q) == %v, want %v", name, lookup.userID, lookup.word, err, nil)
+		}
+	}
+	words := func(entries []HistoryEntry) string {
+		words := []string{}
+		for _, entry := range entries {
+			words = append(words, entry.Word)
+		}
+		return strings.Join(words, ",")
+	}
+	entries, total, err := history.List("user1", 0, 3)
+	if err != nil || total != 5 || words(entries) != "dot,circle,line" || !entries[2].LookedUpAt.Equal(now.Add(-time.Hour)) {
+		t.Errorf("%s.List(%q, 0, 3) == %+v, %d, %v", name, "user1", entries, total, err)
+	}
+	entries, _, _ = history.List("user1", 3, 3)
+	if words(entries) != "square,line" {
+		t.Errorf("%s.List(%q, 3, 3) == %q, want %q", name, "user1", words(entries), "square,line")
+	}
+	recent, err := history.RecentWords("user1", 10)
+	if err != nil || strings.Join(recent, ",") != "dot,circle,line,square" {
+		t.Errorf("%s.RecentWords(%q) == %q, %v", name, "user1", recent, err)
+	}
+	if recent, _ := history.RecentWords("user1", 2); len(recent) != 2 {
+		t.Errorf("%s.RecentWords(%q, 2) returned %d words", name, "user1", len(recent))
+	}
+	since, err := history.Since("user1", now.AddDate(0, 0, -7))
+	if err != nil || words(since) != "dot,circle,line,square" {
+		t.Errorf("%s.Since(%q) == %q, %v", name, "user1", words(since), err)
+	}
+	userIDs, err := history.Users(now.AddDate(0, 0, -7))
+	if err != nil || strings.Join(userIDs, ",") != "user1" {
+		t.Errorf("%s.Users() == %q, %v, want %q", name, userIDs, err, []string{"user1"})
+	}
+	if cleared, err := history.Clear("user1"); err != nil || cleared != 5 {
+		t.Errorf("%s.Clear(%q) == %d, %v, want %d", name, "user1", cleared, err, 5)
+	}
+	if _, total, _ := history.List("user1", 0, 10); total != 0 {
+		t.Errorf("%s.List(%q) after clear has %d entries", name, "user1", total)
+	}
+	if _, total, _ := history.List("user2", 0, 10); total != 1 {
+		t.Errorf("%s.List(%q) after clearing %q has %d entries, want %d", name, "user2", "user1", total, 1)
+	}
+}
+
+func TestMemoryHistory(t *testing.T) {
+	testHistory(t, "MemoryHistory", NewMemoryHistory())
+}
+
+func TestSQLiteHistory(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	history, err := NewSQLiteHistory(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testHistory(t, "SQLiteHistory", history)
+}
@@ -0,0 +1,183 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrEntryNotFound = errors.New("notebook entry not found")
+
+// NotebookEntry keeps the lookup result of a saved word so the notebook can
+// be shown without calling the dictionary again.
+type NotebookEntry struct {
+	Word        string
+	Definitions string
+	Synonyms    string
+	SavedAt     time.Time
+}
+
+type Notebook interface {
+	// Save adds the entry or replaces the saved entry of the same word.
+	Save(userID string, entry NotebookEntry) error
+	Get(userID string, word string) (NotebookEntry, error)
+	// List returns a page of entries, most recently saved first, and the
+	// total number of entries.
+	List(userID string, offset int, limit int) ([]NotebookEntry, int, error)
+	All(userID string) ([]NotebookEntry, error)
+	Remove(userID string, word string) error
+}
+
+type MemoryNotebook struct {
+	entries    map[string]map[string]NotebookEntry
+	entriesMux sync.RWMutex
+}
+
+func NewMemoryNotebook() *MemoryNotebook {
+	return &MemoryNotebook{
+		entries: map[string]map[string]NotebookEntry{},
+	}
+}
+
+func (this *MemoryNotebook) Save(userID string, entry NotebookEntry) error {
+	this.entriesMux.Lock()
+	defer this.entriesMux.Unlock()
+	if this.entries[userID] == nil {
+		this.entries[userID] = map[string]NotebookEntry{}
+	}
+	this.entries[userID][entry.Word] = entry
+	return nil
+}
+
+func (this *MemoryNotebook) Get(userID string, word string) (NotebookEntry, error) {
+	this.entriesMux.RLock()
+	defer this.entriesMux.RUnlock()
+	entry, ok := this.entries[userID][word]
+	if !ok {
+		return NotebookEntry{}, ErrEntryNotFound
+	}
+	return entry, nil
+}
+
+func (this *MemoryNotebook) List(userID string, offset int, limit int) ([]NotebookEntry, int, error) {
+	entries, _ := this.All(userID)
+	total := len(entries)
+	if offset >= total {
+		return []NotebookEntry{}, total, nil
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+	return entries[offset:end], total, nil
+}
+
+func (this *MemoryNotebook) All(userID string) ([]NotebookEntry, error) {
+	this.entriesMux.RLock()
+	defer this.entriesMux.RUnlock()
+	entries := []NotebookEntry{}
+	for _, entry := range this.entries[userID] {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].SavedAt.Equal(entries[j].SavedAt) {
+			return entries[i].Word < entries[j].Word
+		}
+		return entries[i].SavedAt.After(entries[j].SavedAt)
+	})
+	return entries, nil
+}
+
+func (this *MemoryNotebook) Remove(userID string, word string) error {
+	this.entriesMux.Lock()
+	defer this.entriesMux.Unlock()
+	if _, ok := this.entries[userID][word]; !ok {
+		return ErrEntryNotFound
+	}
+	delete(this.entries[userID], word)
+	return nil
+}
+
+type SQLiteNotebook struct {
+	db *sql.DB
+}
+
+func NewSQLiteNotebook(db *sql.DB) (*SQLiteNotebook, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS notebook_entries (
+			user_id TEXT NOT NULL,
+			word TEXT NOT NULL,
+			definitions TEXT NOT NULL,
+			synonyms TEXT NOT NULL,
+			saved_at INTEGER NOT NULL,
+			PRIMARY KEY (user_id, word)
+		)`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteNotebook{db: db}, nil
+}
+
+func (this *SQLiteNotebook) Save(userID string, entry NotebookEntry) error {
+	_, err := this.db.Exec(`INSERT OR REPLACE INTO notebook_entries (user_id, word, definitions, synonyms, saved_at) VALUES (?, ?, ?, ?, ?)`,
+		userID, entry.Word, entry.Definitions, entry.Synonyms, unixTime(entry.SavedAt))
+	return err
+}
+
+func (this *SQLiteNotebook) Get(userID string, word string) (NotebookEntry, error) {
+	entries, err := this.query(`SELECT word, definitions, synonyms, saved_at FROM notebook_entries WHERE user_id = ? AND word = ?`, userID, word)
+	if err != nil {
+		return NotebookEntry{}, err
+	}
+	if len(entries) == 0 {
+		return NotebookEntry{}, ErrEntryNotFound
+	}
+	return entries[0], nil
+}
+
+func (this *SQLiteNotebook) List(userID string, offset int, limit int) ([]NotebookEntry, int, error) {
+	total := 0
+	if err := this.db.QueryRow(`SELECT COUNT(*) FROM notebook_entries WHERE user_id = ?`, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	entries, err := this.query(`SELECT word, definitions, synonyms, saved_at FROM notebook_entries WHERE user_id = ? ORDER BY saved_at DESC, word LIMIT ? OFFSET ?`, userID, limit, offset)
+	return entries, total, err
+}
+
+func (this *SQLiteNotebook) All(userID string) ([]NotebookEntry, error) {
+	return this.query(`SELECT word, definitions, synonyms, saved_at FROM notebook_entries WHERE user_id = ? ORDER BY saved_at DESC, word`, userID)
+}
+
+func (this *SQLiteNotebook) Remove(userID string, word string) error {
+	res, err := this.db.Exec(`DELETE FROM notebook_entries WHERE user_id = ? AND word = ?`, userID, word)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrEntryNotFound
+	}
+	return nil
+}
+
+func (this *SQLiteNotebook) query(query string, args ...interface{}) ([]NotebookEntry, error) {
+	rows, err := this.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []NotebookEntry{}
+	for rows.Next() {
+		entry := NotebookEntry{}
+		var savedAt int64
+		if err := rows.Scan(&entry.Word, &entry.Definitions, &entry.Synonyms, &savedAt); err != nil {
+			return nil, err
+		}
+		entry.SavedAt = fromUnixTime(savedAt)
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
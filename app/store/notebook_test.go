@@ -0,0 +1,77 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func testNotebook(t *testing.T, name string, notebook Notebook) {
+	savedAt := time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC)
+	for i, word := range []string{"line", "square", "circle"} {
+		entry := NotebookEntry{Word: word, Definitions: "definitions of " + word, Synonyms: "synonyms of " + word, SavedAt: savedAt.Add(time.Duration(i) * time.Minute)}
+		if err := notebook.Save("user1", entry); err != nil {
+			t.Errorf("%s.Save(%q) == %v, want %v", name, word, err, nil)
+		}
+	}
+	// Saving again replaces the entry
+	notebook.Save("user1", NotebookEntry{Word: "line", Definitions: "a long, narrow mark or band", SavedAt: savedAt.Add(time.Hour)})
+	notebook.Save("user2", NotebookEntry{Word: "dot", SavedAt: savedAt})
+
+	cases := []struct {
+		offset int
+		limit  int
+		want   []string
+	}{
+		{0, 2, []string{"line", "circle"}},
+		{2, 2, []string{"square"}},
+		{3, 2, []string{}},
+	}
+	for _, c := range cases {
+		entries, total, err := notebook.List("user1", c.offset, c.limit)
+		words := []string{}
+		for _, entry := range entries {
+			words = append(words, entry.Word)
+		}
+		if err != nil || total != 3 || len(words) != len(c.want) || (len(words) > 0 && words[0] != c.want[0]) {
+			t.Errorf("%s.List(%q, %d, %d) == %q, %d, %v, want %q, %d", name, "user1", c.offset, c.limit, words, total, err, c.want, 3)
+		}
+	}
+	entry, err := notebook.Get("user1", "square")
+	if err != nil || entry.Definitions != "definitions of square" || entry.Synonyms != "synonyms of square" {
+		t.Errorf("%s.Get(%q) == %+v, %v", name, "square", entry, err)
+	}
+	if _, err := notebook.Get("user2", "square"); err != ErrEntryNotFound {
+		t.Errorf("%s.Get(%q) == %v, want %v", name, "square", err, ErrEntryNotFound)
+	}
+	entries, err := notebook.All("user1")
+	if err != nil || len(entries) != 3 || entries[0].Definitions != "a long, narrow mark or band" || !entries[0].SavedAt.Equal(savedAt.Add(time.Hour)) {
+		t.Errorf("%s.All(%q) == %+v, %v", name, "user1", entries, err)
+	}
+
+	if err := notebook.Remove("user1", "square"); err != nil {
+		t.Errorf("%s.Remove(%q) == %v, want %v", name, "square", err, nil)
+	}
+	if err := notebook.Remove("user1", "square"); err != ErrEntryNotFound {
+		t.Errorf("%s.Remove(%q) == %v, want %v", name, "square", err, ErrEntryNotFound)
+	}
+	if err := notebook.Remove("user1", "dot"); err != ErrEntryNotFound {
+		t.Errorf("%s.Remove(%q) == %v, want %v", name, "dot", err, ErrEntryNotFound)
+	}
+	if _, total, _ := notebook.List("user1", 0, 10); total != 2 {
+		t.Errorf("%s.List(%q) total == %d, want %d", name, "user1", total, 2)
+	}
+}
+
+func TestMemoryNotebook(t *testing.T) {
+	testNotebook(t, "MemoryNotebook", NewMemoryNotebook())
+}
+
+func TestSQLiteNotebook(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	notebook, err := NewSQLiteNotebook(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testNotebook(t, "SQLiteNotebook", notebook)
+}
@@ -0,0 +1,163 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrSessionNotFound = errors.New("quiz session not found")
+
+// QuizSession is the question a user is currently answering. Nonce, unique
+// to each question, tells the answer buttons of an old question apart from
+// the current one.
+type QuizSession struct {
+	Nonce   string
+	Word    string
+	Choices []string
+	AskedAt time.Time
+}
+
+type QuizStats struct {
+	Played     int
+	Correct    int
+	Streak     int
+	BestStreak int
+}
+
+type QuizStore interface {
+	GetSession(userID string) (QuizSession, error)
+	SaveSession(userID string, session QuizSession) error
+	DeleteSession(userID string) error
+	// GetStats returns zero stats for users who have never played.
+	GetStats(userID string) (QuizStats, error)
+	SaveStats(userID string, stats QuizStats) error
+}
+
+type MemoryQuizStore struct {
+	sessions map[string]QuizSession
+	stats    map[string]QuizStats
+	mux      sync.RWMutex
+}
+
+func NewMemoryQuizStore() *MemoryQuizStore {
+	return &MemoryQuizStore{
+		sessions: map[string]QuizSession{},
+		stats:    map[string]QuizStats{},
+	}
+}
+
+func (this *MemoryQuizStore) GetSession(userID string) (QuizSession, error) {
+	this.mux.RLock()
+	defer this.mux.RUnlock()
+	session, ok := this.sessions[userID]
+	if !ok {
+		return QuizSession{}, ErrSessionNotFound
+	}
+	session.Choices = append([]string{}, session.Choices...)
+	return session, nil
+}
+
+func (this *MemoryQuizStore) SaveSession(userID string, session QuizSession) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	session.Choices = append([]string{}, session.Choices...)
+	this.sessions[userID] = session
+	return nil
+}
+
+func (this *MemoryQuizStore) DeleteSession(userID string) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	delete(this.sessions, userID)
+	return nil
+}
+
+func (this *MemoryQuizStore) GetStats(userID string) (QuizStats, error) {
+	this.mux.RLock()
+	defer this.mux.RUnlock()
+	return this.stats[userID], nil
+}
+
+func (this *MemoryQuizStore) SaveStats(userID string, stats QuizStats) error {
+	this.mux.Lock()
+	defer this.mux.Unlock()
+	this.stats[userID] = stats
+	return nil
+}
+
+type SQLiteQuizStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteQuizStore(db *sql.DB) (*SQLiteQuizStore, error) {
+	// Sessions numbered by round came before nonces, only the questions
+	// open at the upgrade are lost
+	if _, err := db.Exec(`SELECT round FROM quiz_sessions LIMIT 0`); err == nil {
+		if _, err := db.Exec(`DROP TABLE quiz_sessions`); err != nil {
+			return nil, err
+		}
+	}
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS quiz_sessions (
+			user_id TEXT PRIMARY KEY,
+			nonce TEXT NOT NULL,
+			word TEXT NOT NULL,
+			choices TEXT NOT NULL,
+			asked_at INTEGER NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS quiz_stats (
+			user_id TEXT PRIMARY KEY,
+			played INTEGER NOT NULL,
+			correct INTEGER NOT NULL,
+			streak INTEGER NOT NULL,
+			best_streak INTEGER NOT NULL
+		);`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteQuizStore{db: db}, nil
+}
+
+func (this *SQLiteQuizStore) GetSession(userID string) (QuizSession, error) {
+	session := QuizSession{}
+	var choices string
+	var askedAt int64
+	err := this.db.QueryRow(`SELECT nonce, word, choices, asked_at FROM quiz_sessions WHERE user_id = ?`, userID).Scan(&session.Nonce, &session.Word, &choices, &askedAt)
+	if err == sql.ErrNoRows {
+		return QuizSession{}, ErrSessionNotFound
+	} else if err != nil {
+		return QuizSession{}, err
+	}
+	session.Choices = strings.Split(choices, "\n")
+	session.AskedAt = fromUnixTime(askedAt)
+	return session, nil
+}
+
+func (this *SQLiteQuizStore) SaveSession(userID string, session QuizSession) error {
+	_, err := this.db.Exec(`INSERT OR REPLACE INTO quiz_sessions (user_id, nonce, word, choices, asked_at) VALUES (?, ?, ?, ?, ?)`,
+		userID, session.Nonce, session.Word, strings.Join(session.Choices, "\n"), unixTime(session.AskedAt))
+	return err
+}
+
+func (this *SQLiteQuizStore) DeleteSession(userID string) error {
+	_, err := this.db.Exec(`DELETE FROM quiz_sessions WHERE user_id = ?`, userID)
+	return err
+}
+
+func (this *SQLiteQuizStore) GetStats(userID string) (QuizStats, error) {
+	stats := QuizStats{}
+	err := this.db.QueryRow(`SELECT played, correct, streak, best_streak FROM quiz_stats WHERE user_id = ?`, userID).Scan(&stats.Played, &stats.Correct, &stats.Streak, &stats.BestStreak)
+	if err == sql.ErrNoRows {
+		return QuizStats{}, nil
+	}
+	return stats, err
+}
+
+func (this *SQLiteQuizStore) SaveStats(userID string, stats QuizStats) error {
+	_, err := this.db.Exec(`INSERT OR REPLACE INTO quiz_stats (user_id, played, correct, streak, best_streak) VALUES (?, ?, ?, ?, ?)`,
+		userID, stats.Played, stats.Correct, stats.Streak, stats.BestStreak)
+	return err
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testQuizStore(t *testing.T, name string, quizzes QuizStore) {
+	askedAt := time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC)
+	if _, err := quizzes.GetSession("user1"); err != ErrSessionNotFound {
+		t.Errorf("%s.GetSession(%q) == %v, want %v", name, "user1", err, ErrSessionNotFound)
+	}
+	session := QuizSession{Nonce: "3f2a", Word: "line", Choices: []string{"dot", "line", "square", "circle"}, AskedAt: askedAt}
+	if err := quizzes.SaveSession("user1", session); err != nil {
+		t.Errorf("%s.SaveSession(%q) == %v, want %v", name, "user1", err, nil)
+	}
+	got, err := quizzes.GetSession("user1")
+	if err != nil || got.Nonce != "3f2a" || got.Word != "line" || strings.Join(got.Choices, ",") != "dot,line,square,circle" || !got.AskedAt.Equal(askedAt) {
+		t.Errorf("%s.GetSession(%q) == %+v, %v", name, "user1", got, err)
+	}
+	if _, err := quizzes.GetSession("user2"); err != ErrSessionNotFound {
+		t.Errorf("%s.GetSession(%q) == %v, want %v", name, "user2", err, ErrSessionNotFound)
+	}
+	if err := quizzes.DeleteSession("user1"); err != nil {
+		t.Errorf("%s.DeleteSession(%q) == %v, want %v", name, "user1", err, nil)
+	}
+	if _, err := quizzes.GetSession("user1"); err != ErrSessionNotFound {
+		t.Errorf("%s.GetSession(%q) after delete == %v, want %v", name, "user1", err, ErrSessionNotFound)
+	}
+
+	if stats, err := quizzes.GetStats("user1"); err != nil || stats != (QuizStats{}) {
+		t.Errorf("%s.GetStats(%q) == %+v, %v, want zero stats", name, "user1", stats, err)
+	}
+	stats := QuizStats{Played: 5, Correct: 4, Streak: 2, BestStreak: 3}
+	if err := quizzes.SaveStats("user1", stats); err != nil {
+		t.Errorf("%s.SaveStats(%q) == %v, want %v", name, "user1", err, nil)
+	}
+	stats.Streak = 0
+	quizzes.SaveStats("user1", stats)
+	if got, err := quizzes.GetStats("user1"); err != nil || got != stats {
+		t.Errorf("%s.GetStats(%q) == %+v, %v, want %+v", name, "user1", got, err, stats)
+	}
+}
+
+func TestMemoryQuizStore(t *testing.T) {
+	testQuizStore(t, "MemoryQuizStore", NewMemoryQuizStore())
+}
+
+func TestSQLiteQuizStore(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	quizzes, err := NewSQLiteQuizStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testQuizStore(t, "SQLiteQuizStore", quizzes)
+}
+
+func TestSQLiteQuizStoreUpgrade(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	// Sessions numbered by round
+	if _, err := db.Exec(`CREATE TABLE quiz_sessions (user_id TEXT PRIMARY KEY, round INTEGER NOT NULL, word TEXT NOT NULL, choices TEXT NOT NULL, asked_at INTEGER NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	db.Exec(`INSERT INTO quiz_sessions VALUES ('user1', 3, 'line', 'dot', 0)`)
+	quizzes, err := NewSQLiteQuizStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testQuizStore(t, "SQLiteQuizStore", quizzes)
+}
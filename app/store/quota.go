@@ -0,0 +1,89 @@
+package store
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// QuotaStore keeps counts of the calls made to upstream providers. A period
+// names the window a count belongs to, such as "day 2018-11-20" or
+// "month 2018-11".
+type QuotaStore interface {
+	Increment(provider string, period string) (int, error)
+	Get(provider string, period string) (int, error)
+}
+
+type quotaKey struct {
+	provider string
+	period   string
+}
+
+type MemoryQuotaStore struct {
+	counts    map[quotaKey]int
+	countsMux sync.Mutex
+}
+
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{
+		counts: map[quotaKey]int{},
+	}
+}
+
+func (this *MemoryQuotaStore) Increment(provider string, period string) (int, error) {
+	this.countsMux.Lock()
+	defer this.countsMux.Unlock()
+	key := quotaKey{provider, period}
+	this.counts[key]++
+	return this.counts[key], nil
+}
+
+func (this *MemoryQuotaStore) Get(provider string, period string) (int, error) {
+	this.countsMux.Lock()
+	defer this.countsMux.Unlock()
+	return this.counts[quotaKey{provider, period}], nil
+}
+
+type SQLiteQuotaStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteQuotaStore(db *sql.DB) (*SQLiteQuotaStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS quota_usage (
+			provider TEXT NOT NULL,
+			period TEXT NOT NULL,
+			count INTEGER NOT NULL,
+			PRIMARY KEY (provider, period)
+		)`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteQuotaStore{db: db}, nil
+}
+
+func (this *SQLiteQuotaStore) Increment(provider string, period string) (int, error) {
+	tx, err := this.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`INSERT INTO quota_usage (provider, period, count) VALUES (?, ?, 1)
+		ON CONFLICT (provider, period) DO UPDATE SET count = count + 1`, provider, period); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	count := 0
+	if err := tx.QueryRow(`SELECT count FROM quota_usage WHERE provider = ? AND period = ?`, provider, period).Scan(&count); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+func (this *SQLiteQuotaStore) Get(provider string, period string) (int, error) {
+	count := 0
+	err := this.db.QueryRow(`SELECT count FROM quota_usage WHERE provider = ? AND period = ?`, provider, period).Scan(&count)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return count, err
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"testing"
+)
+
+func testQuotaStore(t *testing.T, name string, quotas QuotaStore) {
+	if count, err := quotas.Get("oxford", "day 2018-11-20"); err != nil || count != 0 {
+		t.Errorf("%s.Get() before any calls == %d, %v, want %d", name, count, err, 0)
+	}
+	for i := 1; i <= 3; i++ {
+		if count, err := quotas.Increment("oxford", "day 2018-11-20"); err != nil || count != i {
+			t.Errorf("%s.Increment() == %d, %v, want %d", name, count, err, i)
+		}
+	}
+	quotas.Increment("oxford", "day 2018-11-21")
+	quotas.Increment("fallback", "day 2018-11-20")
+	cases := []struct {
+		provider string
+		period   string
+		want     int
+	}{
+		{"oxford", "day 2018-11-20", 3},
+		{"oxford", "day 2018-11-21", 1},
+		{"fallback", "day 2018-11-20", 1},
+		{"oxford", "month 2018-11", 0},
+	}
+	for _, c := range cases {
+		if count, err := quotas.Get(c.provider, c.period); err != nil || count != c.want {
+			t.Errorf("%s.Get(%q, %q) == %d, %v, want %d", name, c.provider, c.period, count, err, c.want)
+		}
+	}
+}
+
+func TestMemoryQuotaStore(t *testing.T) {
+	testQuotaStore(t, "MemoryQuotaStore", NewMemoryQuotaStore())
+}
+
+func TestSQLiteQuotaStore(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	quotas, err := NewSQLiteQuotaStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testQuotaStore(t, "SQLiteQuotaStore", quotas)
+}
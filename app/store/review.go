@@ -0,0 +1,192 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrCardNotFound = errors.New("review card not found")
+
+// ReviewCard is the spaced repetition state of a saved word.
+type ReviewCard struct {
+	Word         string
+	Repetitions  int
+	IntervalDays int
+	EaseFactor   float64
+	Due          time.Time
+	LastReviewed time.Time
+}
+
+type ReviewStore interface {
+	Get(userID string, word string) (ReviewCard, error)
+	Save(userID string, card ReviewCard) error
+	Delete(userID string, word string) error
+	// Due returns the user's cards due at the given time, most overdue first.
+	Due(userID string, at time.Time, limit int) ([]ReviewCard, error)
+	// DueUsers returns the users having at least one card due.
+	DueUsers(at time.Time) ([]string, error)
+}
+
+type MemoryReviewStore struct {
+	cards    map[string]map[string]ReviewCard
+	cardsMux sync.RWMutex
+}
+
+func NewMemoryReviewStore() *MemoryReviewStore {
+	return &MemoryReviewStore{
+		cards: map[string]map[string]ReviewCard{},
+	}
+}
+
+func (this *MemoryReviewStore) Get(userID string, word string) (ReviewCard, error) {
+	this.cardsMux.RLock()
+	defer this.cardsMux.RUnlock()
+	card, ok := this.cards[userID][word]
+	if !ok {
+		return ReviewCard{}, ErrCardNotFound
+	}
+	return card, nil
+}
+
+func (this *MemoryReviewStore) Save(userID string, card ReviewCard) error {
+	this.cardsMux.Lock()
+	defer this.cardsMux.Unlock()
+	if this.cards[userID] == nil {
+		this.cards[userID] = map[string]ReviewCard{}
+	}
+	this.cards[userID][card.Word] = card
+	return nil
+}
+
+func (this *MemoryReviewStore) Delete(userID string, word string) error {
+	this.cardsMux.Lock()
+	defer this.cardsMux.Unlock()
+	delete(this.cards[userID], word)
+	return nil
+}
+
+func (this *MemoryReviewStore) Due(userID string, at time.Time, limit int) ([]ReviewCard, error) {
+	this.cardsMux.RLock()
+	defer this.cardsMux.RUnlock()
+	cards := []ReviewCard{}
+	for _, card := range this.cards[userID] {
+		if !card.Due.After(at) {
+			cards = append(cards, card)
+		}
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		if cards[i].Due.Equal(cards[j].Due) {
+			return cards[i].Word < cards[j].Word
+		}
+		return cards[i].Due.Before(cards[j].Due)
+	})
+	if len(cards) > limit {
+		cards = cards[:limit]
+	}
+	return cards, nil
+}
+
+func (this *MemoryReviewStore) DueUsers(at time.Time) ([]string, error) {
+	this.cardsMux.RLock()
+	defer this.cardsMux.RUnlock()
+	userIDs := []string{}
+	for userID, cards := range this.cards {
+		for _, card := range cards {
+			if !card.Due.After(at) {
+				userIDs = append(userIDs, userID)
+				break
+			}
+		}
+	}
+	sort.Strings(userIDs)
+	return userIDs, nil
+}
+
+type SQLiteReviewStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteReviewStore(db *sql.DB) (*SQLiteReviewStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS review_cards (
+			user_id TEXT NOT NULL,
+			word TEXT NOT NULL,
+			repetitions INTEGER NOT NULL,
+			interval_days INTEGER NOT NULL,
+			ease_factor REAL NOT NULL,
+			due INTEGER NOT NULL,
+			last_reviewed INTEGER NOT NULL,
+			PRIMARY KEY (user_id, word)
+		);
+		CREATE INDEX IF NOT EXISTS review_cards_due ON review_cards (due);`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteReviewStore{db: db}, nil
+}
+
+func (this *SQLiteReviewStore) Get(userID string, word string) (ReviewCard, error) {
+	cards, err := this.query(`SELECT word, repetitions, interval_days, ease_factor, due, last_reviewed FROM review_cards WHERE user_id = ? AND word = ?`, userID, word)
+	if err != nil {
+		return ReviewCard{}, err
+	}
+	if len(cards) == 0 {
+		return ReviewCard{}, ErrCardNotFound
+	}
+	return cards[0], nil
+}
+
+func (this *SQLiteReviewStore) Save(userID string, card ReviewCard) error {
+	_, err := this.db.Exec(`INSERT OR REPLACE INTO review_cards (user_id, word, repetitions, interval_days, ease_factor, due, last_reviewed) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		userID, card.Word, card.Repetitions, card.IntervalDays, card.EaseFactor, unixTime(card.Due), unixTime(card.LastReviewed))
+	return err
+}
+
+func (this *SQLiteReviewStore) Delete(userID string, word string) error {
+	_, err := this.db.Exec(`DELETE FROM review_cards WHERE user_id = ? AND word = ?`, userID, word)
+	return err
+}
+
+func (this *SQLiteReviewStore) Due(userID string, at time.Time, limit int) ([]ReviewCard, error) {
+	return this.query(`SELECT word, repetitions, interval_days, ease_factor, due, last_reviewed FROM review_cards WHERE user_id = ? AND due <= ? ORDER BY due, word LIMIT ?`, userID, at.Unix(), limit)
+}
+
+func (this *SQLiteReviewStore) DueUsers(at time.Time) ([]string, error) {
+	rows, err := this.db.Query(`SELECT DISTINCT user_id FROM review_cards WHERE due <= ? ORDER BY user_id`, at.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	userIDs := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+func (this *SQLiteReviewStore) query(query string, args ...interface{}) ([]ReviewCard, error) {
+	rows, err := this.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cards := []ReviewCard{}
+	for rows.Next() {
+		card := ReviewCard{}
+		var due, lastReviewed int64
+		if err := rows.Scan(&card.Word, &card.Repetitions, &card.IntervalDays, &card.EaseFactor, &due, &lastReviewed); err != nil {
+			return nil, err
+		}
+		card.Due = fromUnixTime(due)
+		card.LastReviewed = fromUnixTime(lastReviewed)
+		cards = append(cards, card)
+	}
+	return cards, rows.Err()
+}
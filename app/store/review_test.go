@@ -0,0 +1,64 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func testReviewStore(t *testing.T, name string, reviews ReviewStore) {
+	now := time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC)
+	if _, err := reviews.Get("user1", "line"); err != ErrCardNotFound {
+		t.Errorf("%s.Get(%q) == %v, want %v", name, "line", err, ErrCardNotFound)
+	}
+	cards := []struct {
+		userID string
+		card   ReviewCard
+	}{
+		{"user1", ReviewCard{Word: "line", Repetitions: 2, IntervalDays: 6, EaseFactor: 2.5, Due: now.Add(-time.Hour), LastReviewed: now.AddDate(0, 0, -6)}},
+		{"user1", ReviewCard{Word: "square", EaseFactor: 2.5, Due: now.Add(-2 * time.Hour)}},
+		{"user1", ReviewCard{Word: "circle", EaseFactor: 2.5, Due: now.Add(time.Hour)}},
+		{"user2", ReviewCard{Word: "dot", EaseFactor: 2.5, Due: now.Add(time.Hour)}},
+		{"user3", ReviewCard{Word: "dot", EaseFactor: 2.5, Due: now}},
+	}
+	for _, c := range cards {
+		if err := reviews.Save(c.userID, c.card); err != nil {
+			t.Errorf("%s.Save(%q, %q) == %v, want %v", name, c.userID, c.card.Word, err, nil)
+		}
+	}
+	card, err := reviews.Get("user1", "line")
+	if err != nil || card.Repetitions != 2 || card.IntervalDays != 6 || card.EaseFactor != 2.5 || !card.Due.Equal(now.Add(-time.Hour)) || !card.LastReviewed.Equal(now.AddDate(0, 0, -6)) {
+		t.Errorf("%s.Get(%q) == %+v, %v", name, "line", card, err)
+	}
+	due, err := reviews.Due("user1", now, 10)
+	if err != nil || len(due) != 2 || due[0].Word != "square" || due[1].Word != "line" {
+		t.Errorf("%s.Due(%q) == %+v, %v", name, "user1", due, err)
+	}
+	due, _ = reviews.Due("user1", now, 1)
+	if len(due) != 1 {
+		t.Errorf("%s.Due(%q, 1) returned %d cards", name, "user1", len(due))
+	}
+	userIDs, err := reviews.DueUsers(now)
+	if err != nil || len(userIDs) != 2 || userIDs[0] != "user1" || userIDs[1] != "user3" {
+		t.Errorf("%s.DueUsers() == %q, %v, want %q", name, userIDs, err, []string{"user1", "user3"})
+	}
+	if err := reviews.Delete("user1", "square"); err != nil {
+		t.Errorf("%s.Delete(%q) == %v, want %v", name, "square", err, nil)
+	}
+	if due, _ := reviews.Due("user1", now, 10); len(due) != 1 {
+		t.Errorf("%s.Due(%q) after delete returned %d cards", name, "user1", len(due))
+	}
+}
+
+func TestMemoryReviewStore(t *testing.T) {
+	testReviewStore(t, "MemoryReviewStore", NewMemoryReviewStore())
+}
+
+func TestSQLiteReviewStore(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	reviews, err := NewSQLiteReviewStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testReviewStore(t, "SQLiteReviewStore", reviews)
+}
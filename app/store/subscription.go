@@ -0,0 +1,178 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrSubscriptionNotFound = errors.New("subscription not found")
+
+// Subscription opts a user, group or room in to the word of the day.
+// LastSentOn is the date, as YYYY-MM-DD, of the last word sent.
+type Subscription struct {
+	ChatID       string
+	Group        bool
+	Level        string
+	SubscribedAt time.Time
+	LastSentOn   string
+}
+
+type SubscriptionStore interface {
+	Subscribe(subscription Subscription) error
+	Unsubscribe(chatID string) error
+	Get(chatID string) (Subscription, error)
+	List() ([]Subscription, error)
+	MarkSent(chatIDs []string, day string) error
+}
+
+type MemorySubscriptionStore struct {
+	subscriptions    map[string]Subscription
+	subscriptionsMux sync.RWMutex
+}
+
+func NewMemorySubscriptionStore() *MemorySubscriptionStore {
+	return &MemorySubscriptionStore{
+		subscriptions: map[string]Subscription{},
+	}
+}
+
+func (this *MemorySubscriptionStore) Subscribe(subscription Subscription) error {
+	this.subscriptionsMux.Lock()
+	defer this.subscriptionsMux.Unlock()
+	this.subscriptions[subscription.ChatID] = subscription
+	return nil
+}
+
+func (this *MemorySubscriptionStore) Unsubscribe(chatID string) error {
+	this.subscriptionsMux.Lock()
+	defer this.subscriptionsMux.Unlock()
+	if _, ok := this.subscriptions[chatID]; !ok {
+		return ErrSubscriptionNotFound
+	}
+	delete(this.subscriptions, chatID)
+	return nil
+}
+
+func (this *MemorySubscriptionStore) Get(chatID string) (Subscription, error) {
+	this.subscriptionsMux.RLock()
+	defer this.subscriptionsMux.RUnlock()
+	subscription, ok := this.subscriptions[chatID]
+	if !ok {
+		return Subscription{}, ErrSubscriptionNotFound
+	}
+	return subscription, nil
+}
+
+func (this *MemorySubscriptionStore) List() ([]Subscription, error) {
+	this.subscriptionsMux.RLock()
+	defer this.subscriptionsMux.RUnlock()
+	subscriptions := []Subscription{}
+	for _, subscription := range this.subscriptions {
+		subscriptions = append(subscriptions, subscription)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		return subscriptions[i].ChatID < subscriptions[j].ChatID
+	})
+	return subscriptions, nil
+}
+
+func (this *MemorySubscriptionStore) MarkSent(chatIDs []string, day string) error {
+	this.subscriptionsMux.Lock()
+	defer this.subscriptionsMux.Unlock()
+	for _, chatID := range chatIDs {
+		if subscription, ok := this.subscriptions[chatID]; ok {
+			subscription.LastSentOn = day
+			this.subscriptions[chatID] = subscription
+		}
+	}
+	return nil
+}
+
+type SQLiteSubscriptionStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteSubscriptionStore(db *sql.DB) (*SQLiteSubscriptionStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS subscriptions (
+			chat_id TEXT PRIMARY KEY,
+			is_group INTEGER NOT NULL,
+			level TEXT NOT NULL,
+			subscribed_at INTEGER NOT NULL,
+			last_sent_on TEXT NOT NULL
+		)`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteSubscriptionStore{db: db}, nil
+}
+
+func (this *SQLiteSubscriptionStore) Subscribe(subscription Subscription) error {
+	_, err := this.db.Exec(`INSERT OR REPLACE INTO subscriptions (chat_id, is_group, level, subscribed_at, last_sent_on) VALUES (?, ?, ?, ?, ?)`,
+		subscription.ChatID, subscription.Group, subscription.Level, unixTime(subscription.SubscribedAt), subscription.LastSentOn)
+	return err
+}
+
+func (this *SQLiteSubscriptionStore) Unsubscribe(chatID string) error {
+	res, err := this.db.Exec(`DELETE FROM subscriptions WHERE chat_id = ?`, chatID)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+func (this *SQLiteSubscriptionStore) Get(chatID string) (Subscription, error) {
+	subscriptions, err := this.query(`SELECT chat_id, is_group, level, subscribed_at, last_sent_on FROM subscriptions WHERE chat_id = ?`, chatID)
+	if err != nil {
+		return Subscription{}, err
+	}
+	if len(subscriptions) == 0 {
+		return Subscription{}, ErrSubscriptionNotFound
+	}
+	return subscriptions[0], nil
+}
+
+func (this *SQLiteSubscriptionStore) List() ([]Subscription, error) {
+	return this.query(`SELECT chat_id, is_group, level, subscribed_at, last_sent_on FROM subscriptions ORDER BY chat_id`)
+}
+
+func (this *SQLiteSubscriptionStore) MarkSent(chatIDs []string, day string) error {
+	tx, err := this.db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, chatID := range chatIDs {
+		if _, err := tx.Exec(`UPDATE subscriptions SET last_sent_on = ? WHERE chat_id = ?`, day, chatID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (this *SQLiteSubscriptionStore) query(query string, args ...interface{}) ([]Subscription, error) {
+	rows, err := this.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	subscriptions := []Subscription{}
+	for rows.Next() {
+		subscription := Subscription{}
+		var subscribedAt int64
+		if err := rows.Scan(&subscription.ChatID, &subscription.Group, &subscription.Level, &subscribedAt, &subscription.LastSentOn); err != nil {
+			return nil, err
+		}
+		subscription.SubscribedAt = fromUnixTime(subscribedAt)
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, rows.Err()
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func testSubscriptionStore(t *testing.T, name string, subscriptions SubscriptionStore) {
+	subscribedAt := time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC)
+	if _, err := subscriptions.Get("user1"); err != ErrSubscriptionNotFound {
+		t.Errorf("%s.Get(%q) == %v, want %v", name, "user1", err, ErrSubscriptionNotFound)
+	}
+	for _, subscription := range []Subscription{
+		{ChatID: "user1", Level: "curated", SubscribedAt: subscribedAt},
+		{ChatID: "group1", Group: true, Level: "hard", SubscribedAt: subscribedAt},
+		{ChatID: "user2", Level: "easy", SubscribedAt: subscribedAt},
+	} {
+		if err := subscriptions.Subscribe(subscription); err != nil {
+			t.Errorf("%s.Subscribe(%q) == %v, want %v", name, subscription.ChatID, err, nil)
+		}
+	}
+	subscriptions.Subscribe(Subscription{ChatID: "user1", Level: "medium", SubscribedAt: subscribedAt})
+	subscription, err := subscriptions.Get("user1")
+	if err != nil || subscription.Level != "medium" || !subscription.SubscribedAt.Equal(subscribedAt) || subscription.LastSentOn != "" {
+		t.Errorf("%s.Get(%q) == %+v, %v", name, "user1", subscription, err)
+	}
+	if err := subscriptions.MarkSent([]string{"user1", "group1", "unknown"}, "2018-11-20"); err != nil {
+		t.Errorf("%s.MarkSent() == %v, want %v", name, err, nil)
+	}
+	list, err := subscriptions.List()
+	if err != nil || len(list) != 3 || list[0].ChatID != "group1" || !list[0].Group || list[1].Group || list[0].LastSentOn != "2018-11-20" || list[1].LastSentOn != "2018-11-20" || list[2].LastSentOn != "" {
+		t.Errorf("%s.List() == %+v, %v", name, list, err)
+	}
+	if err := subscriptions.Unsubscribe("user2"); err != nil {
+		t.Errorf("%s.Unsubscribe(%q) == %v, want %v", name, "user2", err, nil)
+	}
+	if err := subscriptions.Unsubscribe("user2"); err != ErrSubscriptionNotFound {
+		t.Errorf("%s.Unsubscribe(%q) again == %v, want %v", name, "user2", err, ErrSubscriptionNotFound)
+	}
+	if list, _ := subscriptions.List(); len(list) != 2 {
+		t.Errorf("%s.List() after unsubscribe returned %d subscriptions", name, len(list))
+	}
+}
+
+func TestMemorySubscriptionStore(t *testing.T) {
+	testSubscriptionStore(t, "MemorySubscriptionStore", NewMemorySubscriptionStore())
+}
+
+func TestSQLiteSubscriptionStore(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	subscriptions, err := NewSQLiteSubscriptionStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testSubscriptionStore(t, "SQLiteSubscriptionStore", subscriptions)
+}
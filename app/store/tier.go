@@ -0,0 +1,126 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+var ErrTierNotFound = errors.New("tier not found")
+
+// TierStore assigns plan tiers to users and to groups or rooms, keyed by
+// their LINE IDs.
+type TierStore interface {
+	Get(id string) (string, error)
+	Set(id string, tier string) error
+	Remove(id string) error
+	List() (map[string]string, error)
+}
+
+type MemoryTierStore struct {
+	tiers    map[string]string
+	tiersMux sync.RWMutex
+}
+
+func NewMemoryTierStore() *MemoryTierStore {
+	return &MemoryTierStore{
+		tiers: map[string]string{},
+	}
+}
+
+func (this *MemoryTierStore) Get(id string) (string, error) {
+	this.tiersMux.RLock()
+	defer this.tiersMux.RUnlock()
+	tier, ok := this.tiers[id]
+	if !ok {
+		return "", ErrTierNotFound
+	}
+	return tier, nil
+}
+
+func (this *MemoryTierStore) Set(id string, tier string) error {
+	this.tiersMux.Lock()
+	defer this.tiersMux.Unlock()
+	this.tiers[id] = tier
+	return nil
+}
+
+func (this *MemoryTierStore) Remove(id string) error {
+	this.tiersMux.Lock()
+	defer this.tiersMux.Unlock()
+	if _, ok := this.tiers[id]; !ok {
+		return ErrTierNotFound
+	}
+	delete(this.tiers, id)
+	return nil
+}
+
+func (this *MemoryTierStore) List() (map[string]string, error) {
+	this.tiersMux.RLock()
+	defer this.tiersMux.RUnlock()
+	tiers := map[string]string{}
+	for id, tier := range this.tiers {
+		tiers[id] = tier
+	}
+	return tiers, nil
+}
+
+type SQLiteTierStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteTierStore(db *sql.DB) (*SQLiteTierStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS tiers (
+			id TEXT PRIMARY KEY,
+			tier TEXT NOT NULL
+		)`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteTierStore{db: db}, nil
+}
+
+func (this *SQLiteTierStore) Get(id string) (string, error) {
+	tier := ""
+	err := this.db.QueryRow(`SELECT tier FROM tiers WHERE id = ?`, id).Scan(&tier)
+	if err == sql.ErrNoRows {
+		return "", ErrTierNotFound
+	}
+	return tier, err
+}
+
+func (this *SQLiteTierStore) Set(id string, tier string) error {
+	_, err := this.db.Exec(`INSERT OR REPLACE INTO tiers (id, tier) VALUES (?, ?)`, id, tier)
+	return err
+}
+
+func (this *SQLiteTierStore) Remove(id string) error {
+	res, err := this.db.Exec(`DELETE FROM tiers WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return err
+	} else if affected == 0 {
+		return ErrTierNotFound
+	}
+	return nil
+}
+
+func (this *SQLiteTierStore) List() (map[string]string, error) {
+	rows, err := this.db.Query(`SELECT id, tier FROM tiers`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tiers := map[string]string{}
+	for rows.Next() {
+		id, tier := "", ""
+		if err := rows.Scan(&id, &tier); err != nil {
+			return nil, err
+		}
+		tiers[id] = tier
+	}
+	return tiers, rows.Err()
+}
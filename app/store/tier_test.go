@@ -0,0 +1,42 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testTierStore(t *testing.T, name string, tiers TierStore) {
+	if _, err := tiers.Get("user1"); err != ErrTierNotFound {
+		t.Errorf("%s.Get(%q) == %v, want %v", name, "user1", err, ErrTierNotFound)
+	}
+	tiers.Set("user1", "premium")
+	tiers.Set("group1", "staff")
+	tiers.Set("user1", "staff")
+	if tier, err := tiers.Get("user1"); err != nil || tier != "staff" {
+		t.Errorf("%s.Get(%q) == %q, %v, want %q", name, "user1", tier, err, "staff")
+	}
+	want := map[string]string{"user1": "staff", "group1": "staff"}
+	if list, err := tiers.List(); err != nil || !reflect.DeepEqual(list, want) {
+		t.Errorf("%s.List() == %v, %v, want %v", name, list, err, want)
+	}
+	if err := tiers.Remove("group1"); err != nil {
+		t.Errorf("%s.Remove(%q) == %v, want %v", name, "group1", err, nil)
+	}
+	if err := tiers.Remove("group1"); err != ErrTierNotFound {
+		t.Errorf("%s.Remove(%q) again == %v, want %v", name, "group1", err, ErrTierNotFound)
+	}
+}
+
+func TestMemoryTierStore(t *testing.T) {
+	testTierStore(t, "MemoryTierStore", NewMemoryTierStore())
+}
+
+func TestSQLiteTierStore(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	tiers, err := NewSQLiteTierStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTierStore(t, "SQLiteTierStore", tiers)
+}
@@ -0,0 +1,246 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+type User struct {
+	ID           string
+	DisplayName  string
+	PictureURL   string
+	Language     string
+	Active       bool
+	FollowedAt   time.Time
+	UnfollowedAt time.Time
+	Preferences  map[string]string
+}
+
+type UserRegistry interface {
+	// Follow records the user as active, keeping preferences of returning users.
+	Follow(user User) error
+	Unfollow(userID string, at time.Time) error
+	Get(userID string) (User, error)
+	SetPreference(userID string, key string, value string) error
+	// SavePreference is like SetPreference, but registers unknown users
+	// instead of failing. It never changes whether a user follows the bot.
+	SavePreference(userID string, key string, value string) error
+}
+
+type MemoryUserRegistry struct {
+	users    map[string]User
+	usersMux sync.RWMutex
+}
+
+func NewMemoryUserRegistry() *MemoryUserRegistry {
+	return &MemoryUserRegistry{
+		users: map[string]User{},
+	}
+}
+
+func (this *MemoryUserRegistry) Follow(user User) error {
+	this.usersMux.Lock()
+	defer this.usersMux.Unlock()
+	preferences := map[string]string{}
+	if existing, ok := this.users[user.ID]; ok {
+		preferences = existing.Preferences
+	}
+	for key, value := range user.Preferences {
+		preferences[key] = value
+	}
+	user.Preferences = preferences
+	user.Active = true
+	user.UnfollowedAt = time.Time{}
+	this.users[user.ID] = user
+	return nil
+}
+
+func (this *MemoryUserRegistry) Unfollow(userID string, at time.Time) error {
+	this.usersMux.Lock()
+	defer this.usersMux.Unlock()
+	user, ok := this.users[userID]
+	if !ok {
+		user = User{ID: userID, Preferences: map[string]string{}}
+	}
+	user.Active = false
+	user.UnfollowedAt = at
+	this.users[userID] = user
+	return nil
+}
+
+func (this *MemoryUserRegistry) Get(userID string) (User, error) {
+	this.usersMux.RLock()
+	defer this.usersMux.RUnlock()
+	user, ok := this.users[userID]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	preferences := map[string]string{}
+	for key, value := range user.Preferences {
+		preferences[key] = value
+	}
+	user.Preferences = preferences
+	return user, nil
+}
+
+func (this *MemoryUserRegistry) SetPreference(userID string, key string, value string) error {
+	this.usersMux.Lock()
+	defer this.usersMux.Unlock()
+	user, ok := this.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	user.Preferences[key] = value
+	return nil
+}
+
+func (this *MemoryUserRegistry) SavePreference(userID string, key string, value string) error {
+	this.usersMux.Lock()
+	defer this.usersMux.Unlock()
+	user, ok := this.users[userID]
+	if !ok {
+		// Users the registry hasn't seen follow the bot as far as it knows
+		user = User{ID: userID, Active: true, Preferences: map[string]string{}}
+		this.users[userID] = user
+	}
+	user.Preferences[key] = value
+	return nil
+}
+
+type SQLiteUserRegistry struct {
+	db *sql.DB
+}
+
+func NewSQLiteUserRegistry(db *sql.DB) (*SQLiteUserRegistry, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS users (
+			id TEXT PRIMARY KEY,
+			display_name TEXT NOT NULL DEFAULT '',
+			picture_url TEXT NOT NULL DEFAULT '',
+			language TEXT NOT NULL DEFAULT '',
+			active INTEGER NOT NULL DEFAULT 1,
+			followed_at INTEGER NOT NULL DEFAULT 0,
+			unfollowed_at INTEGER NOT NULL DEFAULT 0
+		);
+		CREATE TABLE IF NOT EXISTS user_preferences (
+			user_id TEXT NOT NULL,
+			key TEXT NOT NULL,
+			value TEXT NOT NULL,
+			PRIMARY KEY (user_id, key)
+		);`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteUserRegistry{db: db}, nil
+}
+
+func (this *SQLiteUserRegistry) Follow(user User) error {
+	tx, err := this.db.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO users (id, display_name, picture_url, language, active, followed_at, unfollowed_at)
+		VALUES (?, ?, ?, ?, 1, ?, 0)
+		ON CONFLICT (id) DO UPDATE SET
+			display_name = excluded.display_name,
+			picture_url = excluded.picture_url,
+			language = excluded.language,
+			active = 1,
+			followed_at = excluded.followed_at,
+			unfollowed_at = 0`,
+		user.ID, user.DisplayName, user.PictureURL, user.Language, unixTime(user.FollowedAt))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for key, value := range user.Preferences {
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO user_preferences (user_id, key, value) VALUES (?, ?, ?)`, user.ID, key, value); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (this *SQLiteUserRegistry) Unfollow(userID string, at time.Time) error {
+	_, err := this.db.Exec(`
+		INSERT INTO users (id, active, unfollowed_at) VALUES (?, 0, ?)
+		ON CONFLICT (id) DO UPDATE SET active = 0, unfollowed_at = excluded.unfollowed_at`,
+		userID, unixTime(at))
+	return err
+}
+
+func (this *SQLiteUserRegistry) Get(userID string) (User, error) {
+	user := User{Preferences: map[string]string{}}
+	var followedAt, unfollowedAt int64
+	err := this.db.QueryRow(`SELECT id, display_name, picture_url, language, active, followed_at, unfollowed_at FROM users WHERE id = ?`, userID).
+		Scan(&user.ID, &user.DisplayName, &user.PictureURL, &user.Language, &user.Active, &followedAt, &unfollowedAt)
+	if err == sql.ErrNoRows {
+		return User{}, ErrUserNotFound
+	} else if err != nil {
+		return User{}, err
+	}
+	user.FollowedAt = fromUnixTime(followedAt)
+	user.UnfollowedAt = fromUnixTime(unfollowedAt)
+	rows, err := this.db.Query(`SELECT key, value FROM user_preferences WHERE user_id = ?`, userID)
+	if err != nil {
+		return User{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return User{}, err
+		}
+		user.Preferences[key] = value
+	}
+	return user, rows.Err()
+}
+
+func (this *SQLiteUserRegistry) SetPreference(userID string, key string, value string) error {
+	var exists bool
+	if err := this.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE id = ?)`, userID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+	_, err := this.db.Exec(`INSERT OR REPLACE INTO user_preferences (user_id, key, value) VALUES (?, ?, ?)`, userID, key, value)
+	return err
+}
+
+func (this *SQLiteUserRegistry) SavePreference(userID string, key string, value string) error {
+	tx, err := this.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO users (id) VALUES (?)`, userID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO user_preferences (user_id, key, value) VALUES (?, ?, ?)`, userID, key, value); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Times are stored as Unix seconds, 0 meaning not set.
+func unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func fromUnixTime(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0).UTC()
+}
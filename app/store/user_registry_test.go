@@ -0,0 +1,96 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to ":memory:" opens a new database
+	db.SetMaxOpenConns(1)
+	return db
+}
+
+func testUserRegistry(t *testing.T, name string, registry UserRegistry) {
+	followedAt := time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC)
+	if _, err := registry.Get("user1"); err != ErrUserNotFound {
+		t.Errorf("%s.Get(%q) == %v, want %v", name, "user1", err, ErrUserNotFound)
+	}
+	if err := registry.SetPreference("user1", "lang", "th"); err != ErrUserNotFound {
+		t.Errorf("%s.SetPreference(%q) == %v, want %v", name, "user1", err, ErrUserNotFound)
+	}
+
+	err := registry.Follow(User{ID: "user1", DisplayName: "Choo", Language: "th", FollowedAt: followedAt})
+	if err != nil {
+		t.Errorf("%s.Follow(%q) == %v, want %v", name, "user1", err, nil)
+	}
+	if err := registry.SetPreference("user1", "senses", "3"); err != nil {
+		t.Errorf("%s.SetPreference(%q) == %v, want %v", name, "user1", err, nil)
+	}
+	user, err := registry.Get("user1")
+	if err != nil || user.DisplayName != "Choo" || user.Language != "th" || !user.Active || !user.FollowedAt.Equal(followedAt) || user.Preferences["senses"] != "3" {
+		t.Errorf("%s.Get(%q) == %+v, %v", name, "user1", user, err)
+	}
+
+	unfollowedAt := followedAt.Add(time.Hour)
+	if err := registry.Unfollow("user1", unfollowedAt); err != nil {
+		t.Errorf("%s.Unfollow(%q) == %v, want %v", name, "user1", err, nil)
+	}
+	user, err = registry.Get("user1")
+	if err != nil || user.Active || !user.UnfollowedAt.Equal(unfollowedAt) || user.DisplayName != "Choo" {
+		t.Errorf("%s.Get(%q) after unfollow == %+v, %v", name, "user1", user, err)
+	}
+
+	// Returning users keep their preferences
+	err = registry.Follow(User{ID: "user1", DisplayName: "Choopong", FollowedAt: unfollowedAt})
+	user, _ = registry.Get("user1")
+	if err != nil || !user.Active || !user.UnfollowedAt.IsZero() || user.DisplayName != "Choopong" || user.Preferences["senses"] != "3" {
+		t.Errorf("%s.Get(%q) after follow again == %+v, %v", name, "user1", user, err)
+	}
+
+	// Unfollow of an unknown user is recorded
+	if err := registry.Unfollow("user2", unfollowedAt); err != nil {
+		t.Errorf("%s.Unfollow(%q) == %v, want %v", name, "user2", err, nil)
+	}
+	user, err = registry.Get("user2")
+	if err != nil || user.Active {
+		t.Errorf("%s.Get(%q) == %+v, %v", name, "user2", user, err)
+	}
+
+	// Saving a preference leaves the follow state alone
+	if err := registry.SavePreference("user2", "review", "on"); err != nil {
+		t.Errorf("%s.SavePreference(%q) == %v, want %v", name, "user2", err, nil)
+	}
+	user, err = registry.Get("user2")
+	if err != nil || user.Active || !user.FollowedAt.IsZero() || !user.UnfollowedAt.Equal(unfollowedAt) || user.Preferences["review"] != "on" {
+		t.Errorf("%s.Get(%q) after SavePreference == %+v, %v", name, "user2", user, err)
+	}
+	if err := registry.SavePreference("user3", "review", "on"); err != nil {
+		t.Errorf("%s.SavePreference(%q) == %v, want %v", name, "user3", err, nil)
+	}
+	user, err = registry.Get("user3")
+	if err != nil || !user.Active || !user.FollowedAt.IsZero() || user.Preferences["review"] != "on" {
+		t.Errorf("%s.Get(%q) after SavePreference == %+v, %v", name, "user3", user, err)
+	}
+}
+
+func TestMemoryUserRegistry(t *testing.T) {
+	testUserRegistry(t, "MemoryUserRegistry", NewMemoryUserRegistry())
+}
+
+func TestSQLiteUserRegistry(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	registry, err := NewSQLiteUserRegistry(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testUserRegistry(t, "SQLiteUserRegistry", registry)
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// EventStore remembers the webhook events that have been handled, by their
+// webhook event IDs, until they expire.
+type EventStore interface {
+	// Claim records an event, or reports false if it is already recorded
+	// and hasn't expired.
+	Claim(eventID string, now time.Time, ttl time.Duration) (bool, error)
+}
+
+type MemoryEventStore struct {
+	expiries    map[string]time.Time
+	lastSweep   time.Time
+	expiriesMux sync.Mutex
+}
+
+func NewMemoryEventStore() *MemoryEventStore {
+	return &MemoryEventStore{
+		expiries: map[string]time.Time{},
+	}
+}
+
+func (this *MemoryEventStore) Claim(eventID string, now time.Time, ttl time.Duration) (bool, error) {
+	this.expiriesMux.Lock()
+	defer this.expiriesMux.Unlock()
+	if now.Sub(this.lastSweep) >= ttl {
+		for id, expiry := range this.expiries {
+			if !now.Before(expiry) {
+				delete(this.expiries, id)
+			}
+		}
+		this.lastSweep = now
+	}
+	if expiry, ok := this.expiries[eventID]; ok && now.Before(expiry) {
+		return false, nil
+	}
+	this.expiries[eventID] = now.Add(ttl)
+	return true, nil
+}
+
+type SQLiteEventStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteEventStore(db *sql.DB) (*SQLiteEventStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS webhook_events (
+			event_id TEXT PRIMARY KEY,
+			expires_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS webhook_events_expires_at ON webhook_events (expires_at)`)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteEventStore{db: db}, nil
+}
+
+func (this *SQLiteEventStore) Claim(eventID string, now time.Time, ttl time.Duration) (bool, error) {
+	tx, err := this.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(`DELETE FROM webhook_events WHERE expires_at <= ?`, now.Unix()); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	res, err := tx.Exec(`INSERT OR IGNORE INTO webhook_events (event_id, expires_at) VALUES (?, ?)`, eventID, now.Add(ttl).Unix())
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return affected == 1, tx.Commit()
+}
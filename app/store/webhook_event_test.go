@@ -0,0 +1,40 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func testEventStore(t *testing.T, name string, events EventStore) {
+	now := time.Date(2018, 11, 20, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		eventID string
+		after   time.Duration
+		want    bool
+	}{
+		{"event1", 0, true},
+		{"event1", time.Minute, false},
+		{"event2", time.Minute, true},
+		{"event1", time.Hour, true},
+		{"event1", time.Hour + time.Minute, false},
+	}
+	for _, c := range cases {
+		if claimed, err := events.Claim(c.eventID, now.Add(c.after), time.Hour); err != nil || claimed != c.want {
+			t.Errorf("%s.Claim(%q) after %s == %v, %v, want %v", name, c.eventID, c.after, claimed, err, c.want)
+		}
+	}
+}
+
+func TestMemoryEventStore(t *testing.T) {
+	testEventStore(t, "MemoryEventStore", NewMemoryEventStore())
+}
+
+func TestSQLiteEventStore(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	events, err := NewSQLiteEventStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testEventStore(t, "SQLiteEventStore", events)
+}
@@ -3,7 +3,42 @@
 export OXFORD_API_ID=
 export OXFORD_API_KEY=
 
+# Oxford plan limits on upstream calls, no limit when empty
+export OXFORD_QUOTA_PER_MINUTE=60
+export OXFORD_QUOTA_PER_DAY=
+export OXFORD_QUOTA_PER_MONTH=
+
 export LINE_BOT_SECRET=
 export LINE_BOT_TOKEN=
 
+# Runs the bot on Telegram too when set. In "webhook" mode, the default,
+# Telegram sends updates to /telegram with the secret token, if any, that the
+# webhook was set with. "polling" fetches them instead, for servers Telegram
+# can't reach, and needs the webhook deleted.
+export TELEGRAM_BOT_TOKEN=
+export TELEGRAM_MODE=
+export TELEGRAM_WEBHOOK_SECRET=
+
+# Runs the bot on Slack too when set. The app's slash commands, such as
+# /define, interactivity and app_mention events all go to /slack.
+export SLACK_BOT_TOKEN=
+export SLACK_SIGNING_SECRET=
+
+# SQLite file for users and their data, in memory when empty
+export DATABASE_PATH=
+
+# Comma separated user IDs allowed to manage every group, Telegram ones as
+# tg:<user ID> and Slack ones as slack:<user ID>
+export BOT_ADMINS=
+
+# Comma separated words to pick the curated word of the day from
+export WORD_OF_THE_DAY_WORDS=
+
+# "true" to push replies to redelivered webhook events whose reply token
+# has expired, which counts against the LINE plan's push messages
+export PUSH_ON_REDELIVERY=
+
+# Bearer token for the admin API under /admin/, disabled when empty
+export ADMIN_TOKEN=
+
 export HEROKU_APP=choo-dict-bot
\ No newline at end of file